@@ -7,6 +7,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/cachemount"
 	"github.com/docker/docker/builder/fscache"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/stringid"
@@ -29,12 +30,13 @@ type Builder interface {
 type Backend struct {
 	builder        Builder
 	fsCache        *fscache.FSCache
+	cacheMounts    *cachemount.Store
 	imageComponent ImageComponent
 }
 
 // NewBackend creates a new build backend from components
-func NewBackend(components ImageComponent, builder Builder, fsCache *fscache.FSCache) (*Backend, error) {
-	return &Backend{imageComponent: components, builder: builder, fsCache: fsCache}, nil
+func NewBackend(components ImageComponent, builder Builder, fsCache *fscache.FSCache, cacheMounts *cachemount.Store) (*Backend, error) {
+	return &Backend{imageComponent: components, builder: builder, fsCache: fsCache, cacheMounts: cacheMounts}, nil
 }
 
 // Build builds an image from a Source
@@ -49,6 +51,10 @@ func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string
 	if err != nil {
 		return "", err
 	}
+	if options.Check {
+		// nothing was built
+		return "", nil
+	}
 
 	var imageID = build.ImageID
 	if options.Squash {
@@ -68,12 +74,20 @@ func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string
 	return imageID, err
 }
 
-// PruneCache removes all cached build sources
+// PruneCache removes all cached build sources and the cache mounts not in
+// use by a build
 func (b *Backend) PruneCache(ctx context.Context) (*types.BuildCachePruneReport, error) {
 	size, err := b.fsCache.Prune(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to prune build cache")
 	}
+	if b.cacheMounts != nil {
+		mountsSize, err := b.cacheMounts.Prune(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to prune build cache mounts")
+		}
+		size += uint64(mountsSize)
+	}
 	return &types.BuildCachePruneReport{SpaceReclaimed: size}, nil
 }
 
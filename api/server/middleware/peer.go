@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// peerAddr is the remote address of a unix socket connection whose peer
+// process is known.
+type peerAddr struct {
+	net.Addr
+	uid uint32
+}
+
+func (a peerAddr) String() string {
+	return "uid:" + strconv.FormatUint(uint64(a.uid), 10)
+}
+
+type peerConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *peerConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// CloseWrite is used by the HTTP server to close connections gracefully.
+func (c *peerConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface {
+		CloseWrite() error
+	}); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+type peerListener struct {
+	net.Listener
+}
+
+// NewPeerListener returns a listener whose unix socket connections report
+// the UID of their peer process as their remote address, in the form
+// "uid:<uid>", where supported. The API server uses it as the RemoteAddr of
+// the requests, which identifies the client.
+func NewPeerListener(l net.Listener) net.Listener {
+	return &peerListener{l}
+}
+
+func (l *peerListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return c, err
+	}
+	if uid, ok := peerUID(c); ok {
+		return &peerConn{Conn: c, addr: peerAddr{Addr: c.RemoteAddr(), uid: uid}}, nil
+	}
+	return c, nil
+}
+
+// clientKey identifies the client making the request.
+func clientKey(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return "cn:" + r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	if strings.HasPrefix(r.RemoteAddr, "uid:") {
+		return r.RemoteAddr
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return "ip:" + host
+	}
+	if r.RemoteAddr == "" || r.RemoteAddr == "@" {
+		return "unix"
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerUID returns the UID of the process on the other end of a unix socket
+// connection.
+func peerUID(c net.Conn) (uint32, bool) {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return 0, false
+	}
+	// The credentials are read from a duplicate of the descriptor, as
+	// net.UnixConn only has SyscallConn since Go 1.9, and the daemon is
+	// built with Go 1.8 (see GO_VERSION in the Dockerfile).
+	f, err := uc.File()
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+	// File switches the socket to blocking mode in Go 1.8, which the
+	// runtime poller of the connection does not expect.
+	defer unix.SetNonblock(fd, true)
+
+	cred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return 0, false
+	}
+	return cred.Uid, true
+}
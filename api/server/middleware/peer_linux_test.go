@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peer-listener")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("unix", filepath.Join(dir, "sock"))
+	require.NoError(t, err)
+	pl := NewPeerListener(l)
+	defer pl.Close()
+
+	go func() {
+		if c, err := net.Dial("unix", l.Addr().String()); err == nil {
+			c.Close()
+		}
+	}()
+
+	c, err := pl.Accept()
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, "uid:"+strconv.Itoa(os.Getuid()), c.RemoteAddr().String())
+}
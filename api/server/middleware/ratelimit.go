@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+)
+
+// DefaultRateLimitKey is the key of the budget applying to the requests
+// that are not matched by a more specific route.
+const DefaultRateLimitKey = "default"
+
+// pruneInterval is how often the limiters of idle clients are dropped.
+const pruneInterval = time.Minute
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// rateLimitRule is the budget of a route, in requests per second.
+type rateLimitRule struct {
+	key     string
+	method  string
+	pattern string
+	limit   rate.Limit
+	burst   int
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+	idle     time.Duration // time after which the limiter is full again
+}
+
+// RateLimitMiddleware limits the number of API requests each client can
+// make. Clients are identified by the common name of their TLS client
+// certificate, the UID of the peer of unix socket connections, or their
+// remote address. Each route has its own budget per client; requests not
+// matching any route share the default budget.
+type RateLimitMiddleware struct {
+	mu        sync.Mutex
+	rules     []rateLimitRule
+	clients   map[string]*clientLimiter
+	lastPrune time.Time
+}
+
+// NewRateLimitMiddleware creates a new RateLimitMiddleware with the given
+// budgets, keyed by route. See parseRateLimitRule for their format.
+func NewRateLimitMiddleware(limits map[string]string) (*RateLimitMiddleware, error) {
+	m := &RateLimitMiddleware{}
+	if err := m.SetLimits(limits); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetLimits replaces the budgets of the middleware. The budgets already
+// consumed by clients are reset.
+func (m *RateLimitMiddleware) SetLimits(limits map[string]string) error {
+	var rules []rateLimitRule
+	for key, value := range limits {
+		rule, err := parseRateLimitRule(key, value)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+
+	// Match the most specific routes first: routes with a method before
+	// the ones without, then longer patterns before shorter ones. The
+	// default budget always comes last.
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if (a.key == DefaultRateLimitKey) != (b.key == DefaultRateLimitKey) {
+			return b.key == DefaultRateLimitKey
+		}
+		if (a.method == "") != (b.method == "") {
+			return a.method != ""
+		}
+		if len(a.pattern) != len(b.pattern) {
+			return len(a.pattern) > len(b.pattern)
+		}
+		return a.key < b.key
+	})
+
+	m.mu.Lock()
+	m.rules = rules
+	m.clients = make(map[string]*clientLimiter)
+	m.mu.Unlock()
+	return nil
+}
+
+// ValidateRateLimit validates a "route=budget" pair as given on the command
+// line.
+func ValidateRateLimit(val string) (string, error) {
+	kv := strings.SplitN(val, "=", 2)
+	if len(kv) != 2 {
+		return "", fmt.Errorf("invalid API rate limit %q: expected route=budget", val)
+	}
+	if _, err := parseRateLimitRule(kv[0], kv[1]); err != nil {
+		return "", err
+	}
+	return val, nil
+}
+
+// parseRateLimitRule parses the budget of a route. The route is either
+// "default" or an optional HTTP method followed by a path pattern, such as
+// "POST /build" or "/containers/*/logs"; the pattern is matched against
+// the request path without the API version. The budget has the form
+// "<count>/<period>[:<burst>]", for example "10/s", "100/1m" or "1/10s:3".
+// The burst is the number of requests that can be made at once, and
+// defaults to the count.
+func parseRateLimitRule(key, value string) (rateLimitRule, error) {
+	rule := rateLimitRule{key: key}
+	if key != DefaultRateLimitKey {
+		route := strings.Fields(key)
+		switch len(route) {
+		case 1:
+			rule.pattern = route[0]
+		case 2:
+			rule.method, rule.pattern = strings.ToUpper(route[0]), route[1]
+		default:
+			return rule, fmt.Errorf("invalid API rate limit route %q", key)
+		}
+		if !strings.HasPrefix(rule.pattern, "/") {
+			return rule, fmt.Errorf("invalid API rate limit route %q: the path must start with /", key)
+		}
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			return rule, fmt.Errorf("invalid API rate limit route %q: %v", key, err)
+		}
+	}
+
+	budget := value
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		burst, err := strconv.Atoi(value[i+1:])
+		if err != nil || burst < 1 {
+			return rule, fmt.Errorf("invalid burst in API rate limit %q for %s", value, key)
+		}
+		rule.burst = burst
+		budget = value[:i]
+	}
+
+	parts := strings.SplitN(budget, "/", 2)
+	if len(parts) != 2 {
+		return rule, fmt.Errorf("invalid API rate limit %q for %s: expected <count>/<period>[:<burst>]", value, key)
+	}
+	count, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || count <= 0 {
+		return rule, fmt.Errorf("invalid count in API rate limit %q for %s", value, key)
+	}
+	period := parts[1]
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return rule, fmt.Errorf("invalid period in API rate limit %q for %s", value, key)
+	}
+
+	rule.limit = rate.Limit(count / d.Seconds())
+	if rule.burst == 0 {
+		rule.burst = int(math.Max(1, math.Ceil(count)))
+	}
+	return rule, nil
+}
+
+func (r rateLimitRule) matches(method, p string) bool {
+	if r.key == DefaultRateLimitKey {
+		return true
+	}
+	if r.method != "" && r.method != method {
+		return false
+	}
+	ok, _ := path.Match(r.pattern, p)
+	return ok
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m *RateLimitMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		client := clientKey(r)
+		rule, delay := m.reserve(client, r.Method, versionPrefix.ReplaceAllString(r.URL.Path, ""), time.Now())
+		if delay > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			msg := fmt.Sprintf("too many requests from %s to %s, please retry later", client, rule)
+			return httputils.WriteJSON(w, http.StatusTooManyRequests, &types.ErrorResponse{Message: msg})
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+// reserve takes a token from the budget of the client for the route. It
+// returns the key of the matching route, and how long the client has to
+// wait before its next request is accepted if the budget is exhausted.
+func (m *RateLimitMiddleware) reserve(client, method, p string, now time.Time) (string, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rule *rateLimitRule
+	for i := range m.rules {
+		if m.rules[i].matches(method, p) {
+			rule = &m.rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return "", 0
+	}
+
+	if now.Sub(m.lastPrune) > pruneInterval {
+		for k, c := range m.clients {
+			if now.Sub(c.lastSeen) > c.idle {
+				delete(m.clients, k)
+			}
+		}
+		m.lastPrune = now
+	}
+
+	k := client + " " + rule.key
+	c, ok := m.clients[k]
+	if !ok {
+		c = &clientLimiter{
+			limiter: rate.NewLimiter(rule.limit, rule.burst),
+			idle:    time.Duration(float64(rule.burst) / float64(rule.limit) * float64(time.Second)),
+		}
+		m.clients[k] = c
+	}
+	c.lastSeen = now
+
+	res := c.limiter.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return rule.key, delay
+	}
+	return rule.key, 0
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestParseRateLimitRule(t *testing.T) {
+	rule, err := parseRateLimitRule("post /build", "10/1m:3")
+	require.NoError(t, err)
+	assert.Equal(t, "POST", rule.method)
+	assert.Equal(t, "/build", rule.pattern)
+	assert.InDelta(t, 10.0/60, float64(rule.limit), 1e-9)
+	assert.Equal(t, 3, rule.burst)
+
+	rule, err = parseRateLimitRule("default", "5/s")
+	require.NoError(t, err)
+	assert.InDelta(t, 5.0, float64(rule.limit), 1e-9)
+	assert.Equal(t, 5, rule.burst)
+
+	for _, c := range []struct{ key, value string }{
+		{"build", "1/s"},
+		{"GET /containers/json extra", "1/s"},
+		{"/containers/[", "1/s"},
+		{"/build", "1"},
+		{"/build", "0/s"},
+		{"/build", "1/x"},
+		{"/build", "1/s:0"},
+	} {
+		_, err := parseRateLimitRule(c.key, c.value)
+		assert.Error(t, err, "%s=%s", c.key, c.value)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	m, err := NewRateLimitMiddleware(map[string]string{
+		"default":                "100/s",
+		"POST /build":            "1/1h",
+		"/containers/*/logs":     "2/1h",
+		"GET /containers/*/logs": "1/1h",
+	})
+	require.NoError(t, err)
+
+	handler := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+	do := func(method, path, remoteAddr string, tlsCN string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		if tlsCN != "" {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: tlsCN}}}}
+		}
+		rec := httptest.NewRecorder()
+		require.NoError(t, handler(context.Background(), rec, req, map[string]string{}))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, do("POST", "/v1.34/build", "10.0.0.1:1234", "").Code)
+	rec := do("POST", "/build", "10.0.0.1:4321", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// other clients have their own budget
+	assert.Equal(t, http.StatusOK, do("POST", "/build", "10.0.0.2:1234", "").Code)
+	assert.Equal(t, http.StatusOK, do("POST", "/build", "10.0.0.1:1234", "runner-1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("POST", "/build", "10.0.0.3:1234", "runner-1").Code)
+
+	// the most specific route applies
+	assert.Equal(t, http.StatusOK, do("GET", "/containers/abc/logs", "10.0.0.1:1234", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("GET", "/containers/abc/logs", "10.0.0.1:1234", "").Code)
+
+	// other routes use the default budget
+	assert.Equal(t, http.StatusOK, do("GET", "/containers/json", "10.0.0.1:1234", "").Code)
+
+	// reloading the limits resets the budgets
+	require.NoError(t, m.SetLimits(map[string]string{"POST /build": "1/1h"}))
+	assert.Equal(t, http.StatusOK, do("POST", "/build", "10.0.0.1:1234", "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/containers/abc/logs", "10.0.0.1:1234", "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/containers/abc/logs", "10.0.0.1:1234", "").Code)
+}
+
+func TestRateLimitPruneIdleClients(t *testing.T) {
+	m, err := NewRateLimitMiddleware(map[string]string{"default": "1/s"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	_, delay := m.reserve("ip:10.0.0.1", "GET", "/info", now)
+	assert.Equal(t, time.Duration(0), delay)
+	_, delay = m.reserve("ip:10.0.0.1", "GET", "/info", now)
+	assert.NotEqual(t, time.Duration(0), delay)
+
+	m.reserve("ip:10.0.0.2", "GET", "/info", now.Add(2*pruneInterval))
+	assert.Len(t, m.clients, 1)
+}
@@ -134,7 +134,25 @@ func newImageBuildOptions(ctx context.Context, r *http.Request) (*types.ImageBui
 		}
 		options.CacheFrom = cacheFrom
 	}
+
+	cacheToJSON := r.FormValue("cacheto")
+	if cacheToJSON != "" {
+		var cacheTo = []string{}
+		if err := json.Unmarshal([]byte(cacheToJSON), &cacheTo); err != nil {
+			return nil, err
+		}
+		options.CacheTo = cacheTo
+	}
 	options.SessionID = r.FormValue("session")
+	options.ProgressEvents = httputils.BoolValue(r, "progressevents")
+	options.Check = httputils.BoolValue(r, "check")
+
+	if epoch := r.FormValue("sourcedateepoch"); epoch != "" {
+		if seconds, err := strconv.ParseInt(epoch, 10, 64); err != nil || seconds < 0 {
+			return nil, validationError{fmt.Errorf("invalid sourcedateepoch %q: must be a non-negative number of seconds", epoch)}
+		}
+		options.SourceDateEpoch = epoch
+	}
 
 	return options, nil
 }
@@ -91,6 +91,11 @@ func (s *containerRouter) getContainersLogs(ctx context.Context, w http.Response
 		return validationError{errors.New("Bad parameters: you must choose at least one stream")}
 	}
 
+	logFilters, err := filters.FromJSON(r.Form.Get("filters"))
+	if err != nil {
+		return err
+	}
+
 	containerName := vars["name"]
 	logsConfig := &types.ContainerLogsOptions{
 		Follow:     httputils.BoolValue(r, "follow"),
@@ -100,6 +105,7 @@ func (s *containerRouter) getContainersLogs(ctx context.Context, w http.Response
 		ShowStdout: stdout,
 		ShowStderr: stderr,
 		Details:    httputils.BoolValue(r, "details"),
+		Filters:    logFilters,
 	}
 
 	msgs, tty, err := s.backend.ContainerLogs(ctx, containerName, logsConfig)
@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/docker/docker/pkg/system"
@@ -203,12 +204,17 @@ func (s *imageRouter) postImagesPush(ctx context.Context, w http.ResponseWriter,
 	image := vars["name"]
 	tag := r.Form.Get("tag")
 
+	compression, err := parseLayerCompression(r.Form.Get("compression"), r.Form.Get("compressionlevel"), httputils.BoolValue(r, "compressionparallel"))
+	if err != nil {
+		return err
+	}
+
 	output := ioutils.NewWriteFlusher(w)
 	defer output.Close()
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if err := s.backend.PushImage(ctx, image, tag, metaHeaders, authConfig, output); err != nil {
+	if err := s.backend.PushImage(ctx, image, tag, metaHeaders, authConfig, compression, output); err != nil {
 		if !output.Flushed() {
 			return err
 		}
@@ -217,11 +223,43 @@ func (s *imageRouter) postImagesPush(ctx context.Context, w http.ResponseWriter,
 	return nil
 }
 
+// parseLayerCompression returns the compression of the layers to push, or
+// nil to use the defaults.
+func parseLayerCompression(name, level string, parallel bool) (*archive.CompressionOptions, error) {
+	if name == "" && level == "" && !parallel {
+		return nil, nil
+	}
+	options := &archive.CompressionOptions{Compression: archive.Gzip, Parallel: parallel}
+	switch name {
+	case "", types.ImagePushCompressionGzip:
+	case types.ImagePushCompressionZstd:
+		options.Compression = archive.Zstd
+	default:
+		return nil, validationError{errors.Errorf("invalid compression %q: must be %q or %q", name, types.ImagePushCompressionGzip, types.ImagePushCompressionZstd)}
+	}
+	if level != "" {
+		l, err := strconv.Atoi(level)
+		if err != nil {
+			return nil, validationError{errors.Errorf("invalid compressionlevel %q", level)}
+		}
+		options.Level = l
+	}
+	if err := options.Validate(); err != nil {
+		return nil, validationError{err}
+	}
+	return options, nil
+}
+
 func (s *imageRouter) getImagesGet(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := httputils.ParseForm(r); err != nil {
 		return err
 	}
 
+	format := r.Form.Get("format")
+	if format != "" && format != types.ImageSaveFormatDocker && format != types.ImageSaveFormatOCI {
+		return validationError{errors.Errorf("invalid format %q: must be %q or %q", format, types.ImageSaveFormatDocker, types.ImageSaveFormatOCI)}
+	}
+
 	w.Header().Set("Content-Type", "application/x-tar")
 
 	output := ioutils.NewWriteFlusher(w)
@@ -233,7 +271,7 @@ func (s *imageRouter) getImagesGet(ctx context.Context, w http.ResponseWriter, r
 		names = r.Form["names"]
 	}
 
-	if err := s.backend.ExportImage(names, output); err != nil {
+	if err := s.backend.ExportImage(names, format, output); err != nil {
 		if !output.Flushed() {
 			return err
 		}
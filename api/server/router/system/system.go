@@ -2,6 +2,7 @@ package system
 
 import (
 	"github.com/docker/docker/api/server/router"
+	"github.com/docker/docker/builder/cachemount"
 	"github.com/docker/docker/builder/fscache"
 	"github.com/docker/docker/daemon/cluster"
 )
@@ -13,14 +14,18 @@ type systemRouter struct {
 	cluster *cluster.Cluster
 	routes  []router.Route
 	builder *fscache.FSCache
+	// cacheMounts holds the cache mounts of RUN instructions, counted in
+	// the build cache usage
+	cacheMounts *cachemount.Store
 }
 
 // NewRouter initializes a new system router
-func NewRouter(b Backend, c *cluster.Cluster, fscache *fscache.FSCache) router.Router {
+func NewRouter(b Backend, c *cluster.Cluster, fscache *fscache.FSCache, cacheMounts *cachemount.Store) router.Router {
 	r := &systemRouter{
-		backend: b,
-		cluster: c,
-		builder: fscache,
+		backend:     b,
+		cluster:     c,
+		builder:     fscache,
+		cacheMounts: cacheMounts,
 	}
 
 	r.routes = []router.Route{
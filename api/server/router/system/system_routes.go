@@ -79,6 +79,13 @@ func (s *systemRouter) getDiskUsage(ctx context.Context, w http.ResponseWriter,
 	if err != nil {
 		return pkgerrors.Wrap(err, "error getting build cache usage")
 	}
+	if s.cacheMounts != nil {
+		mountsSize, err := s.cacheMounts.DiskUsage()
+		if err != nil {
+			return pkgerrors.Wrap(err, "error getting build cache mounts usage")
+		}
+		builderSize += mountsSize
+	}
 	du.BuilderSize = builderSize
 
 	return httputils.WriteJSON(w, http.StatusOK, du)
@@ -139,13 +146,19 @@ func (s *systemRouter) getEvents(ctx context.Context, w http.ResponseWriter, r *
 
 	enc := json.NewEncoder(output)
 
-	buffered, l := s.backend.SubscribeToEvents(since, until, ef)
+	replay, l := s.backend.SubscribeToEvents(since, until, ef)
 	defer s.backend.UnsubscribeFromEvents(l)
 
-	for _, ev := range buffered {
-		if err := enc.Encode(ev); err != nil {
-			return err
-		}
+	var encErr error
+	err = replay(func(ev events.Message) bool {
+		encErr = enc.Encode(ev)
+		return encErr == nil
+	})
+	if encErr != nil {
+		return encErr
+	}
+	if err != nil {
+		return err
 	}
 
 	if onlyPastEvents {
@@ -1,6 +1,8 @@
 package volume
 
 import (
+	"io"
+
 	"golang.org/x/net/context"
 
 	// TODO return types need to be refactored into pkg id:12 gh:13
@@ -16,4 +18,7 @@ type Backend interface {
 	VolumeCreate(name, driverName string, opts, labels map[string]string) (*types.Volume, error)
 	VolumeRm(name string, force bool) error
 	VolumesPrune(ctx context.Context, pruneFilters filters.Args) (*types.VolumesPruneReport, error)
+	VolumeClone(source, name, driverName string, opts, labels map[string]string) (*types.Volume, error)
+	VolumeExport(name string, out io.Writer) error
+	VolumeImport(name, driverName string, opts, labels map[string]string, in io.Reader) (*types.Volume, error)
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types/filters"
 	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
 
@@ -83,3 +84,73 @@ func (v *volumeRouter) postVolumesPrune(ctx context.Context, w http.ResponseWrit
 	}
 	return httputils.WriteJSON(w, http.StatusOK, pruneReport)
 }
+
+func (v *volumeRouter) postVolumeClone(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var req volumetypes.VolumesCreateBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	volume, err := v.backend.VolumeClone(vars["name"], req.Name, req.Driver, req.DriverOpts, req.Labels)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusCreated, volume)
+}
+
+func (v *volumeRouter) getVolumeExport(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	w.Header().Set("Content-Type", "application/x-tar")
+	return v.backend.VolumeExport(vars["name"], w)
+}
+
+func (v *volumeRouter) postVolumesImport(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	opts, err := formJSONMap(r, "opts")
+	if err != nil {
+		return err
+	}
+	labels, err := formJSONMap(r, "labels")
+	if err != nil {
+		return err
+	}
+
+	volume, err := v.backend.VolumeImport(r.Form.Get("name"), r.Form.Get("driver"), opts, labels, r.Body)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusCreated, volume)
+}
+
+type invalidRequestError struct {
+	cause error
+}
+
+func (e invalidRequestError) Error() string {
+	return e.cause.Error()
+}
+
+func (e invalidRequestError) InvalidParameter() {}
+
+// formJSONMap decodes the JSON encoded map of the given query parameter.
+func formJSONMap(r *http.Request, key string) (map[string]string, error) {
+	value := r.Form.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return nil, errors.Wrapf(invalidRequestError{err}, "error reading %s", key)
+	}
+	return m, nil
+}
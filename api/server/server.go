@@ -59,7 +59,7 @@ func (s *Server) Accept(addr string, listeners ...net.Listener) {
 			srv: &http.Server{
 				Addr: addr,
 			},
-			l: listener,
+			l: middleware.NewPeerListener(listener),
 		}
 		s.servers = append(s.servers, httpServer)
 	}
@@ -103,4 +103,8 @@ type ContainerCommitConfig struct {
 	// TODO: ContainerConfig is only used by the dockerfile Builder, so remove it id:17 gh:18
 	// once the Builder has been updated to use a different interface
 	ContainerConfig *container.Config
+	// SourceDateEpoch, if set, is the creation time of the image, and the
+	// modification times of the files of its layer are clamped to it, for
+	// reproducible builds.
+	SourceDateEpoch *time.Time
 }
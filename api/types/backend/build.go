@@ -2,6 +2,7 @@ package backend
 
 import (
 	"io"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/pkg/streamformatter"
@@ -41,4 +42,14 @@ type GetImageAndLayerOptions struct {
 	AuthConfig map[string]types.AuthConfig
 	Output     io.Writer
 	Platform   string
+	// SourceDateEpoch, if set, clamps the modification times of the files of
+	// the layers committed from the returned layer.
+	SourceDateEpoch *time.Time
+}
+
+// BuildCacheOptions are the options to import or export a build cache
+type BuildCacheOptions struct {
+	AuthConfig map[string]types.AuthConfig
+	Output     io.Writer
+	Platform   string
 }
@@ -78,6 +78,7 @@ type ContainerLogsOptions struct {
 	Follow     bool
 	Tail       string
 	Details    bool
+	Filters    filters.Args
 }
 
 // ContainerRemoveOptions holds parameters to remove containers.
@@ -174,11 +175,31 @@ type ImageBuildOptions struct {
 	Squash bool
 	// CacheFrom specifies images that are used for matching cache. Images
 	// specified here do not need to have a valid parent chain to match cache.
-	CacheFrom   []string
+	// It may also specify build caches to import, such as
+	// "type=registry,ref=example.com/app:buildcache" or
+	// "type=local,src=app.tar", relative to the build cache directory of
+	// the daemon.
+	CacheFrom []string
+	// CacheTo specifies the locations the build cache is exported to, such
+	// as "type=registry,ref=example.com/app:buildcache" or
+	// "type=local,dest=app.tar", relative to the build cache directory of
+	// the daemon.
+	CacheTo     []string
 	SecurityOpt []string
 	ExtraHosts  []string // List of extra hosts
 	Target      string
 	SessionID   string
+	// ProgressEvents requests the progress of the steps of the build as
+	// BuildStep messages in the aux stream, in addition to the text output.
+	ProgressEvents bool
+	// Check validates the Dockerfile without building it, and reports the
+	// problems found as a BuildCheckResult in the aux stream.
+	Check bool
+	// SourceDateEpoch is a number of seconds since the Unix epoch used as
+	// the creation time of the images of the build, and to which the
+	// modification times of the files of their layers are clamped, so that
+	// builds are reproducible.
+	SourceDateEpoch string
 
 	// TODO @jhowardmsft LCOW Support: This will require extending to include id:92 gh:93
 	// `Platform string`, but is omitted for now as it's hard-coded temporarily
@@ -211,6 +232,15 @@ type ImageImportOptions struct {
 	Changes []string // Changes are the raw changes to apply to this image
 }
 
+// VolumeImportOptions holds information to import the content of a volume
+// from the client host.
+type VolumeImportOptions struct {
+	Name       string            // Name is the name of the volume to import to, which is created if it doesn't exist
+	Driver     string            // Driver is the driver of the volume if it is created
+	DriverOpts map[string]string // DriverOpts are the driver options of the volume if it is created
+	Labels     map[string]string // Labels are the labels of the volume if it is created
+}
+
 // ImageListOptions holds parameters to filter the list of images with.
 type ImageListOptions struct {
 	All     bool
@@ -240,7 +270,18 @@ type ImagePullOptions struct {
 type RequestPrivilegeFunc func() (string, error)
 
 //ImagePushOptions holds information to push images.
-type ImagePushOptions ImagePullOptions
+type ImagePushOptions struct {
+	All           bool
+	RegistryAuth  string // RegistryAuth is the base64 encoded credentials for the registry
+	PrivilegeFunc RequestPrivilegeFunc
+	// Compression is the algorithm used to compress the layers, "gzip" or
+	// "zstd", and CompressionLevel its level. The daemon defaults are used
+	// if they are not set. CompressionParallel compresses the gzip layers on
+	// all the CPUs of the daemon host, if pigz is installed.
+	Compression         string
+	CompressionLevel    int
+	CompressionParallel bool
+}
 
 // ImageRemoveOptions holds parameters to remove images.
 type ImageRemoveOptions struct {
@@ -22,8 +22,15 @@ type HealthConfig struct {
 	// {"NONE"} : disable healthcheck
 	// {"CMD", args...} : exec arguments directly
 	// {"CMD-SHELL", command} : run command with system's default shell
+	// {"HTTP"} : send the HTTP request described by HTTP
+	// {"TCP"} : open the TCP connection described by TCP
 	Test []string `json:",omitempty"`
 
+	// HTTP describes the request sent by the "HTTP" test.
+	HTTP *HealthHTTPConfig `json:",omitempty"`
+	// TCP describes the connection opened by the "TCP" test.
+	TCP *HealthTCPConfig `json:",omitempty"`
+
 	// Zero means to inherit. Durations are expressed as integer nanoseconds.
 	Interval    time.Duration `json:",omitempty"` // Interval is the time to wait between checks.
 	Timeout     time.Duration `json:",omitempty"` // Timeout is the time to wait before considering the check to have hung.
@@ -34,6 +41,29 @@ type HealthConfig struct {
 	Retries int `json:",omitempty"`
 }
 
+// HealthHTTPConfig holds the settings of an "HTTP" healthcheck. The request
+// is sent by the daemon from the container's network namespace, so that the
+// container does not need any HTTP client.
+type HealthHTTPConfig struct {
+	Scheme  string            `json:",omitempty"` // Scheme is "http" (the default) or "https".
+	Port    int               // Port is the port the container listens on.
+	Path    string            `json:",omitempty"` // Path of the request. Defaults to "/".
+	Method  string            `json:",omitempty"` // Method of the request. Defaults to "GET".
+	Headers map[string]string `json:",omitempty"` // Headers to add to the request.
+
+	// StatusMin and StatusMax are the range of response status codes
+	// considered healthy. Zero means inherit the defaults, 200 and 399.
+	StatusMin int `json:",omitempty"`
+	StatusMax int `json:",omitempty"`
+}
+
+// HealthTCPConfig holds the settings of a "TCP" healthcheck. The container
+// is healthy if the daemon can connect to the port from the container's
+// network namespace.
+type HealthTCPConfig struct {
+	Port int // Port is the port the container listens on.
+}
+
 // Config contains the configuration data about a container.
 // It should hold only portable information about the container.
 // Here, "portable" means "independent from the host we are running on".
@@ -496,11 +496,12 @@ type Runtime struct {
 // DiskUsage contains response of Engine API:
 // GET "/system/df"
 type DiskUsage struct {
-	LayersSize  int64
-	Images      []*ImageSummary
-	Containers  []*Container
-	Volumes     []*Volume
-	BuilderSize int64
+	LayersSize       int64
+	LayersSharedSize int64 // Bytes of file content stored once but used by several layers
+	Images           []*ImageSummary
+	Containers       []*Container
+	Volumes          []*Volume
+	BuilderSize      int64
 }
 
 // ContainersPruneReport contains the response for Engine API:
@@ -573,3 +574,70 @@ type PushResult struct {
 type BuildResult struct {
 	ID string
 }
+
+// BuildWarning is a problem found in a Dockerfile by a build check
+type BuildWarning struct {
+	// Rule identifies the kind of problem, such as "UnknownFlag".
+	Rule string
+	// Line is the line of the instruction in the Dockerfile, or 0 if the
+	// problem is not about an instruction.
+	Line    int `json:",omitempty"`
+	Message string
+}
+
+// BuildCheckResult is the result of the check of a Dockerfile. It is emitted
+// in the aux stream of the build when ImageBuildOptions.Check is set.
+type BuildCheckResult struct {
+	Warnings []BuildWarning
+}
+
+// Statuses of a BuildStep
+const (
+	BuildStepStarted   = "started"
+	BuildStepCompleted = "completed"
+	BuildStepCached    = "cached"
+	BuildStepFailed    = "failed"
+)
+
+// BuildStep reports the progress of a step of a build. It is emitted in the
+// aux stream of the build when ImageBuildOptions.ProgressEvents is set, when
+// the step starts and when it ends.
+type BuildStep struct {
+	// Stage is the name of the build stage of the step.
+	Stage string
+	// Step is the index of the step in the build, starting at 1.
+	Step int
+	// Steps is the number of steps of the build.
+	Steps int
+	// Instruction is the Dockerfile instruction of the step.
+	Instruction string
+	// Status is one of "started", "completed", "cached" or "failed".
+	Status string
+	// Duration is the duration of the step in nanoseconds, once it ended.
+	Duration int64 `json:",omitempty"`
+	// ImageID is the image of the stage after the step, once it ended.
+	ImageID string `json:",omitempty"`
+	// Size is the size in bytes of the layer created by the step, if any.
+	Size int64 `json:",omitempty"`
+	// Error is the error of a failed step.
+	Error string `json:",omitempty"`
+}
+
+// Formats of the tar archive of the images exported by GET /images/get
+const (
+	// ImageSaveFormatDocker is the format read by docker load, with a
+	// manifest.json file and a directory per layer.
+	ImageSaveFormatDocker = "docker"
+	// ImageSaveFormatOCI is the OCI image layout, with an oci-layout and an
+	// index.json file, and the content addressed blobs of the images.
+	ImageSaveFormatOCI = "oci"
+)
+
+// Compression algorithms of the layers pushed by POST /images/(name)/push
+const (
+	// ImagePushCompressionGzip compresses the layers with gzip, the default.
+	ImagePushCompressionGzip = "gzip"
+	// ImagePushCompressionZstd compresses the layers with zstd, with the OCI
+	// media type application/vnd.oci.image.layer.v1.tar+zstd.
+	ImagePushCompressionZstd = "zstd"
+)
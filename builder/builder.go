@@ -47,11 +47,15 @@ type Backend interface {
 	CreateImage(config []byte, parent string, platform string) (Image, error)
 
 	ImageCacheBuilder
+	BuildCacheBackend
 }
 
 // ImageBackend are the interface methods required from an image component
 type ImageBackend interface {
 	GetImageAndReleasableLayer(ctx context.Context, refOrID string, opts backend.GetImageAndLayerOptions) (Image, ReleaseableLayer, error)
+	// ImageLayerSize returns the size of the layer created by the last step
+	// of the history of an image, or 0 if that step created no layer.
+	ImageLayerSize(imageID string) (int64, error)
 }
 
 // ExecBackend contains the interface methods required for executing containers
@@ -82,6 +86,17 @@ type ImageCacheBuilder interface {
 	MakeImageCache(cacheFrom []string, platform string) ImageCache
 }
 
+// BuildCacheBackend imports and exports build caches, so that they can be
+// shared by daemons.
+type BuildCacheBackend interface {
+	// ImportBuildCache returns an image cache matching the build cache
+	// stored at src.
+	ImportBuildCache(ctx context.Context, src CacheSpec, opts backend.BuildCacheOptions) (ImageCache, error)
+	// ExportBuildCache stores the build cache of the images of chains at
+	// dest.
+	ExportBuildCache(ctx context.Context, dest CacheSpec, chains []CacheChain, opts backend.BuildCacheOptions) error
+}
+
 // ImageCache abstracts an image cache.
 // (parent image, child runconfig) -> child image
 type ImageCache interface {
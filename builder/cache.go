@@ -0,0 +1,86 @@
+package builder
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CacheTypeRegistry is the type of the build caches stored in a registry
+	CacheTypeRegistry = "registry"
+	// CacheTypeLocal is the type of the build caches stored in a tarball in
+	// the build cache directory of the daemon
+	CacheTypeLocal = "local"
+)
+
+// CacheSpec is the location a build cache is imported from or exported to.
+// It is given as a comma-separated list of key=value pairs, such as
+// "type=registry,ref=example.com/app:buildcache" or
+// "type=local,dest=app.tar".
+type CacheSpec struct {
+	Type string
+	// Ref is the reference the build cache is pushed to or pulled from, for
+	// the registry type
+	Ref string
+	// Path is the tarball of the build cache, relative to the build cache
+	// directory of the daemon, for the local type
+	Path string
+}
+
+// IsCacheSpec returns true if s is a build cache location rather than an
+// image reference.
+func IsCacheSpec(s string) bool {
+	return strings.Contains(s, "=")
+}
+
+// ParseCacheSpec parses a build cache location.
+func ParseCacheSpec(s string) (CacheSpec, error) {
+	var spec CacheSpec
+	for _, field := range strings.Split(s, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return spec, errors.Errorf("invalid build cache field %q in %q, must be a key=value pair", field, s)
+		}
+		switch kv[0] {
+		case "type":
+			spec.Type = kv[1]
+		case "ref":
+			spec.Ref = kv[1]
+		case "src", "dest":
+			spec.Path = kv[1]
+		default:
+			return spec, errors.Errorf("unknown build cache field %q in %q", kv[0], s)
+		}
+	}
+
+	switch spec.Type {
+	case CacheTypeRegistry:
+		if spec.Ref == "" {
+			return spec, errors.Errorf("build cache %q requires a ref", s)
+		}
+	case CacheTypeLocal:
+		if spec.Path == "" {
+			return spec, errors.Errorf("build cache %q requires a src or dest path", s)
+		}
+	case "":
+		return spec, errors.Errorf("build cache %q requires a type", s)
+	default:
+		return spec, errors.Errorf("unsupported build cache type %q in %q", spec.Type, s)
+	}
+	return spec, nil
+}
+
+func (s CacheSpec) String() string {
+	if s.Type == CacheTypeRegistry {
+		return s.Type + ":" + s.Ref
+	}
+	return s.Type + ":" + s.Path
+}
+
+// CacheChain is a chain of images created by a build stage, from Top up to
+// but excluding Base, the image the stage started from.
+type CacheChain struct {
+	Top  string
+	Base string
+}
@@ -0,0 +1,37 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/docker/docker/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheSpec(t *testing.T) {
+	spec, err := ParseCacheSpec("type=registry,ref=example.com/app:buildcache")
+	require.NoError(t, err)
+	assert.Equal(t, CacheSpec{Type: CacheTypeRegistry, Ref: "example.com/app:buildcache"}, spec)
+	assert.Equal(t, "registry:example.com/app:buildcache", spec.String())
+
+	spec, err = ParseCacheSpec("type=local,dest=app.tar")
+	require.NoError(t, err)
+	assert.Equal(t, CacheSpec{Type: CacheTypeLocal, Path: "app.tar"}, spec)
+
+	assert.True(t, IsCacheSpec("type=local,src=app.tar"))
+	assert.False(t, IsCacheSpec("example.com/app:latest"))
+}
+
+func TestParseCacheSpecErrors(t *testing.T) {
+	for spec, expected := range map[string]string{
+		"type=registry":          "requires a ref",
+		"type=local":             "requires a src or dest path",
+		"ref=busybox":            "requires a type",
+		"type=s3,ref=busybox":    "unsupported build cache type",
+		"type=registry,mode=max": "unknown build cache field",
+		"type=registry,ref":      "must be a key=value pair",
+	} {
+		_, err := ParseCacheSpec(spec)
+		testutil.ErrorContains(t, err, expected)
+	}
+}
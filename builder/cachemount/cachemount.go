@@ -0,0 +1,135 @@
+// Package cachemount manages the directories mounted by the builder in the
+// containers of RUN instructions with "--mount=type=cache". The content of
+// these directories is kept between builds, but is never committed to an
+// image.
+package cachemount
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/directory"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Store manages the cache directories, keyed by the id of the cache mount.
+// A cache directory can be used by several builds at once.
+type Store struct {
+	root    string
+	rootIDs idtools.IDPair
+
+	mu     sync.Mutex
+	active map[string]int // reference counts of the directories in use
+}
+
+// NewStore creates a Store keeping the cache directories under root.
+func NewStore(root string, rootIDs idtools.IDPair) (*Store, error) {
+	if err := idtools.MkdirAllAndChown(root, 0700, rootIDs); err != nil {
+		return nil, errors.Wrap(err, "failed to create cache mounts directory")
+	}
+	return &Store{
+		root:    root,
+		rootIDs: rootIDs,
+		active:  make(map[string]int),
+	}, nil
+}
+
+// Get returns the path of the cache directory with the given id, creating
+// it if needed. The directory is not pruned until release is called.
+func (s *Store) Get(id string) (path string, release func(), err error) {
+	if id == "" {
+		return "", nil, errors.New("cache mount id cannot be empty")
+	}
+	key := dirName(id)
+	path = filepath.Join(s.root, key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := idtools.MkdirAllAndChown(path, 0755, s.rootIDs); err != nil {
+		return "", nil, errors.Wrapf(err, "failed to create cache mount %s", id)
+	}
+	// record the last use of the cache mount
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		logrus.Debugf("failed to update the access time of cache mount %s: %v", id, err)
+	}
+	s.active[key]++
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			s.mu.Lock()
+			if s.active[key]--; s.active[key] <= 0 {
+				delete(s.active, key)
+			}
+			s.mu.Unlock()
+		})
+	}
+	return path, release, nil
+}
+
+// DiskUsage returns the space used by the cache directories.
+func (s *Store) DiskUsage() (int64, error) {
+	dirs, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, d := range dirs {
+		size, err := directory.Size(filepath.Join(s.root, d.Name()))
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// Prune removes the cache directories not in use by a build, and returns
+// the space reclaimed.
+func (s *Store) Prune(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirs, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		return 0, err
+	}
+	var reclaimed int64
+	for _, d := range dirs {
+		select {
+		case <-ctx.Done():
+			return reclaimed, ctx.Err()
+		default:
+		}
+		if s.active[d.Name()] > 0 {
+			continue
+		}
+		p := filepath.Join(s.root, d.Name())
+		size, err := directory.Size(p)
+		if err != nil {
+			return reclaimed, err
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return reclaimed, errors.Wrap(err, "failed to remove cache mount")
+		}
+		reclaimed += size
+	}
+	return reclaimed, nil
+}
+
+// dirName returns the name of the directory of a cache mount. The id is
+// hashed as it is chosen by the user and may contain path separators.
+func dirName(id string) string {
+	h := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(h[:])
+}
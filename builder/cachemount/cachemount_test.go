@@ -0,0 +1,73 @@
+package cachemount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestStorePrune(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "cachemount")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewStore(filepath.Join(tmpDir, "cachemounts"), idtools.IDPair{UID: os.Getuid(), GID: os.Getgid()})
+	require.NoError(t, err)
+
+	p1, release1, err := s.Get("/root/.cache")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(p1, "foo"), []byte("data"), 0600))
+
+	// the same id maps to the same directory
+	p2, release2, err := s.Get("/root/.cache")
+	require.NoError(t, err)
+	assert.Equal(t, p1, p2)
+
+	p3, release3, err := s.Get("other")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(p3, "bar"), []byte("bar"), 0600))
+	release3()
+
+	size, err := s.DiskUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), size)
+
+	// directories in use are kept
+	reclaimed, err := s.Prune(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), reclaimed)
+	_, err = os.Stat(p3)
+	assert.True(t, os.IsNotExist(err))
+
+	release1()
+	release1() // release is idempotent
+	reclaimed, err = s.Prune(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), reclaimed)
+
+	release2()
+	reclaimed, err = s.Prune(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), reclaimed)
+
+	size, err = s.DiskUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+}
+
+func TestStoreGetEmptyID(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "cachemount")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewStore(tmpDir, idtools.IDPair{UID: os.Getuid(), GID: os.Getgid()})
+	require.NoError(t, err)
+	_, _, err = s.Get("")
+	assert.Error(t, err)
+}
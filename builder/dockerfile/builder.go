@@ -6,13 +6,16 @@ import (
 	"io"
 	"io/ioutil"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/cachemount"
 	"github.com/docker/docker/builder/dockerfile/instructions"
 	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/builder/fscache"
@@ -52,21 +55,29 @@ type SessionGetter interface {
 
 // BuildManager is shared across all Builder objects
 type BuildManager struct {
-	idMappings *idtools.IDMappings
-	backend    builder.Backend
-	pathCache  pathCache // TODO: make this persistent
-	sg         SessionGetter
-	fsCache    *fscache.FSCache
+	idMappings  *idtools.IDMappings
+	backend     builder.Backend
+	pathCache   pathCache // TODO: make this persistent
+	sg          SessionGetter
+	fsCache     *fscache.FSCache
+	cacheMounts *cachemount.Store
+	secrets     map[string]string
+	hostNetwork bool
 }
 
-// NewBuildManager creates a BuildManager
-func NewBuildManager(b builder.Backend, sg SessionGetter, fsCache *fscache.FSCache, idMappings *idtools.IDMappings) (*BuildManager, error) {
+// NewBuildManager creates a BuildManager. secrets are the files on the daemon
+// host that can be mounted as build secrets, by ID. hostNetwork allows the
+// RUN instructions to use the host network with --network=host.
+func NewBuildManager(b builder.Backend, sg SessionGetter, fsCache *fscache.FSCache, cacheMounts *cachemount.Store, secrets map[string]string, hostNetwork bool, idMappings *idtools.IDMappings) (*BuildManager, error) {
 	bm := &BuildManager{
-		backend:    b,
-		pathCache:  &syncmap.Map{},
-		sg:         sg,
-		idMappings: idMappings,
-		fsCache:    fsCache,
+		backend:     b,
+		pathCache:   &syncmap.Map{},
+		sg:          sg,
+		idMappings:  idMappings,
+		fsCache:     fsCache,
+		cacheMounts: cacheMounts,
+		secrets:     secrets,
+		hostNetwork: hostNetwork,
 	}
 	if err := fsCache.RegisterTransport(remotecontext.ClientSessionRemote, NewClientSessionTransport()); err != nil {
 		return nil, err
@@ -105,27 +116,39 @@ func (bm *BuildManager) Build(ctx context.Context, config backend.BuildConfig) (
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	if src, err := bm.initializeClientSession(ctx, cancel, config.Options); err != nil {
+	sourceDateEpoch, err := parseSourceDateEpoch(config.Options.SourceDateEpoch)
+	if err != nil {
 		return nil, err
-	} else if src != nil {
+	}
+
+	src, caller, err := bm.initializeClientSession(ctx, cancel, config.Options)
+	if err != nil {
+		return nil, err
+	}
+	if src != nil {
 		source = src
 	}
 
 	builderOptions := builderOptions{
-		Options:        config.Options,
-		ProgressWriter: config.ProgressWriter,
-		Backend:        bm.backend,
-		PathCache:      bm.pathCache,
-		IDMappings:     bm.idMappings,
-		Platform:       dockerfile.Platform,
+		Options:         config.Options,
+		ProgressWriter:  config.ProgressWriter,
+		Backend:         bm.backend,
+		PathCache:       bm.pathCache,
+		IDMappings:      bm.idMappings,
+		CacheMounts:     bm.cacheMounts,
+		Session:         caller,
+		Secrets:         bm.secrets,
+		HostNetwork:     bm.hostNetwork,
+		SourceDateEpoch: sourceDateEpoch,
+		Platform:        dockerfile.Platform,
 	}
 
 	return newBuilder(ctx, builderOptions).build(source, dockerfile)
 }
 
-func (bm *BuildManager) initializeClientSession(ctx context.Context, cancel func(), options *types.ImageBuildOptions) (builder.Source, error) {
+func (bm *BuildManager) initializeClientSession(ctx context.Context, cancel func(), options *types.ImageBuildOptions) (builder.Source, session.Caller, error) {
 	if options.SessionID == "" || bm.sg == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 	logrus.Debug("client is session enabled")
 
@@ -134,7 +157,7 @@ func (bm *BuildManager) initializeClientSession(ctx context.Context, cancel func
 
 	c, err := bm.sg.Get(ctx, options.SessionID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	go func() {
 		<-c.Context().Done()
@@ -144,26 +167,45 @@ func (bm *BuildManager) initializeClientSession(ctx context.Context, cancel func
 		st := time.Now()
 		csi, err := NewClientSessionSourceIdentifier(ctx, bm.sg, options.SessionID)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		src, err := bm.fsCache.SyncFrom(ctx, csi)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		logrus.Debugf("sync-time: %v", time.Since(st))
-		return src, nil
+		return src, c, nil
+	}
+	return nil, c, nil
+}
+
+// parseSourceDateEpoch parses the SOURCE_DATE_EPOCH of a build, a number of
+// seconds since the Unix epoch, if set.
+func parseSourceDateEpoch(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
 	}
-	return nil, nil
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return nil, validationError{errors.Errorf("invalid SOURCE_DATE_EPOCH %q: must be a non-negative number of seconds", value)}
+	}
+	epoch := time.Unix(seconds, 0).UTC()
+	return &epoch, nil
 }
 
 // builderOptions are the dependencies required by the builder
 type builderOptions struct {
-	Options        *types.ImageBuildOptions
-	Backend        builder.Backend
-	ProgressWriter backend.ProgressWriter
-	PathCache      pathCache
-	IDMappings     *idtools.IDMappings
-	Platform       string
+	Options         *types.ImageBuildOptions
+	Backend         builder.Backend
+	ProgressWriter  backend.ProgressWriter
+	PathCache       pathCache
+	IDMappings      *idtools.IDMappings
+	CacheMounts     *cachemount.Store
+	Session         session.Caller
+	Secrets         map[string]string
+	HostNetwork     bool
+	SourceDateEpoch *time.Time
+	Platform        string
 }
 
 // Builder is a Dockerfile builder
@@ -180,11 +222,18 @@ type Builder struct {
 	clientCtx context.Context
 
 	idMappings       *idtools.IDMappings
+	cacheMounts      *cachemount.Store
+	session          session.Caller
+	secrets          map[string]string
+	hostNetwork      bool
 	disableCommit    bool
 	imageSources     *imageSources
 	pathCache        pathCache
 	containerManager *containerManager
 	imageProber      ImageProber
+	steps            *stepReporter
+	stageName        string
+	sourceDateEpoch  *time.Time
 
 	// TODO @jhowardmft LCOW Support. This will be moved to options at a later
 	// stage, however that cannot be done now as it affects the public API
@@ -211,19 +260,33 @@ func newBuilder(clientCtx context.Context, options builderOptions) *Builder {
 		options.Platform = "linux"
 	}
 
+	// the stages of the build may write their output concurrently, so all
+	// the writes to the client go through the same lock
+	outputMu := &sync.Mutex{}
+	var aux *streamformatter.AuxFormatter
+	if options.ProgressWriter.AuxFormatter != nil {
+		aux = &streamformatter.AuxFormatter{Writer: newLockedWriter(outputMu, options.ProgressWriter.AuxFormatter.Writer)}
+	}
+
 	b := &Builder{
 		clientCtx:        clientCtx,
 		options:          config,
-		Stdout:           options.ProgressWriter.StdoutFormatter,
-		Stderr:           options.ProgressWriter.StderrFormatter,
-		Aux:              options.ProgressWriter.AuxFormatter,
-		Output:           options.ProgressWriter.Output,
+		Stdout:           newLockedWriter(outputMu, options.ProgressWriter.StdoutFormatter),
+		Stderr:           newLockedWriter(outputMu, options.ProgressWriter.StderrFormatter),
+		Aux:              aux,
+		Output:           newLockedWriter(outputMu, options.ProgressWriter.Output),
 		docker:           options.Backend,
 		idMappings:       options.IDMappings,
+		cacheMounts:      options.CacheMounts,
+		session:          options.Session,
+		secrets:          options.Secrets,
+		hostNetwork:      options.HostNetwork,
 		imageSources:     newImageSources(clientCtx, options),
 		pathCache:        options.PathCache,
 		imageProber:      newImageProber(options.Backend, config.CacheFrom, options.Platform, config.NoCache),
 		containerManager: newContainerManager(options.Backend),
+		steps:            newStepReporter(aux, config.ProgressEvents),
+		sourceDateEpoch:  options.SourceDateEpoch,
 		platform:         options.Platform,
 	}
 
@@ -236,6 +299,9 @@ func (b *Builder) build(source builder.Source, dockerfile *parser.Result) (*buil
 	defer b.imageSources.Unmount()
 
 	addNodesForLabelOption(dockerfile.AST, b.options.Labels)
+	if b.options.Check {
+		return b.checkDockerfile(dockerfile)
+	}
 
 	stages, metaArgs, err := instructions.Parse(dockerfile.AST)
 	if err != nil {
@@ -253,6 +319,10 @@ func (b *Builder) build(source builder.Source, dockerfile *parser.Result) (*buil
 		stages = stages[:targetIx+1]
 	}
 
+	if err := b.importBuildCaches(); err != nil {
+		return nil, err
+	}
+
 	dockerfile.PrintWarnings(b.Stderr)
 	dispatchState, err := b.dispatchDockerfileWithCancellation(stages, metaArgs, dockerfile.EscapeToken, source)
 	if err != nil {
@@ -265,6 +335,74 @@ func (b *Builder) build(source builder.Source, dockerfile *parser.Result) (*buil
 	return &builder.Result{ImageID: dispatchState.imageID, FromImage: dispatchState.baseImage}, nil
 }
 
+// importBuildCaches adds the build caches of the cache-from locations to the
+// image cache. A build cache that cannot be imported is skipped with a
+// warning, like the cache-from images that cannot be found.
+func (b *Builder) importBuildCaches() error {
+	var imported []builder.ImageCache
+	for _, s := range b.options.CacheFrom {
+		if !builder.IsCacheSpec(s) {
+			continue
+		}
+		spec, err := builder.ParseCacheSpec(s)
+		if err != nil {
+			return validationError{err}
+		}
+		if b.options.NoCache {
+			continue
+		}
+		fmt.Fprintf(b.Stdout, "Importing build cache from %s\n", spec)
+		c, err := b.docker.ImportBuildCache(b.clientCtx, spec, b.buildCacheOptions())
+		if err != nil {
+			fmt.Fprintf(b.Stderr, "[Warning] Could not import build cache from %s, skipping: %v\n", spec, err)
+			continue
+		}
+		imported = append(imported, c)
+	}
+	if len(imported) > 0 {
+		b.imageProber = newImageProber(b.docker, b.options.CacheFrom, b.platform, b.options.NoCache, imported...)
+	}
+	return nil
+}
+
+// exportBuildCaches exports the images created by the stages to the
+// cache-to locations.
+func (b *Builder) exportBuildCaches(states []*dispatchState) error {
+	if len(b.options.CacheTo) == 0 {
+		return nil
+	}
+	var chains []builder.CacheChain
+	for _, state := range states {
+		if state == nil || state.imageID == "" {
+			continue
+		}
+		chain := builder.CacheChain{Top: state.imageID}
+		if state.baseImage != nil {
+			chain.Base = state.baseImage.ImageID()
+		}
+		chains = append(chains, chain)
+	}
+	for _, s := range b.options.CacheTo {
+		spec, err := builder.ParseCacheSpec(s)
+		if err != nil {
+			return validationError{err}
+		}
+		fmt.Fprintf(b.Stdout, "Exporting build cache to %s\n", spec)
+		if err := b.docker.ExportBuildCache(b.clientCtx, spec, chains, b.buildCacheOptions()); err != nil {
+			return errors.Wrapf(err, "failed to export build cache to %s", spec)
+		}
+	}
+	return nil
+}
+
+func (b *Builder) buildCacheOptions() backend.BuildCacheOptions {
+	return backend.BuildCacheOptions{
+		AuthConfig: b.options.AuthConfigs,
+		Output:     b.Output,
+		Platform:   b.platform,
+	}
+}
+
 func emitImageID(aux *streamformatter.AuxFormatter, state *dispatchState) error {
 	if aux == nil || state.imageID == "" {
 		return nil
@@ -292,71 +430,190 @@ func printCommand(out io.Writer, currentCommandIndex int, totalCommands int, cmd
 }
 
 func (b *Builder) dispatchDockerfileWithCancellation(parseResult []instructions.Stage, metaArgs []instructions.ArgCommand, escapeToken rune, source builder.Source) (*dispatchState, error) {
-	dispatchRequest := dispatchRequest{}
 	buildArgs := newBuildArgs(b.options.BuildArgs)
-	totalCommands := len(metaArgs) + len(parseResult)
-	currentCommandIndex := 1
-	for _, stage := range parseResult {
-		totalCommands += len(stage.Commands)
-	}
 	shlex := NewShellLex(escapeToken)
 	for _, meta := range metaArgs {
-		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, &meta)
-
 		err := processMetaArg(meta, shlex, buildArgs)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	stagesResults := newStagesBuildResults()
+	graph, err := newStageGraph(parseResult, shlex, convertMapToEnvList(buildArgs.GetAllMeta()), b.options.Target != "")
+	if err != nil {
+		return nil, err
+	}
 
-	for _, stage := range parseResult {
-		if err := stagesResults.checkStageNameAvailable(stage.Name); err != nil {
-			return nil, err
-		}
-		dispatchRequest = newDispatchRequest(b, escapeToken, source, buildArgs, stagesResults)
+	totalCommands := len(metaArgs) + graph.commandCount()
+	currentCommandIndex := 1
+	for _, meta := range metaArgs {
+		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, &meta)
+	}
 
-		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, stage.SourceCode)
-		if err := initializeStage(dispatchRequest, &stage); err != nil {
-			return nil, err
+	dispatchState, states, err := b.dispatchStages(graph, escapeToken, source, buildArgs, currentCommandIndex, totalCommands)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.exportBuildCaches(states); err != nil {
+		return nil, err
+	}
+	if b.options.Remove {
+		b.containerManager.RemoveAll(b.Stdout)
+	}
+	buildArgs.WarnOnUnusedBuildArgs(b.Stdout)
+	return dispatchState, nil
+}
+
+// dispatchStages builds the stages of the graph, each one as soon as the
+// stages it depends on are built. The output of the stages is prefixed with
+// their name when there are several of them. It returns the state of the
+// last stage, and the states of all the stages built.
+func (b *Builder) dispatchStages(graph *stageGraph, escapeToken rune, source builder.Source, buildArgs *buildArgs, currentCommandIndex, totalCommands int) (*dispatchState, []*dispatchState, error) {
+	ctx, cancel := context.WithCancel(b.clientCtx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex // protects buildArgs, states and firstErr
+		states   = make([]*dispatchState, len(graph.stages))
+		firstErr error
+		done     = make([]chan struct{}, len(graph.stages))
+		last     = len(graph.stages) - 1
+		prefix   = graph.count() > 1
+	)
+	for i := range graph.stages {
+		done[i] = make(chan struct{})
+	}
+
+	for i := range graph.stages {
+		if !graph.needed[i] {
+			continue
 		}
-		dispatchRequest.state.updateRunConfig()
-		fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
-		for _, cmd := range stage.Commands {
-			select {
-			case <-b.clientCtx.Done():
-				logrus.Debug("Builder: build cancelled!")
-				fmt.Fprint(b.Stdout, "Build cancelled\n")
-				buildsFailed.WithValues(metricsBuildCanceled).Inc()
-				return nil, errors.New("Build cancelled")
-			default:
-				// Not cancelled yet, keep going...
+		wg.Add(1)
+		go func(i, currentCommandIndex int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, dep := range graph.deps[i] {
+				select {
+				case <-done[dep]:
+				case <-ctx.Done():
+					return
+				}
 			}
 
-			currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, cmd)
+			mu.Lock()
+			if firstErr != nil {
+				mu.Unlock()
+				return
+			}
+			stageBuilder := b.newStageBuilder(ctx, stageDisplayName(&graph.stages[i], i), prefix)
+			stages, err := stagesBuildResultsFor(i, states)
+			var dispatchRequest dispatchRequest
+			if err == nil {
+				dispatchRequest = newDispatchRequest(stageBuilder, escapeToken, source, buildArgs, stages)
+			}
+			mu.Unlock()
 
-			if err := dispatch(dispatchRequest, cmd); err != nil {
-				return nil, err
+			if err == nil {
+				err = stageBuilder.dispatchStage(dispatchRequest, &graph.stages[i], currentCommandIndex, totalCommands)
 			}
+			stageBuilder.flushOutput()
 
-			dispatchRequest.state.updateRunConfig()
-			fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil && i != last {
+				err = emitImageID(b.Aux, dispatchRequest.state)
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			buildArgs.MergeReferencedArgs(dispatchRequest.state.buildArgs)
+			states[i] = dispatchRequest.state
+		}(i, currentCommandIndex)
+		currentCommandIndex += len(graph.stages[i].Commands) + 1
+	}
+	wg.Wait()
 
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if last < 0 {
+		return &dispatchState{}, nil, nil
+	}
+	if states[last] == nil {
+		buildsFailed.WithValues(metricsBuildCanceled).Inc()
+		return nil, nil, errors.New("Build cancelled")
+	}
+	// the ID of the last stage is emitted last, as it is the result of the build
+	if err := emitImageID(b.Aux, states[last]); err != nil {
+		return nil, nil, err
+	}
+	return states[last], states, nil
+}
+
+// newStageBuilder returns a copy of the builder to build a stage, possibly
+// concurrently with other stages.
+func (b *Builder) newStageBuilder(ctx context.Context, name string, prefixOutput bool) *Builder {
+	stageBuilder := *b
+	stageBuilder.clientCtx = ctx
+	stageBuilder.imageProber = b.imageProber.Clone()
+	stageBuilder.stageName = name
+	if prefixOutput {
+		stageBuilder.Stdout = newPrefixWriter(b.Stdout, name)
+		stageBuilder.Stderr = newPrefixWriter(b.Stderr, name)
+	}
+	return &stageBuilder
+}
+
+func (b *Builder) flushOutput() {
+	for _, w := range []io.Writer{b.Stdout, b.Stderr} {
+		if p, ok := w.(*prefixWriter); ok {
+			if err := p.Flush(); err != nil {
+				logrus.Debugf("[BUILDER] failed to flush stage output: %v", err)
+			}
 		}
-		if err := emitImageID(b.Aux, dispatchRequest.state); err != nil {
-			return nil, err
+	}
+}
+
+func (b *Builder) dispatchStage(dispatchRequest dispatchRequest, stage *instructions.Stage, currentCommandIndex int, totalCommands int) error {
+	endStep := b.startStep(dispatchRequest.state, currentCommandIndex, totalCommands, stage.SourceCode)
+	currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, stage.SourceCode)
+	err := initializeStage(dispatchRequest, stage)
+	endStep(err)
+	if err != nil {
+		return err
+	}
+	dispatchRequest.state.updateRunConfig()
+	fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+	for _, cmd := range stage.Commands {
+		select {
+		case <-b.clientCtx.Done():
+			logrus.Debug("Builder: build cancelled!")
+			fmt.Fprint(b.Stdout, "Build cancelled\n")
+			buildsFailed.WithValues(metricsBuildCanceled).Inc()
+			return errors.New("Build cancelled")
+		default:
+			// Not cancelled yet, keep going...
 		}
-		buildArgs.MergeReferencedArgs(dispatchRequest.state.buildArgs)
-		if err := commitStage(dispatchRequest.state, stagesResults); err != nil {
-			return nil, err
+
+		endStep := b.startStep(dispatchRequest.state, currentCommandIndex, totalCommands, cmd)
+		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, cmd)
+
+		err := dispatch(dispatchRequest, cmd)
+		endStep(err)
+		if err != nil {
+			return err
 		}
+
+		dispatchRequest.state.updateRunConfig()
+		fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
 	}
-	if b.options.Remove {
-		b.containerManager.RemoveAll(b.Stdout)
-	}
-	buildArgs.WarnOnUnusedBuildArgs(b.Stdout)
-	return dispatchRequest.state, nil
+	return nil
 }
 
 func addNodesForLabelOption(dockerfile *parser.Node, labels map[string]string) {
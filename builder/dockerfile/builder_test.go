@@ -3,6 +3,7 @@ package dockerfile
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/stretchr/testify/assert"
@@ -32,3 +33,20 @@ func TestAddNodesForLabelOption(t *testing.T) {
 		assert.Equal(t, expected[i], v.Original)
 	}
 }
+
+func TestParseSourceDateEpoch(t *testing.T) {
+	epoch, err := parseSourceDateEpoch("")
+	assert.NoError(t, err)
+	assert.Nil(t, epoch)
+
+	epoch, err = parseSourceDateEpoch("1500000000")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1500000000, 0).UTC(), *epoch)
+
+	for _, value := range []string{"-1", "yesterday", "1.5"} {
+		_, err = parseSourceDateEpoch(value)
+		assert.Error(t, err, value)
+		_, ok := err.(validationError)
+		assert.True(t, ok, value)
+	}
+}
@@ -0,0 +1,245 @@
+package dockerfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/command"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/dockerfile/parser"
+)
+
+// jsonFormCommands are the instructions accepting their arguments as a JSON
+// array
+var jsonFormCommands = map[string]bool{
+	command.Add:        true,
+	command.Cmd:        true,
+	command.Copy:       true,
+	command.Entrypoint: true,
+	command.Run:        true,
+	command.Volume:     true,
+}
+
+const emptyContinuationLineWarning = "[WARNING]: Empty continuation line found in:"
+
+// checkDockerfile validates a Dockerfile without building it, and reports
+// the problems found as warnings on the output and in the aux stream.
+func (b *Builder) checkDockerfile(dockerfile *parser.Result) (*builder.Result, error) {
+	warnings := newDockerfileChecker(dockerfile.EscapeToken, b.options).check(dockerfile)
+	for _, w := range warnings {
+		if w.Line > 0 {
+			fmt.Fprintf(b.Stdout, "[%s] line %d: %s\n", w.Rule, w.Line, w.Message)
+		} else {
+			fmt.Fprintf(b.Stdout, "[%s] %s\n", w.Rule, w.Message)
+		}
+	}
+	fmt.Fprintf(b.Stdout, "Dockerfile check found %d warning(s)\n", len(warnings))
+	if b.Aux != nil {
+		if err := b.Aux.Emit(types.BuildCheckResult{Warnings: warnings}); err != nil {
+			return nil, err
+		}
+	}
+	return &builder.Result{}, nil
+}
+
+// checkedStage is a build stage seen by the checker
+type checkedStage struct {
+	name string
+	base string // the expanded image name of its FROM
+	line int
+}
+
+// copyFrom is the --from flag of a COPY, checked once all the stages are
+// known
+type copyFrom struct {
+	from  string
+	stage int // the index of the stage of the COPY
+	line  int
+}
+
+// dockerfileChecker walks the instructions of a Dockerfile like the builder
+// does, to find the problems a build would hit without running it.
+type dockerfileChecker struct {
+	shlex     *ShellLex
+	options   *types.ImageBuildOptions
+	buildArgs *buildArgs
+	stages    []checkedStage
+	stageArgs *buildArgs
+	env       []string
+	copyFroms []copyFrom
+	warnings  []types.BuildWarning
+}
+
+func newDockerfileChecker(escapeToken rune, options *types.ImageBuildOptions) *dockerfileChecker {
+	return &dockerfileChecker{
+		shlex:     NewShellLex(escapeToken),
+		options:   options,
+		buildArgs: newBuildArgs(options.BuildArgs),
+		warnings:  []types.BuildWarning{},
+	}
+}
+
+func (c *dockerfileChecker) warn(line int, rule, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, types.BuildWarning{
+		Rule:    rule,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *dockerfileChecker) check(dockerfile *parser.Result) []types.BuildWarning {
+	for _, w := range dockerfile.Warnings {
+		if strings.HasPrefix(w, emptyContinuationLineWarning) {
+			c.warn(0, "EmptyContinuationLine", "empty continuation line found in: %s", strings.TrimSpace(strings.TrimPrefix(w, emptyContinuationLineWarning)))
+		}
+	}
+
+	for _, node := range dockerfile.AST.Children {
+		c.checkNode(node)
+		cmd, err := instructions.ParseInstruction(node)
+		if err != nil {
+			switch {
+			case instructions.IsUnknownInstruction(err):
+				c.warn(node.StartLine, "UnknownInstruction", "%v", err)
+			case strings.HasPrefix(err.Error(), "Unknown flag"):
+				c.warn(node.StartLine, "UnknownFlag", "%s: %v", strings.ToUpper(node.Value), err)
+			default:
+				c.warn(node.StartLine, "InvalidInstruction", "%s: %v", strings.ToUpper(node.Value), err)
+			}
+			continue
+		}
+		switch cmd := cmd.(type) {
+		case *instructions.Stage:
+			c.checkStage(node, cmd)
+		case *instructions.ArgCommand:
+			if len(c.stages) == 0 {
+				if err := processMetaArg(*cmd, c.shlex, c.buildArgs); err != nil {
+					c.warn(node.StartLine, "InvalidExpansion", "ARG: %v", err)
+				}
+				continue
+			}
+			c.checkCommand(node, cmd)
+		case instructions.Command:
+			c.checkCommand(node, cmd)
+		}
+	}
+
+	// a FROM may only refer to the stages defined before it
+	for i, stage := range c.stages {
+		if !c.hasStage(stage.base, i) && c.hasStage(stage.base, len(c.stages)) {
+			c.warn(stage.line, "StageNotDefined", "FROM %s refers to a build stage which is not defined before it", stage.base)
+		}
+	}
+
+	for _, f := range c.copyFroms {
+		c.checkCopyFrom(f)
+	}
+
+	if c.options.Target != "" && !c.hasStage(c.options.Target, len(c.stages)) {
+		c.warn(0, "TargetNotFound", "build target %s is not a build stage of the Dockerfile", c.options.Target)
+	}
+	return c.warnings
+}
+
+// checkNode checks the syntax of an instruction
+func (c *dockerfileChecker) checkNode(node *parser.Node) {
+	if node.Value == command.Maintainer {
+		c.warn(node.StartLine, "MaintainerDeprecated", "MAINTAINER is deprecated, use a LABEL instead, such as LABEL maintainer=...")
+	}
+	if jsonFormCommands[node.Value] && !node.Attributes["json"] && node.Next != nil && looksLikeJSON(node.Next.Value) {
+		c.warn(node.StartLine, "InvalidJSONForm", "%s arguments look like a JSON array but are not valid JSON, they are used as a string: %s", strings.ToUpper(node.Value), node.Next.Value)
+	}
+}
+
+func (c *dockerfileChecker) checkStage(node *parser.Node, stage *instructions.Stage) {
+	if stage.Name != "" && c.hasStage(stage.Name, len(c.stages)) {
+		c.warn(node.StartLine, "DuplicateStageName", "build stage name %s is already used", stage.Name)
+	}
+	substitutionArgs := convertMapToEnvList(c.buildArgs.GetAllMeta())
+	base, err := c.shlex.ProcessWord(stage.BaseName, substitutionArgs)
+	if err != nil {
+		c.warn(node.StartLine, "InvalidExpansion", "FROM: %v", err)
+	} else if base == "" {
+		c.warn(node.StartLine, "InvalidBaseImage", "FROM %s resolves to an empty image name", stage.BaseName)
+	}
+	c.stages = append(c.stages, checkedStage{name: stage.Name, base: base, line: node.StartLine})
+	c.stageArgs = c.buildArgs.Clone()
+	c.stageArgs.ResetAllowed()
+	c.env = nil
+}
+
+func (c *dockerfileChecker) checkCommand(node *parser.Node, cmd instructions.Command) {
+	if len(c.stages) == 0 {
+		c.warn(node.StartLine, "NoBuildStage", "%s is not in a build stage, the Dockerfile must start with FROM", strings.ToUpper(node.Value))
+		return
+	}
+	envs := append(append([]string{}, c.env...), c.stageArgs.FilterAllowed(c.env)...)
+	if ex, ok := cmd.(instructions.SupportsSingleWordExpansion); ok {
+		err := ex.Expand(func(word string) (string, error) {
+			return c.shlex.ProcessWord(word, envs)
+		})
+		if err != nil {
+			c.warn(node.StartLine, "InvalidExpansion", "%s: %v", strings.ToUpper(node.Value), err)
+			return
+		}
+	}
+
+	switch cmd := cmd.(type) {
+	case *instructions.ArgCommand:
+		c.stageArgs.AddArg(cmd.Key, cmd.Value)
+	case *instructions.EnvCommand:
+		for _, e := range cmd.Env {
+			c.env = append(c.env, e.String())
+		}
+	case *instructions.CopyCommand:
+		if cmd.From != "" {
+			c.copyFroms = append(c.copyFroms, copyFrom{from: cmd.From, stage: len(c.stages) - 1, line: node.StartLine})
+		}
+	}
+}
+
+// checkCopyFrom checks that the --from flag of COPY refers to a build stage
+// defined before the current one, or to a valid image reference.
+func (c *dockerfileChecker) checkCopyFrom(f copyFrom) {
+	if c.hasStage(f.from, f.stage) {
+		return
+	}
+	if ix, err := strconv.Atoi(f.from); err == nil {
+		if ix < 0 || ix >= f.stage {
+			c.warn(f.line, "StageNotDefined", "COPY --from=%s refers to build stage %d, which is not defined before the current stage", f.from, ix)
+		}
+		return
+	}
+	if c.hasStage(f.from, len(c.stages)) {
+		c.warn(f.line, "StageNotDefined", "COPY --from=%s refers to a build stage which is not defined before the current stage", f.from)
+		return
+	}
+	if _, err := reference.ParseNormalizedNamed(f.from); err != nil {
+		c.warn(f.line, "InvalidImageReference", "COPY --from=%s is neither a build stage nor a valid image reference: %v", f.from, err)
+	}
+}
+
+// hasStage returns whether one of the first n stages is named name
+func (c *dockerfileChecker) hasStage(name string, n int) bool {
+	name = strings.ToLower(name)
+	for _, s := range c.stages[:n] {
+		if s.name != "" && s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeJSON returns whether the arguments of an instruction in the shell
+// form were likely meant to be a JSON array, such as ['a', 'b'], rather than
+// a shell test such as [ -f file ].
+func looksLikeJSON(args string) bool {
+	inner := strings.TrimSpace(strings.TrimPrefix(args, "["))
+	return strings.HasPrefix(args, "[") && strings.HasSuffix(args, "]") &&
+		(strings.HasPrefix(inner, `"`) || strings.HasPrefix(inner, "'")) &&
+		strings.Contains(args, ",")
+}
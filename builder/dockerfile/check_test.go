@@ -0,0 +1,84 @@
+package dockerfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDockerfile(t *testing.T) {
+	dockerfile := `ARG BASE=busybox
+FROM ${BASE} AS build
+MAINTAINER someone
+RUN --mont=type=cache,target=/cache make
+COPY --from=test /out /out
+CMD ['make', 'test']
+RUN [ -f /out ] && echo ok
+FROM build AS test
+COPY --from=1 /out /out
+COPY --from=Invalid:Ref /out /out
+FROM test AS build
+FROM $UNSET
+ENV A=${A:?required}
+`
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+
+	warnings := newDockerfileChecker(result.EscapeToken, &types.ImageBuildOptions{Target: "release"}).check(result)
+	expected := []types.BuildWarning{
+		{Rule: "MaintainerDeprecated", Line: 3},
+		{Rule: "UnknownFlag", Line: 4},
+		{Rule: "InvalidJSONForm", Line: 6},
+		{Rule: "DuplicateStageName", Line: 11},
+		{Rule: "InvalidBaseImage", Line: 12},
+		{Rule: "InvalidExpansion", Line: 13},
+		{Rule: "StageNotDefined", Line: 5},
+		{Rule: "StageNotDefined", Line: 9},
+		{Rule: "InvalidImageReference", Line: 10},
+		{Rule: "TargetNotFound"},
+	}
+	require.Len(t, warnings, len(expected), "%v", warnings)
+	for i, w := range warnings {
+		assert.Equal(t, expected[i].Rule, w.Rule, w.Message)
+		assert.Equal(t, expected[i].Line, w.Line, w.Message)
+	}
+}
+
+func TestCheckDockerfileValid(t *testing.T) {
+	dockerfile := `ARG VERSION=1.9
+FROM golang:${VERSION} AS build
+ARG PKG=app
+ENV SRC=/go/src/${PKG}
+COPY . ${SRC}
+RUN ["go", "build", "-o", "/out/app"]
+FROM scratch
+COPY --from=build /out/app /app
+COPY --from=0 /out/app /app2
+COPY --from=busybox:latest /bin/sh /sh
+CMD ["/app"]
+`
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+
+	out := new(bytes.Buffer)
+	b := newBuilderWithMockBackend()
+	b.options.Target = "build"
+	b.Aux = &streamformatter.AuxFormatter{Writer: out}
+	_, err = b.checkDockerfile(result)
+	require.NoError(t, err)
+
+	var msg jsonmessage.JSONMessage
+	require.NoError(t, json.NewDecoder(out).Decode(&msg))
+	var checkResult types.BuildCheckResult
+	require.NoError(t, json.Unmarshal(*msg.Aux, &checkResult))
+	assert.Equal(t, []types.BuildWarning{}, checkResult.Warnings)
+	assert.Contains(t, b.Stdout.(*bytes.Buffer).String(), "Dockerfile check found 0 warning(s)")
+}
@@ -3,10 +3,12 @@ package dockerfile
 import (
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/instructions"
 	containerpkg "github.com/docker/docker/container"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/pkg/errors"
@@ -15,6 +17,7 @@ import (
 )
 
 type containerManager struct {
+	mu            sync.Mutex // protects tmpContainers
 	tmpContainers map[string]struct{}
 	backend       builder.ExecBackend
 }
@@ -37,10 +40,29 @@ func (c *containerManager) Create(runConfig *container.Config, hostConfig *conta
 	if err != nil {
 		return container, err
 	}
+	c.mu.Lock()
 	c.tmpContainers[container.ID] = struct{}{}
+	c.mu.Unlock()
 	return container, nil
 }
 
+// withRunIsolation applies the --network and --security flags of a RUN
+// instruction to the host config of its container, overriding the options of
+// the build for this step only.
+func withRunIsolation(network, security string) hostConfigModifier {
+	return func(hostConfig *container.HostConfig) {
+		switch network {
+		case instructions.NetworkNone, instructions.NetworkHost:
+			hostConfig.NetworkMode = container.NetworkMode(network)
+		}
+		if security == instructions.SecuritySandbox {
+			// drop the security options of the build, which may have
+			// relaxed the default profiles
+			hostConfig.SecurityOpt = []string{"no-new-privileges"}
+		}
+	}
+}
+
 var errCancelled = errors.New("build cancelled")
 
 // Run a container by ID
@@ -134,6 +156,8 @@ func (c *containerManager) removeContainer(containerID string, stdout io.Writer)
 
 // RemoveAll containers managed by this container manager
 func (c *containerManager) RemoveAll(stdout io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	for containerID := range c.tmpContainers {
 		if err := c.removeContainer(containerID, stdout); err != nil {
 			return
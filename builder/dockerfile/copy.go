@@ -4,10 +4,12 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -15,9 +17,12 @@ import (
 	"time"
 
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/builder/remotecontext"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/containerfs"
+	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
@@ -62,6 +67,8 @@ type copyInstruction struct {
 	infos                   []copyInfo
 	dest                    string
 	chownStr                string
+	chmodStr                string
+	excludes                []string
 	allowLocalDecompression bool
 }
 
@@ -72,6 +79,7 @@ type copier struct {
 	source      builder.Source
 	pathCache   pathCache
 	download    sourceDownloader
+	heredocs    []parser.Heredoc
 	tmpPaths    []string
 	platform    string
 }
@@ -124,6 +132,9 @@ func (o *copier) getCopyInfosForSourcePaths(sources []string, dest string) ([]co
 }
 
 func (o *copier) getCopyInfoForSourcePath(orig, dest string) ([]copyInfo, error) {
+	if h := o.heredoc(orig); h != nil {
+		return o.copyInfoForHeredoc(h)
+	}
 	if !urlutil.IsURL(orig) {
 		return o.calcCopyInfo(orig, true)
 	}
@@ -148,6 +159,48 @@ func (o *copier) getCopyInfoForSourcePath(orig, dest string) ([]copyInfo, error)
 	return newCopyInfos(ci), err
 }
 
+// heredoc returns the heredoc of the source orig, if it is a heredoc marker
+// such as <<EOF
+func (o *copier) heredoc(orig string) *parser.Heredoc {
+	name, ok := parser.ParseHeredocMarker(orig)
+	if !ok {
+		return nil
+	}
+	for i := range o.heredocs {
+		if o.heredocs[i].Name == name {
+			return &o.heredocs[i]
+		}
+	}
+	return nil
+}
+
+// copyInfoForHeredoc writes the content of a heredoc to a file named after
+// it in a temporary directory, to copy it like a downloaded file.
+func (o *copier) copyInfoForHeredoc(h *parser.Heredoc) ([]copyInfo, error) {
+	tmpDir, err := ioutils.TempDir("", "docker-heredoc")
+	if err != nil {
+		return nil, err
+	}
+	o.tmpPaths = append(o.tmpPaths, tmpDir)
+
+	tmpFileName := filepath.Join(tmpDir, h.Name)
+	if err := ioutil.WriteFile(tmpFileName, []byte(h.Content), 0644); err != nil {
+		return nil, err
+	}
+	// remove atime and mtime, so that the cache only depends on the content
+	if err := system.Chtimes(tmpFileName, time.Time{}, time.Time{}); err != nil {
+		return nil, err
+	}
+	remote, err := remotecontext.NewLazySource(containerfs.NewLocalContainerFS(tmpDir))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := remote.Hash(h.Name)
+	ci := newCopyInfoFromSource(remote, h.Name, hash)
+	ci.noDecompress = true
+	return newCopyInfos(ci), err
+}
+
 // Cleanup removes any temporary directories created as part of downloading
 // remote files.
 func (o *copier) Cleanup() {
@@ -429,6 +482,8 @@ func downloadSource(output io.Writer, stdout io.Writer, srcURL string) (remote b
 type copyFileOptions struct {
 	decompress bool
 	chownPair  idtools.IDPair
+	chmod      instructions.ChmodFunc
+	excludes   []string
 	archiver   Archiver
 }
 
@@ -457,11 +512,23 @@ func performCopyForInfo(dest copyInfo, source copyInfo, options copyFileOptions)
 	if err != nil {
 		return errors.Wrapf(err, "source path not found")
 	}
+	// the exclude patterns are relative to the source directory, or match
+	// the path of the file in the source for a file source
+	excludes, err := fileutils.NewPatternMatcher(options.excludes)
+	if err != nil {
+		return err
+	}
 	if src.IsDir() {
-		return copyDirectory(archiver, srcEndpoint, destEndpoint, options.chownPair)
+		return copyDirectory(archiver, srcEndpoint, destEndpoint, options, excludes)
+	}
+	if excluded, err := excludes.Matches(sourceRelPath(source)); err != nil || excluded {
+		return err
 	}
 	if options.decompress && isArchivePath(source.root, srcPath) && !source.noDecompress {
-		return archiver.UntarPath(srcPath, destPath)
+		if options.chmod == nil && len(options.excludes) == 0 {
+			return archiver.UntarPath(srcPath, destPath)
+		}
+		return archiver.UntarPathWithFilter(srcPath, destPath, untarFilter(options.chmod, excludes))
 	}
 
 	destExistsAsDir, err := isExistingDirectory(destEndpoint)
@@ -476,7 +543,46 @@ func performCopyForInfo(dest copyInfo, source copyInfo, options copyFileOptions)
 		destPath = dest.root.Join(destPath, source.root.Base(source.path))
 		destEndpoint = &copyEndpoint{driver: dest.root, path: destPath}
 	}
-	return copyFile(archiver, srcEndpoint, destEndpoint, options.chownPair)
+	return copyFile(archiver, srcEndpoint, destEndpoint, options)
+}
+
+// sourceRelPath returns the path of a file source relative to the root of
+// the source, which the exclude patterns are matched against.
+func sourceRelPath(source copyInfo) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(source.path)), "/")
+}
+
+// untarFilter applies the --chmod and --exclude flags of ADD to the entries
+// of the archives it extracts. The exclude patterns are relative to the root
+// of the archive, as they are to the source directory of a copy.
+func untarFilter(chmod instructions.ChmodFunc, excludes *fileutils.PatternMatcher) func(*tar.Header) (bool, error) {
+	return func(hdr *tar.Header) (bool, error) {
+		if name := path.Clean(strings.TrimPrefix(hdr.Name, "/")); name != "." {
+			excluded, err := excludes.Matches(name)
+			if err != nil || excluded {
+				return false, err
+			}
+		}
+		if chmod != nil && hdr.Typeflag != tar.TypeSymlink {
+			hdr.Mode = tarMode(chmod(hdr.FileInfo().Mode()))
+		}
+		return true, nil
+	}
+}
+
+// tarMode returns the permission bits of mode as the mode of a tar header.
+func tarMode(mode os.FileMode) int64 {
+	m := int64(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
 }
 
 func isArchivePath(driver containerfs.ContainerFS, path string) bool {
@@ -494,20 +600,21 @@ func isArchivePath(driver containerfs.ContainerFS, path string) bool {
 	return err == nil
 }
 
-func copyDirectory(archiver Archiver, source, dest *copyEndpoint, chownPair idtools.IDPair) error {
+func copyDirectory(archiver Archiver, source, dest *copyEndpoint, options copyFileOptions, excludes *fileutils.PatternMatcher) error {
 	destExists, err := isExistingDirectory(dest)
 	if err != nil {
 		return errors.Wrapf(err, "failed to query destination path")
 	}
 
-	if err := archiver.CopyWithTar(source.path, dest.path); err != nil {
+	if err := archiver.CopyWithTarExcluding(source.path, dest.path, options.excludes); err != nil {
 		return errors.Wrapf(err, "failed to copy directory")
 	}
 	// TODO: @gupta-ak. Investigate how LCOW permission mappings will work.
-	return fixPermissions(source.path, dest.path, chownPair, !destExists)
+	return fixPermissions(source.path, dest.path, options.chownPair, !destExists, options.chmod, excludes)
 }
 
-func copyFile(archiver Archiver, source, dest *copyEndpoint, chownPair idtools.IDPair) error {
+func copyFile(archiver Archiver, source, dest *copyEndpoint, options copyFileOptions) error {
+	chownPair := options.chownPair
 	if runtime.GOOS == "windows" && dest.driver.OS() == "linux" {
 		// LCOW
 		if err := dest.driver.MkdirAll(dest.driver.Dir(dest.path), 0755); err != nil {
@@ -524,7 +631,7 @@ func copyFile(archiver Archiver, source, dest *copyEndpoint, chownPair idtools.I
 		return errors.Wrapf(err, "failed to copy file")
 	}
 	// TODO: @gupta-ak. Investigate how LCOW permission mappings will work.
-	return fixPermissions(source.path, dest.path, chownPair, false)
+	return fixPermissions(source.path, dest.path, chownPair, false, options.chmod, nil)
 }
 
 func endsInSlash(driver containerfs.Driver, path string) bool {
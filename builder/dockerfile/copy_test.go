@@ -1,12 +1,15 @@
 package dockerfile
 
 import (
+	"io/ioutil"
 	"net/http"
 	"testing"
 
+	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/pkg/containerfs"
 	"github.com/gotestyourself/gotestyourself/fs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsExistingDirectory(t *testing.T) {
@@ -145,3 +148,22 @@ func TestGetFilenameForDownload(t *testing.T) {
 		assert.Equal(t, testcase.expected, filename)
 	}
 }
+
+func TestCopyInfoForHeredoc(t *testing.T) {
+	o := &copier{heredocs: []parser.Heredoc{{Name: "EOF", Content: "key = value\n"}}}
+	defer o.Cleanup()
+
+	assert.Nil(t, o.heredoc("<<OTHER"))
+	assert.Nil(t, o.heredoc("EOF"))
+	infos, err := o.getCopyInfoForSourcePath("<<-EOF", "/etc/app.conf")
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "EOF", infos[0].path)
+	assert.True(t, infos[0].noDecompress)
+
+	p, err := infos[0].fullPath()
+	require.NoError(t, err)
+	content, err := ioutil.ReadFile(p)
+	require.NoError(t, err)
+	assert.Equal(t, "key = value\n", string(content))
+}
@@ -6,11 +6,16 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/docker/docker/builder/dockerfile/instructions"
 	"github.com/docker/docker/pkg/containerfs"
+	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/idtools"
 )
 
-func fixPermissions(source, destination string, rootIDs idtools.IDPair, overrideSkip bool) error {
+// fixPermissions changes the owner, and the mode if chmod is set, of the files
+// copied from source to destination. The files of source matching excludes
+// were not copied and are left alone.
+func fixPermissions(source, destination string, rootIDs idtools.IDPair, overrideSkip bool, chmod instructions.ChmodFunc, excludes *fileutils.PatternMatcher) error {
 	var (
 		skipChownRoot bool
 		err           error
@@ -38,8 +43,27 @@ func fixPermissions(source, destination string, rootIDs idtools.IDPair, override
 			return err
 		}
 
+		if excludes != nil && cleaned != "." {
+			excluded, err := excludes.Matches(cleaned)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				if info.IsDir() && !excludes.Exclusions() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
 		fullpath = filepath.Join(destination, cleaned)
-		return os.Lchown(fullpath, rootIDs.UID, rootIDs.GID)
+		if err := os.Lchown(fullpath, rootIDs.UID, rootIDs.GID); err != nil {
+			return err
+		}
+		if chmod == nil || info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		return os.Chmod(fullpath, chmod(info.Mode()))
 	})
 }
 
@@ -0,0 +1,138 @@
+// +build !windows
+
+package dockerfile
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/containerfs"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/gotestyourself/gotestyourself/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerformCopyWithChmodAndExclude(t *testing.T) {
+	src := fs.NewDir(t, "copy-src")
+	defer src.Remove()
+	dest := fs.NewDir(t, "copy-dest")
+	defer dest.Remove()
+
+	for name, content := range map[string]string{
+		"app/run.sh":          "#!/bin/sh",
+		"app/run_test.sh":     "#!/bin/sh",
+		"app/docs/README":     "docs",
+		"app/lib/helper.sh":   "#!/bin/sh",
+		"app/lib/lib_test.sh": "#!/bin/sh",
+	} {
+		path := filepath.Join(src.Path(), name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+
+	chmod, err := instructions.ParseChmod("u+x,go+rX")
+	require.NoError(t, err)
+	options := copyFileOptions{
+		archiver: &containerfs.Archiver{
+			SrcDriver:     containerfs.NewLocalDriver(),
+			DstDriver:     containerfs.NewLocalDriver(),
+			Tar:           archive.TarWithOptions,
+			Untar:         archive.Untar,
+			IDMappingsVar: &idtools.IDMappings{},
+		},
+		chownPair: idtools.IDPair{UID: os.Getuid(), GID: os.Getgid()},
+		chmod:     chmod,
+		excludes:  []string{"docs", "**/*_test.sh"},
+	}
+	source := copyInfo{root: containerfs.NewLocalContainerFS(src.Path()), path: "app"}
+	destInfo := copyInfo{root: containerfs.NewLocalContainerFS(dest.Path()), path: "/app/"}
+	require.NoError(t, performCopyForInfo(destInfo, source, options))
+
+	for name, mode := range map[string]os.FileMode{
+		"app":               os.ModeDir | 0755,
+		"app/run.sh":        0755,
+		"app/lib":           os.ModeDir | 0755,
+		"app/lib/helper.sh": 0755,
+	} {
+		fi, err := os.Stat(filepath.Join(dest.Path(), name))
+		require.NoError(t, err)
+		assert.Equal(t, mode, fi.Mode(), name)
+	}
+	for _, name := range []string{"app/run_test.sh", "app/docs", "app/lib/lib_test.sh"} {
+		_, err := os.Stat(filepath.Join(dest.Path(), name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+
+	// the exclude patterns match the path of a file source
+	source = copyInfo{root: containerfs.NewLocalContainerFS(src.Path()), path: "app/run_test.sh"}
+	require.NoError(t, performCopyForInfo(destInfo, source, options))
+	_, err = os.Stat(filepath.Join(dest.Path(), "app/run_test.sh"))
+	assert.True(t, os.IsNotExist(err))
+
+	options.excludes = []string{"app/lib/helper.sh"}
+	source = copyInfo{root: containerfs.NewLocalContainerFS(src.Path()), path: "/app/lib/helper.sh"}
+	destInfo = copyInfo{root: containerfs.NewLocalContainerFS(dest.Path()), path: "/helper/"}
+	require.NoError(t, performCopyForInfo(destInfo, source, options))
+	_, err = os.Stat(filepath.Join(dest.Path(), "helper"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPerformCopyExtractArchiveWithChmodAndExclude(t *testing.T) {
+	src := fs.NewDir(t, "copy-src")
+	defer src.Remove()
+	dest := fs.NewDir(t, "copy-dest")
+	defer dest.Remove()
+
+	f, err := os.Create(filepath.Join(src.Path(), "app.tar"))
+	require.NoError(t, err)
+	tw := tar.NewWriter(f)
+	for _, hdr := range []*tar.Header{
+		{Name: "app/", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "app/run.sh", Typeflag: tar.TypeReg, Mode: 0600},
+		{Name: "app/run_test.sh", Typeflag: tar.TypeReg, Mode: 0600},
+		{Name: "app/docs/", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "app/docs/README", Typeflag: tar.TypeReg, Mode: 0600},
+	} {
+		require.NoError(t, tw.WriteHeader(hdr))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, f.Close())
+
+	chmod, err := instructions.ParseChmod("u+x,go+rX")
+	require.NoError(t, err)
+	options := copyFileOptions{
+		decompress: true,
+		archiver: &containerfs.Archiver{
+			SrcDriver:     containerfs.NewLocalDriver(),
+			DstDriver:     containerfs.NewLocalDriver(),
+			Tar:           archive.TarWithOptions,
+			Untar:         archive.Untar,
+			IDMappingsVar: &idtools.IDMappings{},
+		},
+		chownPair: idtools.IDPair{UID: os.Getuid(), GID: os.Getgid()},
+		chmod:     chmod,
+		excludes:  []string{"app/docs", "**/*_test.sh"},
+	}
+	source := copyInfo{root: containerfs.NewLocalContainerFS(src.Path()), path: "app.tar"}
+	destInfo := copyInfo{root: containerfs.NewLocalContainerFS(dest.Path()), path: "/"}
+	require.NoError(t, performCopyForInfo(destInfo, source, options))
+
+	for name, mode := range map[string]os.FileMode{
+		"app":        os.ModeDir | 0755,
+		"app/run.sh": 0755,
+	} {
+		fi, err := os.Stat(filepath.Join(dest.Path(), name))
+		require.NoError(t, err)
+		assert.Equal(t, mode, fi.Mode(), name)
+	}
+	for _, name := range []string{"app/run_test.sh", "app/docs"} {
+		_, err := os.Stat(filepath.Join(dest.Path(), name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/idtools"
 )
 
@@ -13,8 +15,8 @@ var pathBlacklist = map[string]bool{
 	"c:\\windows": true,
 }
 
-func fixPermissions(source, destination string, rootIDs idtools.IDPair, overrideSkip bool) error {
-	// chown is not supported on Windows
+func fixPermissions(source, destination string, rootIDs idtools.IDPair, overrideSkip bool, chmod instructions.ChmodFunc, excludes *fileutils.PatternMatcher) error {
+	// chown and chmod are not supported on Windows
 	return nil
 }
 
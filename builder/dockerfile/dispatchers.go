@@ -9,6 +9,7 @@ package dockerfile
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"runtime"
 	"sort"
@@ -99,6 +100,8 @@ func dispatchAdd(d dispatchRequest, c *instructions.AddCommand) error {
 		return err
 	}
 	copyInstruction.chownStr = c.Chown
+	copyInstruction.chmodStr = c.Chmod
+	copyInstruction.excludes = c.Exclude
 	copyInstruction.allowLocalDecompression = true
 
 	return d.builder.performCopy(d.state, copyInstruction)
@@ -118,12 +121,15 @@ func dispatchCopy(d dispatchRequest, c *instructions.CopyCommand) error {
 		}
 	}
 	copier := copierFromDispatchRequest(d, errOnSourceDownload, im)
+	copier.heredocs = c.Heredocs
 	defer copier.Cleanup()
 	copyInstruction, err := copier.createCopyInstruction(c.SourcesAndDest, "COPY")
 	if err != nil {
 		return err
 	}
 	copyInstruction.chownStr = c.Chown
+	copyInstruction.chmodStr = c.Chmod
+	copyInstruction.excludes = c.Exclude
 
 	return d.builder.performCopy(d.state, copyInstruction)
 }
@@ -301,6 +307,9 @@ func resolveCmdLine(cmd instructions.ShellDependantCmdLine, runConfig *container
 // RUN [ "echo", "hi" ] # echo hi
 //
 func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
+	if c.Network == instructions.NetworkHost && !d.builder.hostNetwork {
+		return errors.New("RUN --network=host is not allowed, the daemon must be started with --build-allow-host-network")
+	}
 
 	stateRunConfig := d.state.runConfig
 	cmdFromArgs := resolveCmdLine(c.ShellDependantCmdLine, stateRunConfig, d.builder.platform)
@@ -310,6 +319,7 @@ func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
 	if len(buildArgs) > 0 {
 		saveCmd = prependEnvOnCmd(d.state.buildArgs, buildArgs, cmdFromArgs)
 	}
+	saveCmd = prependRunOptionsOnCmd(c, saveCmd)
 
 	runConfigForCacheProbe := copyRunConfig(stateRunConfig,
 		withCmd(saveCmd),
@@ -327,8 +337,14 @@ func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
 	// set config as already being escaped, this prevents double escaping on windows
 	runConfig.ArgsEscaped = true
 
+	mounts, release, err := d.builder.runMounts(c.Mounts)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	logrus.Debugf("[BUILDER] Command to be executed: %v", runConfig.Cmd)
-	cID, err := d.builder.create(runConfig)
+	cID, err := d.builder.create(runConfig, withMounts(mounts), withRunIsolation(c.Network, c.Security))
 	if err != nil {
 		return err
 	}
@@ -373,6 +389,41 @@ func prependEnvOnCmd(buildArgs *buildArgs, buildArgVars []string, cmd strslice.S
 	return strslice.StrSlice(append(tmpEnv, cmd...))
 }
 
+// Derive the command to use for probeCache() and to commit from the options
+// of a RUN instruction which change its result, so that the same command run
+// with other mounts or another network or security mode is not a cache hit.
+// Each option is an argument starting with "|--", before the build-time env
+// vars, which can't be mistaken for a command or for the number of build-time
+// env vars.
+func prependRunOptionsOnCmd(c *instructions.RunCommand, cmd strslice.StrSlice) strslice.StrSlice {
+	var opts []string
+	for _, m := range c.Mounts {
+		opts = append(opts, "|--mount="+mountSpec(m))
+	}
+	if c.Network != "" && c.Network != instructions.NetworkDefault {
+		opts = append(opts, "|--network="+c.Network)
+	}
+	if c.Security != "" {
+		opts = append(opts, "|--security="+c.Security)
+	}
+	if len(opts) == 0 {
+		return cmd
+	}
+	return strslice.StrSlice(append(opts, cmd...))
+}
+
+// mountSpec returns the canonical form of the --mount flag of m.
+func mountSpec(m *instructions.Mount) string {
+	spec := fmt.Sprintf("type=%s,target=%s,id=%s", m.Type, m.Target, m.ID)
+	if m.ReadOnly {
+		spec += ",ro"
+	}
+	if m.Type == instructions.MountTypeSecret {
+		spec += fmt.Sprintf(",uid=%d,gid=%d,mode=%o", m.UID, m.GID, m.Mode)
+	}
+	return spec
+}
+
 // CMD foo
 //
 // Set the default command to run in the container (which may be empty).
@@ -410,7 +461,17 @@ func dispatchHealthcheck(d dispatchRequest, c *instructions.HealthCheckCommand)
 		}
 	}
 	runConfig.Healthcheck = c.Health
-	return d.builder.commit(d.state, fmt.Sprintf("HEALTHCHECK %q", runConfig.Healthcheck))
+	comment := fmt.Sprintf("HEALTHCHECK %q", runConfig.Healthcheck)
+	if c.Health.HTTP != nil || c.Health.TCP != nil {
+		// nested probe configurations would be formatted as pointers,
+		// which would change the cache key on every build
+		b, err := json.Marshal(c.Health)
+		if err != nil {
+			return err
+		}
+		comment = "HEALTHCHECK " + string(b)
+	}
+	return d.builder.commit(d.state, comment)
 }
 
 // ENTRYPOINT /usr/sbin/nginx
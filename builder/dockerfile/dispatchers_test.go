@@ -3,15 +3,22 @@ package dockerfile
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/cachemount"
 	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/internal/testutil"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
@@ -280,6 +287,23 @@ func TestHealthcheckCmd(t *testing.T) {
 	assert.Equal(t, expectedTest, sb.state.runConfig.Healthcheck.Test)
 }
 
+func TestHealthcheckHTTP(t *testing.T) {
+	b := newBuilderWithMockBackend()
+	sb := newDispatchRequest(b, '`', nil, newBuildArgs(make(map[string]*string)), newStagesBuildResults())
+	cmd := &instructions.HealthCheckCommand{
+		Health: &container.HealthConfig{
+			Test: []string{"HTTP"},
+			HTTP: &container.HealthHTTPConfig{Port: 8080, Path: "/healthz"},
+		},
+	}
+	err := dispatch(sb, cmd)
+	require.NoError(t, err)
+
+	require.NotNil(t, sb.state.runConfig.Healthcheck)
+	require.NotNil(t, sb.state.runConfig.Healthcheck.HTTP)
+	assert.Equal(t, 8080, sb.state.runConfig.Healthcheck.HTTP.Port)
+}
+
 func TestEntrypoint(t *testing.T) {
 	b := newBuilderWithMockBackend()
 	sb := newDispatchRequest(b, '`', nil, newBuildArgs(make(map[string]*string)), newStagesBuildResults())
@@ -408,6 +432,31 @@ func TestPrependEnvOnCmd(t *testing.T) {
 	assert.Equal(t, expected, cmdWithEnv)
 }
 
+func TestPrependRunOptionsOnCmd(t *testing.T) {
+	cmd := strslice.StrSlice{"|1", "FOO=bar", "go", "build"}
+	assert.Equal(t, cmd, prependRunOptionsOnCmd(&instructions.RunCommand{}, cmd))
+
+	c := &instructions.RunCommand{
+		Mounts: []*instructions.Mount{
+			{Type: instructions.MountTypeCache, Target: "/root/.cache", ID: "go"},
+			{Type: instructions.MountTypeSecret, Target: "/run/secrets/npmrc", ID: "npmrc", ReadOnly: true, Mode: 0400},
+		},
+		Network:  instructions.NetworkNone,
+		Security: instructions.SecuritySandbox,
+	}
+	expected := strslice.StrSlice{
+		"|--mount=type=cache,target=/root/.cache,id=go",
+		"|--mount=type=secret,target=/run/secrets/npmrc,id=npmrc,ro,uid=0,gid=0,mode=400",
+		"|--network=none",
+		"|--security=sandbox",
+		"|1", "FOO=bar", "go", "build",
+	}
+	assert.Equal(t, expected, prependRunOptionsOnCmd(c, cmd))
+
+	c = &instructions.RunCommand{Network: instructions.NetworkDefault}
+	assert.Equal(t, cmd, prependRunOptionsOnCmd(c, cmd))
+}
+
 func TestRunWithBuildArgs(t *testing.T) {
 	b := newBuilderWithMockBackend()
 	args := newBuildArgs(make(map[string]*string))
@@ -470,3 +519,99 @@ func TestRunWithBuildArgs(t *testing.T) {
 	// Check that runConfig.Cmd has not been modified by run
 	assert.Equal(t, origCmd, sb.state.runConfig.Cmd)
 }
+
+func TestRunWithCacheMount(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("RUN --mount is not supported on Windows")
+	}
+	tmpDir, err := ioutil.TempDir("", "builder-cachemounts")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	b := newBuilderWithMockBackend()
+	b.cacheMounts, err = cachemount.NewStore(tmpDir, idtools.IDPair{UID: os.Getuid(), GID: os.Getgid()})
+	require.NoError(t, err)
+	sb := newDispatchRequest(b, '`', nil, newBuildArgs(make(map[string]*string)), newStagesBuildResults())
+
+	var mounts []mount.Mount
+	mockBackend := b.docker.(*MockBackend)
+	b.imageProber = newImageProber(mockBackend, nil, runtime.GOOS, true)
+	mockBackend.containerCreateFunc = func(config types.ContainerCreateConfig) (container.ContainerCreateCreatedBody, error) {
+		mounts = config.HostConfig.Mounts
+		return container.ContainerCreateCreatedBody{ID: "12345"}, nil
+	}
+	run := &instructions.RunCommand{
+		ShellDependantCmdLine: instructions.ShellDependantCmdLine{
+			CmdLine:      strslice.StrSlice{"go build"},
+			PrependShell: true,
+		},
+		Mounts: []*instructions.Mount{{Type: instructions.MountTypeCache, Target: "/go/pkg", ID: "go"}},
+	}
+	require.NoError(t, dispatch(sb, run))
+
+	require.Len(t, mounts, 1)
+	assert.Equal(t, mount.TypeBind, mounts[0].Type)
+	assert.Equal(t, "/go/pkg", mounts[0].Target)
+	assert.Equal(t, tmpDir, filepath.Dir(mounts[0].Source))
+	// the cache mount is released once the instruction has run
+	reclaimed, err := b.cacheMounts.Prune(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), reclaimed)
+	_, err = os.Stat(mounts[0].Source)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunNetworkAndSecurity(t *testing.T) {
+	b := newBuilderWithMockBackend()
+	b.hostNetwork = true
+	b.options.NetworkMode = "build-net"
+	b.options.SecurityOpt = []string{"seccomp=unconfined"}
+	sb := newDispatchRequest(b, '`', nil, newBuildArgs(make(map[string]*string)), newStagesBuildResults())
+
+	var hostConfig *container.HostConfig
+	mockBackend := b.docker.(*MockBackend)
+	b.imageProber = newImageProber(mockBackend, nil, runtime.GOOS, true)
+	mockBackend.containerCreateFunc = func(config types.ContainerCreateConfig) (container.ContainerCreateCreatedBody, error) {
+		hostConfig = config.HostConfig
+		return container.ContainerCreateCreatedBody{ID: "12345"}, nil
+	}
+
+	cases := []struct {
+		network, security   string
+		expectedNetworkMode container.NetworkMode
+		expectedSecurityOpt []string
+	}{
+		{"", "", "build-net", []string{"seccomp=unconfined"}},
+		{instructions.NetworkDefault, "", "build-net", []string{"seccomp=unconfined"}},
+		{instructions.NetworkNone, "", "none", []string{"seccomp=unconfined"}},
+		{instructions.NetworkHost, instructions.SecuritySandbox, "host", []string{"no-new-privileges"}},
+	}
+	for _, c := range cases {
+		run := &instructions.RunCommand{
+			ShellDependantCmdLine: instructions.ShellDependantCmdLine{
+				CmdLine:      strslice.StrSlice{"make test"},
+				PrependShell: true,
+			},
+			Network:  c.network,
+			Security: c.security,
+		}
+		require.NoError(t, dispatch(sb, run))
+		assert.Equal(t, c.expectedNetworkMode, hostConfig.NetworkMode)
+		assert.Equal(t, c.expectedSecurityOpt, hostConfig.SecurityOpt)
+	}
+	// the options of the build are left untouched
+	assert.Equal(t, []string{"seccomp=unconfined"}, b.options.SecurityOpt)
+}
+
+func TestRunHostNetworkNotAllowed(t *testing.T) {
+	b := newBuilderWithMockBackend()
+	sb := newDispatchRequest(b, '`', nil, newBuildArgs(make(map[string]*string)), newStagesBuildResults())
+	run := &instructions.RunCommand{
+		ShellDependantCmdLine: instructions.ShellDependantCmdLine{
+			CmdLine:      strslice.StrSlice{"make test"},
+			PrependShell: true,
+		},
+		Network: instructions.NetworkHost,
+	}
+	testutil.ErrorContains(t, dispatch(sb, run), "RUN --network=host is not allowed")
+}
@@ -51,6 +51,14 @@ func dispatch(d dispatchRequest, cmd instructions.Command) error {
 			return validationError{err}
 		}
 	}
+	if ex, ok := cmd.(instructions.SupportsHeredocExpansion); ok {
+		err := ex.ExpandHeredocs(func(content string) (string, error) {
+			return d.shlex.ProcessHeredoc(content, envs)
+		})
+		if err != nil {
+			return validationError{err}
+		}
+	}
 
 	if d.builder.options.ForceRemove {
 		defer d.builder.containerManager.RemoveAll(d.builder.Stdout)
@@ -104,6 +112,8 @@ type dispatchState struct {
 	baseImage  builder.Image
 	stageName  string
 	buildArgs  *buildArgs
+	// cached is whether the image of the last step was found in the cache
+	cached bool
 }
 
 func newDispatchState(baseArgs *buildArgs) *dispatchState {
@@ -152,15 +162,6 @@ func (r *stagesBuildResults) get(nameOrIndex string) (*container.Config, error)
 	return r.flat[ix], nil
 }
 
-func (r *stagesBuildResults) checkStageNameAvailable(name string) error {
-	if name != "" {
-		if _, ok := r.getByName(name); ok {
-			return errors.Errorf("%s stage name already used", name)
-		}
-	}
-	return nil
-}
-
 func (r *stagesBuildResults) commitStage(name string, config *container.Config) error {
 	if name != "" {
 		if _, ok := r.getByName(name); ok {
@@ -1,6 +1,8 @@
 package dockerfile
 
 import (
+	"sync"
+
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/remotecontext"
@@ -15,6 +17,7 @@ type getAndMountFunc func(string, bool) (builder.Image, builder.ReleaseableLayer
 // imageSources mounts images and provides a cache for mounted images. It tracks
 // all images so they can be unmounted at the end of the build.
 type imageSources struct {
+	mu        sync.Mutex // protects byImageID and mounts
 	byImageID map[string]*imageMount
 	mounts    []*imageMount
 	getImage  getAndMountFunc
@@ -33,10 +36,11 @@ func newImageSources(ctx context.Context, options builderOptions) *imageSources
 			}
 		}
 		return options.Backend.GetImageAndReleasableLayer(ctx, idOrRef, backend.GetImageAndLayerOptions{
-			PullOption: pullOption,
-			AuthConfig: options.Options.AuthConfigs,
-			Output:     options.ProgressWriter.Output,
-			Platform:   options.Platform,
+			PullOption:      pullOption,
+			AuthConfig:      options.Options.AuthConfigs,
+			Output:          options.ProgressWriter.Output,
+			Platform:        options.Platform,
+			SourceDateEpoch: options.SourceDateEpoch,
 		})
 	}
 
@@ -47,7 +51,10 @@ func newImageSources(ctx context.Context, options builderOptions) *imageSources
 }
 
 func (m *imageSources) Get(idOrRef string, localOnly bool) (*imageMount, error) {
-	if im, ok := m.byImageID[idOrRef]; ok {
+	m.mu.Lock()
+	im, ok := m.byImageID[idOrRef]
+	m.mu.Unlock()
+	if ok {
 		return im, nil
 	}
 
@@ -55,12 +62,14 @@ func (m *imageSources) Get(idOrRef string, localOnly bool) (*imageMount, error)
 	if err != nil {
 		return nil, err
 	}
-	im := newImageMount(image, layer)
+	im = newImageMount(image, layer)
 	m.Add(im)
 	return im, nil
 }
 
 func (m *imageSources) Unmount() (retErr error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, im := range m.mounts {
 		if err := im.unmount(); err != nil {
 			logrus.Error(err)
@@ -71,6 +80,8 @@ func (m *imageSources) Unmount() (retErr error) {
 }
 
 func (m *imageSources) Add(im *imageMount) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	switch im.image {
 	case nil:
 		im.image = &dockerimage.Image{}
@@ -83,6 +94,7 @@ func (m *imageSources) Add(im *imageMount) {
 // imageMount is a reference to an image that can be used as a builder.Source
 type imageMount struct {
 	image  builder.Image
+	mu     sync.Mutex // protects source and layer
 	source builder.Source
 	layer  builder.ReleaseableLayer
 }
@@ -93,6 +105,8 @@ func newImageMount(image builder.Image, layer builder.ReleaseableLayer) *imageMo
 }
 
 func (im *imageMount) Source() (builder.Source, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
 	if im.source == nil {
 		if im.layer == nil {
 			return nil, errors.Errorf("empty context")
@@ -111,6 +125,8 @@ func (im *imageMount) Source() (builder.Source, error) {
 }
 
 func (im *imageMount) unmount() error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
 	if im.layer == nil {
 		return nil
 	}
@@ -126,6 +142,8 @@ func (im *imageMount) Image() builder.Image {
 }
 
 func (im *imageMount) Layer() builder.ReleaseableLayer {
+	im.mu.Lock()
+	defer im.mu.Unlock()
 	return im.layer
 }
 
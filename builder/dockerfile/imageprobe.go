@@ -11,6 +11,9 @@ import (
 type ImageProber interface {
 	Reset()
 	Probe(parentID string, runConfig *container.Config) (string, error)
+	// Clone returns a new prober using the same cache, for a stage built
+	// concurrently with other stages.
+	Clone() ImageProber
 }
 
 type imageProber struct {
@@ -19,13 +22,26 @@ type imageProber struct {
 	cacheBusted bool
 }
 
-func newImageProber(cacheBuilder builder.ImageCacheBuilder, cacheFrom []string, platform string, noCache bool) ImageProber {
+// newImageProber returns a prober matching the images of cacheFrom, or all
+// the local images if there is none, then the imported build caches. The
+// build cache locations of cacheFrom are left to the imported caches.
+func newImageProber(cacheBuilder builder.ImageCacheBuilder, cacheFrom []string, platform string, noCache bool, imported ...builder.ImageCache) ImageProber {
 	if noCache {
 		return &nopProber{}
 	}
 
+	var cacheFromImages []string
+	for _, ref := range cacheFrom {
+		if !builder.IsCacheSpec(ref) {
+			cacheFromImages = append(cacheFromImages, ref)
+		}
+	}
 	reset := func() builder.ImageCache {
-		return cacheBuilder.MakeImageCache(cacheFrom, platform)
+		local := cacheBuilder.MakeImageCache(cacheFromImages, platform)
+		if len(imported) == 0 {
+			return local
+		}
+		return append(imageCaches{local}, imported...)
 	}
 	return &imageProber{cache: reset(), reset: reset}
 }
@@ -35,6 +51,10 @@ func (c *imageProber) Reset() {
 	c.cacheBusted = false
 }
 
+func (c *imageProber) Clone() ImageProber {
+	return &imageProber{cache: c.reset(), reset: c.reset}
+}
+
 // Probe checks if cache match can be found for current build instruction.
 // It returns the cachedID if there is a hit, and the empty string on miss
 func (c *imageProber) Probe(parentID string, runConfig *container.Config) (string, error) {
@@ -58,6 +78,26 @@ type nopProber struct{}
 
 func (c *nopProber) Reset() {}
 
+func (c *nopProber) Clone() ImageProber {
+	return c
+}
+
 func (c *nopProber) Probe(_ string, _ *container.Config) (string, error) {
 	return "", nil
 }
+
+// imageCaches is an image cache returning the first hit of its caches.
+type imageCaches []builder.ImageCache
+
+func (caches imageCaches) GetCache(parentID string, cfg *container.Config) (string, error) {
+	for _, c := range caches {
+		if c == nil {
+			continue
+		}
+		id, err := c.GetCache(parentID, cfg)
+		if err != nil || id != "" {
+			return id, err
+		}
+	}
+	return "", nil
+}
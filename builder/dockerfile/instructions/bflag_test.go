@@ -184,4 +184,28 @@ func TestBuilderFlags(t *testing.T) {
 	if !flBool1.IsTrue() {
 		t.Fatalf("Test %s, bool1 should be true", bf.Args)
 	}
+
+	// ---
+
+	bf = NewBFlags()
+	flStrs1 := bf.AddStrings("strs1")
+	bf.Args = []string{"--strs1=a", "--strs1=b"}
+
+	if err = bf.Parse(); err != nil {
+		t.Fatalf("Test %q was supposed to work: %s", bf.Args, err)
+	}
+
+	if len(flStrs1.StringValues) != 2 || flStrs1.StringValues[0] != "a" || flStrs1.StringValues[1] != "b" {
+		t.Fatalf("Test %s, strs1 should be [a b], got %v", bf.Args, flStrs1.StringValues)
+	}
+
+	// ---
+
+	bf = NewBFlags()
+	bf.AddStrings("strs1")
+	bf.Args = []string{"--strs1"}
+
+	if err = bf.Parse(); err == nil {
+		t.Fatalf("Test %q was supposed to fail", bf.Args)
+	}
 }
@@ -0,0 +1,179 @@
+package instructions
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ChmodFunc returns the mode of a copied file, as changed by the --chmod flag
+// of COPY and ADD.
+type ChmodFunc func(mode os.FileMode) os.FileMode
+
+const (
+	modeSetuid = 04000
+	modeSetgid = 02000
+	modeSticky = 01000
+)
+
+// ParseChmod parses the value of a --chmod flag, either an octal mode such as
+// 0755, or a comma separated list of symbolic modes such as u+x,go-w, with
+// the syntax of chmod(1) for the permissions rwxXst.
+func ParseChmod(value string) (ChmodFunc, error) {
+	if value == "" {
+		return nil, errors.New("empty mode for --chmod")
+	}
+	if value[0] >= '0' && value[0] <= '7' {
+		perm, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || perm > 07777 {
+			return nil, errors.Errorf("invalid mode '%s' for --chmod", value)
+		}
+		return func(mode os.FileMode) os.FileMode {
+			return fromUnixPerm(mode, uint32(perm))
+		}, nil
+	}
+
+	var clauses []symbolicClause
+	for _, s := range strings.Split(value, ",") {
+		c, err := parseSymbolicClause(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid mode '%s' for --chmod", value)
+		}
+		clauses = append(clauses, c)
+	}
+	return func(mode os.FileMode) os.FileMode {
+		perm := toUnixPerm(mode)
+		for _, c := range clauses {
+			perm = c.apply(perm, mode.IsDir())
+		}
+		return fromUnixPerm(mode, perm)
+	}, nil
+}
+
+// symbolicClause is a clause of a symbolic mode, such as go-w or u=rwx+s.
+type symbolicClause struct {
+	who     uint32 // the rwx bits of the users the clause applies to
+	actions []symbolicAction
+}
+
+type symbolicAction struct {
+	op    byte // one of '+', '-' or '='
+	perms string
+}
+
+func parseSymbolicClause(s string) (symbolicClause, error) {
+	var c symbolicClause
+	i := 0
+loop:
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case 'u':
+			c.who |= 0700
+		case 'g':
+			c.who |= 0070
+		case 'o':
+			c.who |= 0007
+		case 'a':
+			c.who |= 0777
+		default:
+			break loop
+		}
+	}
+	if c.who == 0 {
+		c.who = 0777
+	}
+	if i == len(s) {
+		return c, errors.Errorf("missing operator in '%s'", s)
+	}
+	for i < len(s) {
+		op := s[i]
+		if op != '+' && op != '-' && op != '=' {
+			return c, errors.Errorf("invalid operator '%c' in '%s'", op, s)
+		}
+		i++
+		start := i
+		for ; i < len(s) && strings.IndexByte("+-=", s[i]) < 0; i++ {
+			if strings.IndexByte("rwxXst", s[i]) < 0 {
+				return c, errors.Errorf("invalid permission '%c' in '%s'", s[i], s)
+			}
+		}
+		c.actions = append(c.actions, symbolicAction{op: op, perms: s[start:i]})
+	}
+	return c, nil
+}
+
+func (c symbolicClause) apply(perm uint32, isDir bool) uint32 {
+	// the special bits the clause applies to
+	var special uint32
+	if c.who&0700 != 0 {
+		special |= modeSetuid
+	}
+	if c.who&0070 != 0 {
+		special |= modeSetgid
+	}
+	if c.who&0007 != 0 {
+		special |= modeSticky
+	}
+
+	for _, a := range c.actions {
+		var bits uint32
+		for _, p := range a.perms {
+			switch p {
+			case 'r':
+				bits |= c.who & 0444
+			case 'w':
+				bits |= c.who & 0222
+			case 'x':
+				bits |= c.who & 0111
+			case 'X':
+				if isDir || perm&0111 != 0 {
+					bits |= c.who & 0111
+				}
+			case 's':
+				bits |= special & (modeSetuid | modeSetgid)
+			case 't':
+				bits |= special & modeSticky
+			}
+		}
+		switch a.op {
+		case '+':
+			perm |= bits
+		case '-':
+			perm &^= bits
+		case '=':
+			perm = perm&^(c.who|special) | bits
+		}
+	}
+	return perm
+}
+
+func toUnixPerm(mode os.FileMode) uint32 {
+	perm := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		perm |= modeSetuid
+	}
+	if mode&os.ModeSetgid != 0 {
+		perm |= modeSetgid
+	}
+	if mode&os.ModeSticky != 0 {
+		perm |= modeSticky
+	}
+	return perm
+}
+
+func fromUnixPerm(mode os.FileMode, perm uint32) os.FileMode {
+	mode &^= os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	mode |= os.FileMode(perm) & os.ModePerm
+	if perm&modeSetuid != 0 {
+		mode |= os.ModeSetuid
+	}
+	if perm&modeSetgid != 0 {
+		mode |= os.ModeSetgid
+	}
+	if perm&modeSticky != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
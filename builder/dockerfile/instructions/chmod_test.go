@@ -0,0 +1,52 @@
+package instructions
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docker/docker/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChmod(t *testing.T) {
+	cases := []struct {
+		value    string
+		mode     os.FileMode
+		expected os.FileMode
+	}{
+		{"0755", 0600, 0755},
+		{"755", os.ModeDir | 0700, os.ModeDir | 0755},
+		{"4755", 0644, os.ModeSetuid | 0755},
+		{"+x", 0644, 0755},
+		{"u+x,go-w", 0666, 0744},
+		{"a=r", 0755, 0444},
+		{"ug=rwx,o=", 0644, 0770},
+		{"go+rX", 0600, 0644},
+		{"go+rX", 0700, 0755},
+		{"go+rX", os.ModeDir | 0700, os.ModeDir | 0755},
+		{"u+s,+t", 0755, os.ModeSetuid | os.ModeSticky | 0755},
+		{"u-s", os.ModeSetuid | 0755, 0755},
+	}
+	for _, c := range cases {
+		chmod, err := ParseChmod(c.value)
+		require.NoError(t, err, c.value)
+		assert.Equal(t, c.expected, chmod(c.mode), c.value)
+	}
+}
+
+func TestParseChmodErrors(t *testing.T) {
+	cases := map[string]string{
+		"":      "empty mode",
+		"0999":  "invalid mode '0999'",
+		"17777": "invalid mode '17777'",
+		"u":     "missing operator",
+		"u+y":   "invalid permission 'y'",
+		"z+x":   "invalid operator 'z'",
+		"go=u":  "invalid permission 'u'",
+	}
+	for value, expectedErr := range cases {
+		_, err := ParseChmod(value)
+		testutil.ErrorContains(t, err, expectedErr)
+	}
+}
@@ -2,11 +2,13 @@ package instructions
 
 import (
 	"errors"
+	"os"
 
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/builder/dockerfile/parser"
 )
 
 // KeyValuePair represent an arbitrary named value (usefull in slice insted of map[string] string to preserve ordering)
@@ -54,6 +56,12 @@ type SupportsSingleWordExpansion interface {
 	Expand(expander SingleWordExpander) error
 }
 
+// SupportsHeredocExpansion interface marks a command as supporting variable
+// expansion in the content of its heredocs
+type SupportsHeredocExpansion interface {
+	ExpandHeredocs(expander SingleWordExpander) error
+}
+
 // PlatformSpecific adds platform checks to a command
 type PlatformSpecific interface {
 	CheckPlatform(platform string) error
@@ -146,7 +154,9 @@ func (s SourcesAndDest) Dest() string {
 type AddCommand struct {
 	withNameAndCode
 	SourcesAndDest
-	Chown string
+	Chown   string
+	Chmod   string
+	Exclude []string
 }
 
 // Expand variables
@@ -161,8 +171,13 @@ func (c *AddCommand) Expand(expander SingleWordExpander) error {
 type CopyCommand struct {
 	withNameAndCode
 	SourcesAndDest
-	From  string
-	Chown string
+	From    string
+	Chown   string
+	Chmod   string
+	Exclude []string
+	// Heredocs are the inline files of the sources given as heredocs, such
+	// as COPY <<EOF /etc/app.conf
+	Heredocs []parser.Heredoc
 }
 
 // Expand variables
@@ -170,6 +185,21 @@ func (c *CopyCommand) Expand(expander SingleWordExpander) error {
 	return expandSliceInPlace(c.SourcesAndDest, expander)
 }
 
+// ExpandHeredocs expands variables in the heredocs with an unquoted delimiter
+func (c *CopyCommand) ExpandHeredocs(expander SingleWordExpander) error {
+	for i, h := range c.Heredocs {
+		if !h.Expand {
+			continue
+		}
+		content, err := expander(h.Content)
+		if err != nil {
+			return err
+		}
+		c.Heredocs[i].Content = content
+	}
+	return nil
+}
+
 // OnbuildCommand : ONBUILD <some other command>
 type OnbuildCommand struct {
 	withNameAndCode
@@ -214,6 +244,56 @@ type ShellDependantCmdLine struct {
 type RunCommand struct {
 	withNameAndCode
 	ShellDependantCmdLine
+	Mounts []*Mount
+	// Network is the network mode of the container, set with --network.
+	// It defaults to the network mode of the build.
+	Network string
+	// Security is the security mode of the container, set with --security.
+	// It defaults to the security options of the build.
+	Security string
+}
+
+// Network modes of the --network flag of RUN
+const (
+	// NetworkDefault runs the container with the network mode of the build
+	NetworkDefault = "default"
+	// NetworkNone runs the container without network access
+	NetworkNone = "none"
+	// NetworkHost runs the container in the network namespace of the host
+	NetworkHost = "host"
+)
+
+// SecuritySandbox is the security mode of the --security flag of RUN running
+// the container with the default security profile and without gaining new
+// privileges, whatever the security options of the build.
+const SecuritySandbox = "sandbox"
+
+const (
+	// MountTypeCache is the type of mounts persisted by the builder between
+	// builds, without being committed to the image.
+	MountTypeCache = "cache"
+	// MountTypeSecret is the type of mounts of build secrets, provided by
+	// the client or the daemon. Their content is neither committed to the
+	// image nor part of the build cache key.
+	MountTypeSecret = "secret"
+)
+
+// Mount is a filesystem mounted in the container of a RUN instruction
+// with the --mount flag:
+//
+// RUN --mount=type=cache,target=/root/.cache go build
+// RUN --mount=type=secret,id=npmrc,target=/root/.npmrc npm install
+//
+type Mount struct {
+	Type     string
+	Target   string // defaults to /run/secrets/<ID> for secret mounts
+	ID       string // defaults to Target for cache mounts, to its base name for secret mounts
+	ReadOnly bool   // always true for secret mounts
+	// UID, GID and Mode are the owner and the mode of the file of a secret
+	// mount, 0, 0 and 0400 by default.
+	UID  int
+	GID  int
+	Mode os.FileMode
 }
 
 // CmdCommand : CMD foo
@@ -2,6 +2,8 @@ package instructions
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"regexp"
 	"sort"
 	"strconv"
@@ -12,6 +14,8 @@ import (
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/builder/dockerfile/command"
 	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/fileutils"
 	"github.com/pkg/errors"
 )
 
@@ -21,6 +25,7 @@ type parseRequest struct {
 	attributes map[string]bool
 	flags      *BFlags
 	original   string
+	heredocs   []parser.Heredoc
 }
 
 func nodeArgs(node *parser.Node) []string {
@@ -45,6 +50,7 @@ func newParseRequestFromNode(node *parser.Node) parseRequest {
 		attributes: node.Attributes,
 		original:   node.Original,
 		flags:      NewBFlagsWithArgs(node.Flags),
+		heredocs:   node.Heredocs,
 	}
 }
 
@@ -238,13 +244,21 @@ func parseAdd(req parseRequest) (*AddCommand, error) {
 		return nil, errNoDestinationArgument("ADD")
 	}
 	flChown := req.flags.AddString("chown", "")
+	flChmod := req.flags.AddString("chmod", "")
+	flExclude := req.flags.AddStrings("exclude")
 	if err := req.flags.Parse(); err != nil {
 		return nil, err
 	}
+	excludes, err := parseCopyFlags(flChmod, flExclude)
+	if err != nil {
+		return nil, err
+	}
 	return &AddCommand{
 		SourcesAndDest:  SourcesAndDest(req.args),
 		withNameAndCode: newWithNameAndCode(req),
 		Chown:           flChown.Value,
+		Chmod:           flChmod.Value,
+		Exclude:         excludes,
 	}, nil
 }
 
@@ -254,17 +268,48 @@ func parseCopy(req parseRequest) (*CopyCommand, error) {
 	}
 	flChown := req.flags.AddString("chown", "")
 	flFrom := req.flags.AddString("from", "")
+	flChmod := req.flags.AddString("chmod", "")
+	flExclude := req.flags.AddStrings("exclude")
 	if err := req.flags.Parse(); err != nil {
 		return nil, err
 	}
+	excludes, err := parseCopyFlags(flChmod, flExclude)
+	if err != nil {
+		return nil, err
+	}
 	return &CopyCommand{
 		SourcesAndDest:  SourcesAndDest(req.args),
 		From:            flFrom.Value,
 		withNameAndCode: newWithNameAndCode(req),
 		Chown:           flChown.Value,
+		Chmod:           flChmod.Value,
+		Exclude:         excludes,
+		Heredocs:        req.heredocs,
 	}, nil
 }
 
+// parseCopyFlags validates the --chmod flag of COPY and ADD, and returns the
+// patterns of their --exclude flags, cleaned like the patterns of a
+// .dockerignore file.
+func parseCopyFlags(flChmod, flExclude *Flag) ([]string, error) {
+	if flChmod.Value != "" {
+		if _, err := ParseChmod(flChmod.Value); err != nil {
+			return nil, err
+		}
+	}
+	if len(flExclude.StringValues) == 0 {
+		return nil, nil
+	}
+	excludes, err := dockerignore.ReadAll(strings.NewReader(strings.Join(flExclude.StringValues, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fileutils.NewPatternMatcher(excludes); err != nil {
+		return nil, errors.Wrap(err, "invalid pattern for --exclude")
+	}
+	return excludes, nil
+}
+
 func parseFrom(req parseRequest) (*Stage, error) {
 	stageName, err := parseBuildStageName(req.args)
 	if err != nil {
@@ -353,17 +398,159 @@ func parseShellDependentCommand(req parseRequest, emptyAsNil bool) ShellDependan
 }
 
 func parseRun(req parseRequest) (*RunCommand, error) {
-
+	flMounts := req.flags.AddStrings("mount")
+	flNetwork := req.flags.AddString("network", "")
+	flSecurity := req.flags.AddString("security", "")
 	if err := req.flags.Parse(); err != nil {
 		return nil, err
 	}
+	switch flNetwork.Value {
+	case "", NetworkDefault, NetworkNone, NetworkHost:
+	default:
+		return nil, errors.Errorf("unsupported network mode '%s' for RUN --network, must be one of %s, %s or %s", flNetwork.Value, NetworkDefault, NetworkNone, NetworkHost)
+	}
+	switch flSecurity.Value {
+	case "", SecuritySandbox:
+	default:
+		return nil, errors.Errorf("unsupported security mode '%s' for RUN --security, must be %s", flSecurity.Value, SecuritySandbox)
+	}
+	var mounts []*Mount
+	for _, value := range flMounts.StringValues {
+		m, err := parseMount(value)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, m)
+	}
+	cmdLine := parseShellDependentCommand(req, false)
+	if len(req.heredocs) > 0 {
+		cmdLine.CmdLine = strslice.StrSlice{heredocScript(cmdLine.CmdLine[0], req.heredocs)}
+	}
 	return &RunCommand{
-		ShellDependantCmdLine: parseShellDependentCommand(req, false),
+		ShellDependantCmdLine: cmdLine,
 		withNameAndCode:       newWithNameAndCode(req),
+		Mounts:                mounts,
+		Network:               flNetwork.Value,
+		Security:              flSecurity.Value,
 	}, nil
 
 }
 
+// heredocScript returns the shell command of a RUN instruction with heredocs.
+// If the instruction is a single heredoc, the heredoc is the script run by the
+// shell, or by the interpreter of its shebang line if it has one. Otherwise
+// the shell feeds the heredocs to the command.
+func heredocScript(cmd string, heredocs []parser.Heredoc) string {
+	if name, ok := parser.ParseHeredocMarker(strings.TrimSpace(cmd)); ok && len(heredocs) == 1 && heredocs[0].Name == name {
+		h := heredocs[0]
+		if !strings.HasPrefix(h.Content, "#!") {
+			return h.Content
+		}
+		shebang := strings.SplitN(h.Content, "\n", 2)[0]
+		return strings.TrimSpace(shebang[2:]) + " <<'" + h.Name + "'\n" + h.Content + h.Name
+	}
+	script := cmd
+	for _, h := range heredocs {
+		script += "\n" + h.Content + h.Name
+	}
+	return script
+}
+
+// parseMount parses the value of a --mount flag of RUN, a comma separated
+// list of key=value pairs.
+func parseMount(value string) (*Mount, error) {
+	m := &Mount{}
+	var secretFields []string
+	for _, field := range strings.Split(value, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		if len(kv) == 1 {
+			switch key {
+			case "readonly", "ro":
+				m.ReadOnly = true
+				continue
+			}
+			return nil, errors.Errorf("invalid field '%s' in --mount, must be a key=value pair", field)
+		}
+		val := kv[1]
+		switch key {
+		case "type":
+			m.Type = val
+		case "target", "dst", "destination":
+			m.Target = val
+		case "id":
+			m.ID = val
+		case "readonly", "ro":
+			ro, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, errors.Errorf("invalid value for %s in --mount: %s", key, val)
+			}
+			m.ReadOnly = ro
+		case "uid", "gid":
+			id, err := strconv.Atoi(val)
+			if err != nil || id < 0 {
+				return nil, errors.Errorf("invalid value for %s in --mount: %s", key, val)
+			}
+			if key == "uid" {
+				m.UID = id
+			} else {
+				m.GID = id
+			}
+			secretFields = append(secretFields, key)
+		case "mode":
+			mode, err := strconv.ParseUint(val, 8, 32)
+			if err != nil || mode > 0777 {
+				return nil, errors.Errorf("invalid value for %s in --mount: %s", key, val)
+			}
+			m.Mode = os.FileMode(mode)
+			secretFields = append(secretFields, key)
+		default:
+			return nil, errors.Errorf("unknown field '%s' in --mount", key)
+		}
+	}
+
+	switch m.Type {
+	case MountTypeCache:
+		if len(secretFields) > 0 {
+			return nil, errors.Errorf("field '%s' in --mount is only supported for secret mounts", secretFields[0])
+		}
+		if m.Target == "" {
+			return nil, errors.New("--mount requires a target")
+		}
+		if m.ID == "" {
+			m.ID = m.Target
+		}
+	case MountTypeSecret:
+		if m.ID == "" && m.Target == "" {
+			return nil, errors.New("--mount=type=secret requires an id or a target")
+		}
+		if m.ID == "" {
+			m.ID = path.Base(m.Target)
+		}
+		if m.Target == "" {
+			m.Target = "/run/secrets/" + m.ID
+		}
+		m.ReadOnly = true
+		if !containsString(secretFields, "mode") {
+			m.Mode = 0400
+		}
+	case "":
+		return nil, errors.New("--mount requires a type")
+	default:
+		return nil, errors.Errorf("unsupported mount type '%s' in --mount", m.Type)
+	}
+	return m, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func parseCmd(req parseRequest) (*CmdCommand, error) {
 	if err := req.flags.Parse(); err != nil {
 		return nil, err
@@ -430,11 +617,23 @@ func parseHealthcheck(req parseRequest) (*HealthCheckCommand, error) {
 		flTimeout := req.flags.AddString("timeout", "")
 		flStartPeriod := req.flags.AddString("start-period", "")
 		flRetries := req.flags.AddString("retries", "")
+		flHTTPMethod := req.flags.AddString("http-method", "")
+		flHTTPScheme := req.flags.AddString("http-scheme", "")
+		flHTTPStatus := req.flags.AddString("http-status", "")
+		flHTTPHeaders := req.flags.AddStrings("http-header")
 
 		if err := req.flags.Parse(); err != nil {
 			return nil, err
 		}
 
+		if typ != "HTTP" {
+			for _, fl := range []*Flag{flHTTPMethod, flHTTPScheme, flHTTPStatus, flHTTPHeaders} {
+				if fl.IsUsed() {
+					return nil, fmt.Errorf("--%s can only be used with HEALTHCHECK HTTP", fl.name)
+				}
+			}
+		}
+
 		switch typ {
 		case "CMD":
 			cmdSlice := handleJSONArgs(args, req.attributes)
@@ -447,8 +646,59 @@ func parseHealthcheck(req parseRequest) (*HealthCheckCommand, error) {
 			}
 
 			healthcheck.Test = strslice.StrSlice(append([]string{typ}, cmdSlice...))
+		case "HTTP":
+			if len(args) != 1 {
+				return nil, errors.New("HEALTHCHECK HTTP requires exactly one argument: <port>[/path]")
+			}
+			port, path, err := parseHealthcheckTarget(args[0])
+			if err != nil {
+				return nil, err
+			}
+			httpConfig := &container.HealthHTTPConfig{
+				Scheme: strings.ToLower(flHTTPScheme.Value),
+				Port:   port,
+				Path:   path,
+				Method: strings.ToUpper(flHTTPMethod.Value),
+			}
+			switch httpConfig.Scheme {
+			case "", "http", "https":
+			default:
+				return nil, fmt.Errorf("Invalid --http-scheme %q (expected http or https)", flHTTPScheme.Value)
+			}
+			if flHTTPStatus.Value != "" {
+				if httpConfig.StatusMin, httpConfig.StatusMax, err = parseHTTPStatusRange(flHTTPStatus.Value); err != nil {
+					return nil, err
+				}
+			}
+			for _, h := range flHTTPHeaders.StringValues {
+				parts := strings.SplitN(h, ":", 2)
+				if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+					return nil, fmt.Errorf("Invalid --http-header %q (expected name:value)", h)
+				}
+				if httpConfig.Headers == nil {
+					httpConfig.Headers = make(map[string]string)
+				}
+				httpConfig.Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			}
+
+			healthcheck.Test = strslice.StrSlice{typ}
+			healthcheck.HTTP = httpConfig
+		case "TCP":
+			if len(args) != 1 {
+				return nil, errors.New("HEALTHCHECK TCP requires exactly one argument: <port>")
+			}
+			port, path, err := parseHealthcheckTarget(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if path != "" {
+				return nil, fmt.Errorf("Invalid port %q in HEALTHCHECK TCP", args[0])
+			}
+
+			healthcheck.Test = strslice.StrSlice{typ}
+			healthcheck.TCP = &container.HealthTCPConfig{Port: port}
 		default:
-			return nil, fmt.Errorf("Unknown type %#v in HEALTHCHECK (try CMD)", typ)
+			return nil, fmt.Errorf("Unknown type %#v in HEALTHCHECK (try CMD, HTTP or TCP)", typ)
 		}
 
 		interval, err := parseOptInterval(flInterval)
@@ -487,6 +737,40 @@ func parseHealthcheck(req parseRequest) (*HealthCheckCommand, error) {
 	return cmd, nil
 }
 
+// parseHealthcheckTarget splits the <port>[/path] argument of network
+// healthchecks.
+func parseHealthcheckTarget(target string) (int, string, error) {
+	port, path := target, ""
+	if i := strings.Index(target, "/"); i >= 0 {
+		port, path = target[:i], target[i:]
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return 0, "", fmt.Errorf("Invalid port %q in HEALTHCHECK", port)
+	}
+	return p, path, nil
+}
+
+// parseHTTPStatusRange parses the value of --http-status, either a single
+// status code or a range such as 200-299.
+func parseHTTPStatusRange(value string) (int, int, error) {
+	parts := strings.SplitN(value, "-", 2)
+	min, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid --http-status %q", value)
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, fmt.Errorf("Invalid --http-status %q", value)
+		}
+	}
+	if min < 100 || max > 599 || min > max {
+		return 0, 0, fmt.Errorf("Invalid --http-status %q (expected codes between 100 and 599)", value)
+	}
+	return min, max, nil
+}
+
 func parseExpose(req parseRequest) (*ExposeCommand, error) {
 	portsTab := req.args
 
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/builder/dockerfile/command"
 	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/internal/testutil"
@@ -127,6 +128,158 @@ func TestHealthCheckCmd(t *testing.T) {
 	assert.Equal(t, expected, hc.Health.Test)
 }
 
+func TestHealthCheckHTTP(t *testing.T) {
+	dockerfile := `HEALTHCHECK --interval=5s --http-method=head --http-status=200-204 --http-header="X-Probe: docker" --http-header=Accept:text/plain HTTP 8080/healthz`
+	ast, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	hc, ok := cmd.(*HealthCheckCommand)
+	require.True(t, ok)
+	assert.Equal(t, []string{"HTTP"}, []string(hc.Health.Test))
+	require.NotNil(t, hc.Health.HTTP)
+	assert.Equal(t, 8080, hc.Health.HTTP.Port)
+	assert.Equal(t, "/healthz", hc.Health.HTTP.Path)
+	assert.Equal(t, "HEAD", hc.Health.HTTP.Method)
+	assert.Equal(t, 200, hc.Health.HTTP.StatusMin)
+	assert.Equal(t, 204, hc.Health.HTTP.StatusMax)
+	assert.Equal(t, map[string]string{"X-Probe": "docker", "Accept": "text/plain"}, hc.Health.HTTP.Headers)
+}
+
+func TestHealthCheckTCP(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("HEALTHCHECK --retries=2 TCP 5432"))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	hc, ok := cmd.(*HealthCheckCommand)
+	require.True(t, ok)
+	assert.Equal(t, []string{"TCP"}, []string(hc.Health.Test))
+	require.NotNil(t, hc.Health.TCP)
+	assert.Equal(t, 5432, hc.Health.TCP.Port)
+	assert.Equal(t, 2, hc.Health.Retries)
+}
+
+func TestHealthCheckNetworkErrors(t *testing.T) {
+	cases := []struct {
+		dockerfile  string
+		expectedErr string
+	}{
+		{"HEALTHCHECK HTTP", "requires exactly one argument"},
+		{"HEALTHCHECK HTTP http://localhost/", "Invalid port"},
+		{"HEALTHCHECK --http-status=600 HTTP 80", "Invalid --http-status"},
+		{"HEALTHCHECK --http-status=299-200 HTTP 80", "Invalid --http-status"},
+		{"HEALTHCHECK --http-header=X-Probe HTTP 80", "Invalid --http-header"},
+		{"HEALTHCHECK --http-scheme=ftp HTTP 80", "Invalid --http-scheme"},
+		{"HEALTHCHECK TCP 70000", "Invalid port"},
+		{"HEALTHCHECK TCP 80/path", "Invalid port"},
+		{"HEALTHCHECK --http-method=GET TCP 80", "can only be used with HEALTHCHECK HTTP"},
+		{"HEALTHCHECK --http-status=200 CMD true", "can only be used with HEALTHCHECK HTTP"},
+	}
+	for _, c := range cases {
+		ast, err := parser.Parse(strings.NewReader(c.dockerfile))
+		require.NoError(t, err)
+		_, err = ParseInstruction(ast.AST.Children[0])
+		testutil.ErrorContains(t, err, c.expectedErr)
+	}
+}
+
+func TestRunMount(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("RUN --mount=type=cache,target=/root/.cache --mount=type=cache,id=go,dst=/go/pkg,ro go build"))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	run, ok := cmd.(*RunCommand)
+	require.True(t, ok)
+	expected := []*Mount{
+		{Type: MountTypeCache, Target: "/root/.cache", ID: "/root/.cache"},
+		{Type: MountTypeCache, Target: "/go/pkg", ID: "go", ReadOnly: true},
+	}
+	assert.Equal(t, expected, run.Mounts)
+	assert.Equal(t, []string{"go build"}, []string(run.CmdLine))
+}
+
+func TestRunSecretMount(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("RUN --mount=type=secret,id=npmrc,target=/root/.npmrc,uid=1000,mode=0440 --mount=type=secret,id=aws --mount=type=secret,target=/etc/token npm install"))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	run, ok := cmd.(*RunCommand)
+	require.True(t, ok)
+	expected := []*Mount{
+		{Type: MountTypeSecret, Target: "/root/.npmrc", ID: "npmrc", ReadOnly: true, UID: 1000, Mode: 0440},
+		{Type: MountTypeSecret, Target: "/run/secrets/aws", ID: "aws", ReadOnly: true, Mode: 0400},
+		{Type: MountTypeSecret, Target: "/etc/token", ID: "token", ReadOnly: true, Mode: 0400},
+	}
+	assert.Equal(t, expected, run.Mounts)
+}
+
+func TestRunMountErrors(t *testing.T) {
+	cases := []struct {
+		dockerfile  string
+		expectedErr string
+	}{
+		{"RUN --mount=target=/cache true", "requires a type"},
+		{"RUN --mount=type=bind,target=/cache true", "unsupported mount type"},
+		{"RUN --mount=type=cache true", "requires a target"},
+		{"RUN --mount=type=cache,target=/cache,sharing=locked true", "unknown field"},
+		{"RUN --mount=type=cache,target=/cache,mode=0700 true", "only supported for secret mounts"},
+		{"RUN --mount=type=secret true", "requires an id or a target"},
+		{"RUN --mount=type=secret,id=npmrc,mode=0999 true", "invalid value for mode"},
+		{"RUN --mount=type=secret,id=npmrc,uid=-1 true", "invalid value for uid"},
+		{"RUN --mount=type=cache,target=/cache,ro=maybe true", "invalid value for ro"},
+		{"RUN --mount=type=cache,/cache true", "must be a key=value pair"},
+	}
+	for _, c := range cases {
+		ast, err := parser.Parse(strings.NewReader(c.dockerfile))
+		require.NoError(t, err)
+		_, err = ParseInstruction(ast.AST.Children[0])
+		testutil.ErrorContains(t, err, c.expectedErr)
+	}
+}
+
+func TestRunNetworkAndSecurity(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("RUN --network=none --security=sandbox make test"))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	run, ok := cmd.(*RunCommand)
+	require.True(t, ok)
+	assert.Equal(t, NetworkNone, run.Network)
+	assert.Equal(t, SecuritySandbox, run.Security)
+
+	for dockerfile, expectedErr := range map[string]string{
+		"RUN --network=bridge make":    "unsupported network mode 'bridge'",
+		"RUN --security=insecure make": "unsupported security mode 'insecure'",
+	} {
+		ast, err := parser.Parse(strings.NewReader(dockerfile))
+		require.NoError(t, err)
+		_, err = ParseInstruction(ast.AST.Children[0])
+		testutil.ErrorContains(t, err, expectedErr)
+	}
+}
+
+func TestCopyChmodAndExclude(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("COPY --chmod=0755 --exclude=*.md --exclude=/docs/ . /app/"))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	copyCmd, ok := cmd.(*CopyCommand)
+	require.True(t, ok)
+	assert.Equal(t, "0755", copyCmd.Chmod)
+	assert.Equal(t, []string{"*.md", "docs"}, copyCmd.Exclude)
+
+	for dockerfile, expectedErr := range map[string]string{
+		"ADD --chmod=u+q . /app/":               "invalid mode 'u+q'",
+		"COPY --exclude=[ . /app/":              "invalid pattern for --exclude",
+		"ADD --chmod=0755 --chmod=0644 . /app/": "Duplicate flag specified",
+	} {
+		ast, err := parser.Parse(strings.NewReader(dockerfile))
+		require.NoError(t, err)
+		_, err = ParseInstruction(ast.AST.Children[0])
+		testutil.ErrorContains(t, err, expectedErr)
+	}
+}
+
 func TestParseOptInterval(t *testing.T) {
 	flInterval := &Flag{
 		name:     "interval",
@@ -202,3 +355,56 @@ func TestErrorCases(t *testing.T) {
 	}
 
 }
+
+func TestRunHeredoc(t *testing.T) {
+	cases := []struct {
+		dockerfile string
+		expected   string
+	}{
+		{
+			dockerfile: "RUN <<EOF\napt-get update\napt-get install -y curl\nEOF",
+			expected:   "apt-get update\napt-get install -y curl\n",
+		},
+		{
+			dockerfile: "RUN <<-EOF\n\t#!/usr/bin/env python3\n\tprint('hello')\n\tEOF",
+			expected:   "/usr/bin/env python3 <<'EOF'\n#!/usr/bin/env python3\nprint('hello')\nEOF",
+		},
+		{
+			dockerfile: "RUN cat <<EOF > /etc/motd\nhello $USER\nEOF",
+			expected:   "cat <<EOF > /etc/motd\nhello $USER\nEOF",
+		},
+		{
+			dockerfile: "RUN cat <<A <<'B'\na\nA\nb\nB",
+			expected:   "cat <<A <<'B'\na\nA\nb\nB",
+		},
+	}
+	for _, tc := range cases {
+		ast, err := parser.Parse(strings.NewReader(tc.dockerfile))
+		require.NoError(t, err)
+		cmd, err := ParseInstruction(ast.AST.Children[0])
+		require.NoError(t, err)
+		run, ok := cmd.(*RunCommand)
+		require.True(t, ok)
+		assert.True(t, run.PrependShell, tc.dockerfile)
+		assert.Equal(t, strslice.StrSlice{tc.expected}, run.CmdLine, tc.dockerfile)
+	}
+}
+
+func TestCopyHeredoc(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("COPY <<EOF <<'RAW' /etc/app/\nport = $PORT\nEOF\nkey = $KEY\nRAW"))
+	require.NoError(t, err)
+	cmd, err := ParseInstruction(ast.AST.Children[0])
+	require.NoError(t, err)
+	c, ok := cmd.(*CopyCommand)
+	require.True(t, ok)
+	assert.Equal(t, SourcesAndDest{"<<EOF", "<<'RAW'", "/etc/app/"}, c.SourcesAndDest)
+
+	require.NoError(t, c.ExpandHeredocs(func(content string) (string, error) {
+		return strings.Replace(content, "$PORT", "8080", -1), nil
+	}))
+	expected := []parser.Heredoc{
+		{Name: "EOF", Content: "port = 8080\n", Expand: true},
+		{Name: "RAW", Content: "key = $KEY\n"},
+	}
+	assert.Equal(t, expected, c.Heredocs)
+}
@@ -4,6 +4,7 @@ package dockerfile
 // non-contiguous functionality. Please read the comments.
 
 import (
+	"archive/tar"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -17,6 +18,8 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/builder/dockerfile/instructions"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/chrootarchive"
@@ -34,7 +37,9 @@ import (
 type Archiver interface {
 	TarUntar(src, dst string) error
 	UntarPath(src, dst string) error
+	UntarPathWithFilter(src, dst string, filter func(*tar.Header) (bool, error)) error
 	CopyWithTar(src, dst string) error
+	CopyWithTarExcluding(src, dst string, excludes []string) error
 	CopyFileWithTar(src, dst string) error
 	IDMappings() *idtools.IDMappings
 }
@@ -109,6 +114,7 @@ func (b *Builder) commitContainer(dispatchState *dispatchState, id string, conta
 			Config: copyRunConfig(dispatchState.runConfig),
 		},
 		ContainerConfig: containerConfig,
+		SourceDateEpoch: b.sourceDateEpoch,
 	}
 
 	// Commit the container
@@ -136,12 +142,16 @@ func (b *Builder) exportImage(state *dispatchState, imageMount *imageMount, runC
 		return errors.Errorf("unexpected image type")
 	}
 
-	newImage := image.NewChildImage(parentImage, image.ChildConfig{
+	childConfig := image.ChildConfig{
 		Author:          state.maintainer,
 		ContainerConfig: runConfig,
 		DiffID:          newLayer.DiffID(),
 		Config:          copyRunConfig(state.runConfig),
-	}, parentImage.OS)
+	}
+	if b.sourceDateEpoch != nil {
+		childConfig.Created = *b.sourceDateEpoch
+	}
+	newImage := image.NewChildImage(parentImage, childConfig, parentImage.OS)
 
 	// TODO: it seems strange to marshal this here instead of just passing in the
 	// image struct
@@ -167,6 +177,18 @@ func (b *Builder) performCopy(state *dispatchState, inst copyInstruction) error
 	if inst.chownStr != "" {
 		chownComment = fmt.Sprintf("--chown=%s", inst.chownStr)
 	}
+	// the flags added after --chown are separated from the source hash, but
+	// the cache keys of the instructions without them are left unchanged
+	flags := []string{chownComment}
+	for _, exclude := range inst.excludes {
+		flags = append(flags, "--exclude="+exclude)
+	}
+	if inst.chmodStr != "" {
+		flags = append(flags, "--chmod="+inst.chmodStr)
+	}
+	if len(flags) > 1 {
+		chownComment = strings.TrimSpace(strings.Join(flags, " ")) + " "
+	}
 	commentStr := fmt.Sprintf("%s %s%s in %s ", inst.cmdName, chownComment, srcHash, inst.dest)
 
 	// TODO: should this have been using origPaths instead of srcHash in the comment?
@@ -199,11 +221,23 @@ func (b *Builder) performCopy(state *dispatchState, inst copyInstruction) error
 		}
 	}
 
+	var chmod instructions.ChmodFunc
+	if inst.chmodStr != "" {
+		if b.platform == "windows" {
+			return errors.New("--chmod is not supported on Windows")
+		}
+		if chmod, err = instructions.ParseChmod(inst.chmodStr); err != nil {
+			return err
+		}
+	}
+
 	for _, info := range inst.infos {
 		opts := copyFileOptions{
 			decompress: inst.allowLocalDecompression,
 			archiver:   b.getArchiver(info.root, destInfo.root),
 			chownPair:  chownPair,
+			chmod:      chmod,
+			excludes:   inst.excludes,
 		}
 		if err := performCopyForInfo(destInfo, info, opts); err != nil {
 			return errors.Wrapf(err, "failed to copy files")
@@ -452,6 +486,7 @@ func (b *Builder) probeCache(dispatchState *dispatchState, runConfig *container.
 	fmt.Fprint(b.Stdout, " ---> Using cache\n")
 
 	dispatchState.imageID = cachedID
+	dispatchState.cached = true
 	return true, nil
 }
 
@@ -467,8 +502,75 @@ func (b *Builder) probeAndCreate(dispatchState *dispatchState, runConfig *contai
 	return container.ID, err
 }
 
-func (b *Builder) create(runConfig *container.Config) (string, error) {
+// runMounts returns the mounts of the container of a RUN instruction. The
+// returned function releases the cache mounts once the container has run.
+func (b *Builder) runMounts(mounts []*instructions.Mount) ([]mount.Mount, func(), error) {
+	if len(mounts) > 0 && b.platform == "windows" {
+		return nil, nil, errors.New("RUN --mount is not supported on Windows")
+	}
+
+	var (
+		result   []mount.Mount
+		releases []func()
+	)
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, m := range mounts {
+		switch m.Type {
+		case instructions.MountTypeCache:
+			if b.cacheMounts == nil {
+				release()
+				return nil, nil, errors.New("cache mounts are not supported by this builder")
+			}
+			source, r, err := b.cacheMounts.Get(m.ID)
+			if err != nil {
+				release()
+				return nil, nil, err
+			}
+			releases = append(releases, r)
+			result = append(result, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   source,
+				Target:   m.Target,
+				ReadOnly: m.ReadOnly,
+			})
+		case instructions.MountTypeSecret:
+			source, r, err := b.mountSecret(m)
+			if err != nil {
+				release()
+				return nil, nil, err
+			}
+			releases = append(releases, r)
+			result = append(result, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   source,
+				Target:   m.Target,
+				ReadOnly: true,
+			})
+		default:
+			release()
+			return nil, nil, errors.Errorf("unsupported mount type %s", m.Type)
+		}
+	}
+	return result, release, nil
+}
+
+type hostConfigModifier func(*container.HostConfig)
+
+func withMounts(mounts []mount.Mount) hostConfigModifier {
+	return func(hostConfig *container.HostConfig) {
+		hostConfig.Mounts = append(hostConfig.Mounts, mounts...)
+	}
+}
+
+func (b *Builder) create(runConfig *container.Config, modifiers ...hostConfigModifier) (string, error) {
 	hostConfig := hostConfigFromOptions(b.options)
+	for _, modifier := range modifiers {
+		modifier(hostConfig)
+	}
 	container, err := b.containerManager.Create(runConfig, hostConfig, b.platform)
 	if err != nil {
 		return "", err
@@ -72,6 +72,10 @@ func (m *MockBackend) GetImageAndReleasableLayer(ctx context.Context, refOrID st
 	return &mockImage{id: "theid"}, &mockLayer{}, nil
 }
 
+func (m *MockBackend) ImageLayerSize(imageID string) (int64, error) {
+	return 0, nil
+}
+
 func (m *MockBackend) MakeImageCache(cacheFrom []string, platform string) builder.ImageCache {
 	if m.makeImageCacheFunc != nil {
 		return m.makeImageCacheFunc(cacheFrom, platform)
@@ -79,6 +83,14 @@ func (m *MockBackend) MakeImageCache(cacheFrom []string, platform string) builde
 	return nil
 }
 
+func (m *MockBackend) ImportBuildCache(ctx context.Context, src builder.CacheSpec, opts backend.BuildCacheOptions) (builder.ImageCache, error) {
+	return nil, nil
+}
+
+func (m *MockBackend) ExportBuildCache(ctx context.Context, dest builder.CacheSpec, chains []builder.CacheChain, opts backend.BuildCacheOptions) error {
+	return nil
+}
+
 func (m *MockBackend) CreateImage(config []byte, parent string, platform string) (builder.Image, error) {
 	return nil, nil
 }
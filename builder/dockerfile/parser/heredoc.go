@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/builder/dockerfile/command"
+	"github.com/pkg/errors"
+)
+
+// Heredoc is a here-document of an instruction, such as RUN <<EOF or
+// COPY <<EOF /etc/app.conf. Its content follows the instruction in the
+// Dockerfile, up to a line holding only its name.
+type Heredoc struct {
+	Name    string // the delimiter of the heredoc
+	Content string // the lines of the heredoc, each one ending with a newline
+	Chomp   bool   // whether the leading tabs of the lines were removed, with <<-
+	Expand  bool   // whether variables are expanded in the content, if the delimiter is unquoted
+}
+
+// heredocCommands are the instructions that accept heredocs
+var heredocCommands = map[string]bool{
+	command.Copy: true,
+	command.Run:  true,
+}
+
+var heredocMarker = regexp.MustCompile(`^<<(-?)(?:'([^']+)'|"([^"]+)"|([a-zA-Z_][a-zA-Z0-9_]*))`)
+
+// ParseHeredocMarker parses a word starting a heredoc, such as <<EOF, <<-EOF
+// or <<'EOF', and returns the name of the heredoc.
+func ParseHeredocMarker(word string) (string, bool) {
+	h, n := parseHeredocMarker(word)
+	if h == nil || n != len(word) {
+		return "", false
+	}
+	return h.Name, true
+}
+
+// parseHeredocMarker parses the marker of a heredoc at the start of s, and
+// returns the heredoc without its content and the length of the marker.
+func parseHeredocMarker(s string) (*Heredoc, int) {
+	m := heredocMarker.FindStringSubmatch(s)
+	if m == nil {
+		return nil, 0
+	}
+	h := &Heredoc{Chomp: m[1] == "-"}
+	switch {
+	case m[2] != "":
+		h.Name = m[2]
+	case m[3] != "":
+		h.Name = m[3]
+	default:
+		h.Name = m[4]
+		h.Expand = true
+	}
+	return h, len(m[0])
+}
+
+// heredocMarkers returns the heredocs started in the arguments of an
+// instruction, in order. Markers in quotes, in arithmetic expressions such as
+// $((1<<n)) or escaped with the escape token are ignored, and so are the
+// here-strings (<<<) of the shell.
+func heredocMarkers(args string, escapeToken rune) []*Heredoc {
+	var (
+		heredocs []*Heredoc
+		quote    rune
+		escaped  bool
+		parens   int // the parentheses open in an arithmetic expression
+	)
+	for i := 0; i < len(args); i++ {
+		c := rune(args[i])
+		switch {
+		case escaped:
+			escaped = false
+		case c == escapeToken && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(args[i:], "(("):
+			parens += 2
+			i++
+		case c == '(' && parens > 0:
+			parens++
+		case c == ')' && parens > 0:
+			parens--
+		case parens > 0:
+		case strings.HasPrefix(args[i:], "<<") && !strings.HasPrefix(args[i:], "<<<") && (i == 0 || args[i-1] != '<'):
+			if h, n := parseHeredocMarker(args[i:]); h != nil {
+				heredocs = append(heredocs, h)
+				i += n - 1
+			}
+		}
+	}
+	return heredocs
+}
+
+// readHeredocs reads the content of the heredocs started by the instruction
+// of node from the lines following it. It returns the number of lines read.
+func readHeredocs(node *Node, scanner *bufio.Scanner, escapeToken rune) (int, error) {
+	if !heredocCommands[node.Value] || node.Attributes["json"] {
+		return 0, nil
+	}
+	_, _, args, err := splitCommand(node.Original)
+	if err != nil {
+		return 0, err
+	}
+	lines := 0
+	for _, h := range heredocMarkers(args, escapeToken) {
+		var content bytes.Buffer
+		terminated := false
+		for scanner.Scan() {
+			lines++
+			line := scanner.Text()
+			if h.Chomp {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == h.Name {
+				terminated = true
+				break
+			}
+			content.WriteString(line)
+			content.WriteByte('\n')
+		}
+		if !terminated {
+			return lines, errors.Errorf("unterminated heredoc %s in: %s", h.Name, node.Original)
+		}
+		h.Content = content.String()
+		node.Heredocs = append(node.Heredocs, *h)
+	}
+	return lines, nil
+}
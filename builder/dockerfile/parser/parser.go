@@ -36,6 +36,7 @@ type Node struct {
 	Attributes map[string]bool // special attributes for this node
 	Original   string          // original line used before parsing
 	Flags      []string        // only top Node should have this set
+	Heredocs   []Heredoc       // only top Node should have this set
 	StartLine  int             // the line in the original dockerfile where the node begins
 	endLine    int             // the line in the original dockerfile where the node ends
 }
@@ -62,6 +63,10 @@ func (node *Node) Dump() string {
 		}
 	}
 
+	for _, h := range node.Heredocs {
+		str += fmt.Sprintf(" (heredoc %q %q)", h.Name, h.Content)
+	}
+
 	return strings.TrimSpace(str)
 }
 
@@ -313,6 +318,11 @@ func Parse(rwc io.Reader) (*Result, error) {
 		if err != nil {
 			return nil, err
 		}
+		heredocLines, err := readHeredocs(child, scanner, d.escapeToken)
+		if err != nil {
+			return nil, err
+		}
+		currentLine += heredocLines
 		root.AddChild(child, startLine, currentLine)
 	}
 
@@ -159,3 +159,35 @@ RUN indented \
 	assert.Contains(t, warnings[1], "RUN another     thing")
 	assert.Contains(t, warnings[2], "will become errors in a future release")
 }
+
+func TestParseHeredocMarker(t *testing.T) {
+	for word, name := range map[string]string{
+		"<<EOF":      "EOF",
+		"<<-EOF":     "EOF",
+		`<<"EOF"`:    "EOF",
+		"<<-'END_1'": "END_1",
+	} {
+		actual, ok := ParseHeredocMarker(word)
+		assert.True(t, ok, word)
+		assert.Equal(t, name, actual, word)
+	}
+	for _, word := range []string{"EOF", "<<", "<<<EOF", "<<EOF>out", "<<1EOF", `<<"EOF`} {
+		_, ok := ParseHeredocMarker(word)
+		assert.False(t, ok, word)
+	}
+}
+
+func TestParseHeredocArithmeticShift(t *testing.T) {
+	dockerfile := bytes.NewBufferString(`FROM busybox
+RUN echo $((1<<n)) $(( (x << 2) | 1 )) && ((y = 1<<z))
+RUN cat <<EOF
+$((1<<n))
+EOF
+`)
+	result, err := Parse(dockerfile)
+	require.NoError(t, err)
+	require.Len(t, result.AST.Children, 3)
+	assert.Len(t, result.AST.Children[1].Heredocs, 0)
+	require.Len(t, result.AST.Children[2].Heredocs, 1)
+	assert.Equal(t, Heredoc{Name: "EOF", Content: "$((1<<n))\n", Expand: true}, result.AST.Children[2].Heredocs[0])
+}
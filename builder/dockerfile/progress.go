@@ -0,0 +1,74 @@
+package dockerfile
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/sirupsen/logrus"
+)
+
+// stepReporter emits the progress of the steps of a build in the aux stream.
+// It is shared by the stages of the build, which may report concurrently
+// through the locked writer of the aux stream.
+type stepReporter struct {
+	aux *streamformatter.AuxFormatter
+}
+
+// newStepReporter returns the reporter of the steps of a build, or nil if the
+// client did not request progress events.
+func newStepReporter(aux *streamformatter.AuxFormatter, enabled bool) *stepReporter {
+	if aux == nil || !enabled {
+		return nil
+	}
+	return &stepReporter{aux: aux}
+}
+
+func (r *stepReporter) emit(step types.BuildStep) {
+	if err := r.aux.Emit(step); err != nil {
+		logrus.Debugf("[BUILDER] failed to emit build step: %v", err)
+	}
+}
+
+// startStep reports the start of a step of the build, and returns the
+// function reporting its end with the error of the step.
+func (b *Builder) startStep(state *dispatchState, step, steps int, cmd interface{}) func(error) {
+	if b.steps == nil {
+		return func(error) {}
+	}
+	event := types.BuildStep{
+		Stage:       b.stageName,
+		Step:        step,
+		Steps:       steps,
+		Instruction: fmt.Sprint(cmd),
+		Status:      types.BuildStepStarted,
+	}
+	b.steps.emit(event)
+
+	state.cached = false
+	parentID := state.imageID
+	start := time.Now()
+	return func(err error) {
+		event.Duration = int64(time.Since(start))
+		event.ImageID = state.imageID
+		switch {
+		case err != nil:
+			event.Status = types.BuildStepFailed
+			event.Error = err.Error()
+		case state.cached:
+			event.Status = types.BuildStepCached
+		default:
+			event.Status = types.BuildStepCompleted
+		}
+		// the image of the FROM step is the base image, not a layer of the build
+		if err == nil && parentID != "" && state.imageID != parentID {
+			size, err := b.docker.ImageLayerSize(state.imageID)
+			if err != nil {
+				logrus.Debugf("[BUILDER] failed to get the layer size of %s: %v", state.imageID, err)
+			}
+			event.Size = size
+		}
+		b.steps.emit(event)
+	}
+}
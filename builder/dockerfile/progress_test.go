@@ -0,0 +1,70 @@
+package dockerfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeBuildSteps(t *testing.T, r io.Reader) []types.BuildStep {
+	var steps []types.BuildStep
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return steps
+		} else {
+			require.NoError(t, err)
+		}
+		var step types.BuildStep
+		require.NoError(t, json.Unmarshal(*msg.Aux, &step))
+		steps = append(steps, step)
+	}
+}
+
+func TestStartStep(t *testing.T) {
+	out := new(bytes.Buffer)
+	b := newBuilderWithMockBackend()
+	b.steps = newStepReporter(&streamformatter.AuxFormatter{Writer: out}, true)
+	b.stageName = "build"
+	state := &dispatchState{imageID: "parent"}
+
+	endStep := b.startStep(state, 2, 4, "RUN make")
+	state.imageID = "child"
+	endStep(nil)
+
+	endStep = b.startStep(state, 3, 4, "COPY . /src")
+	state.imageID = "cached"
+	state.cached = true
+	endStep(nil)
+
+	endStep = b.startStep(state, 4, 4, "RUN make test")
+	endStep(errors.New("tests failed"))
+
+	steps := decodeBuildSteps(t, out)
+	require.Len(t, steps, 6)
+	assert.Equal(t, types.BuildStep{Stage: "build", Step: 2, Steps: 4, Instruction: "RUN make", Status: types.BuildStepStarted}, steps[0])
+	assert.Equal(t, types.BuildStepCompleted, steps[1].Status)
+	assert.Equal(t, "child", steps[1].ImageID)
+	assert.Equal(t, types.BuildStepCached, steps[3].Status)
+	assert.Equal(t, "cached", steps[3].ImageID)
+	assert.Equal(t, types.BuildStepFailed, steps[5].Status)
+	assert.Equal(t, "tests failed", steps[5].Error)
+}
+
+func TestStartStepDisabled(t *testing.T) {
+	out := new(bytes.Buffer)
+	b := newBuilderWithMockBackend()
+	b.steps = newStepReporter(&streamformatter.AuxFormatter{Writer: out}, false)
+
+	b.startStep(&dispatchState{}, 1, 1, "FROM busybox")(nil)
+	assert.Equal(t, 0, out.Len())
+}
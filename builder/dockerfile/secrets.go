@@ -0,0 +1,92 @@
+package dockerfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/client/session/secrets"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// secretTmpfsOptions are the options of the tmpfs holding a secret while it
+// is mounted.
+const secretTmpfsOptions = "nodev,nosuid,noexec,mode=0700,size=10m"
+
+// mountSecret makes the content of a secret available on tmpfs for the
+// container of a RUN instruction, and returns the path of its file. The
+// secret is received from the client of the build session if it provides it,
+// or else read from the secrets configured on the daemon. The secret is
+// removed when the returned function is called.
+func (b *Builder) mountSecret(m *instructions.Mount) (string, func(), error) {
+	dir, err := ioutils.TempDir("", "docker-build-secret-")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := mount.Mount("tmpfs", dir, "tmpfs", secretTmpfsOptions); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, errors.Wrapf(err, "failed to mount tmpfs for secret %s", m.ID)
+	}
+	release := func() {
+		if err := mount.Unmount(dir); err != nil {
+			logrus.Warnf("failed to unmount tmpfs of build secret %s: %v", m.ID, err)
+			return
+		}
+		os.RemoveAll(dir)
+	}
+
+	p, err := b.readSecret(m.ID, dir)
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+	ids := idtools.IDPair{UID: m.UID, GID: m.GID}
+	if b.idMappings != nil {
+		if ids, err = b.idMappings.ToHost(ids); err != nil {
+			release()
+			return "", nil, err
+		}
+	}
+	if err := os.Chown(p, ids.UID, ids.GID); err != nil {
+		release()
+		return "", nil, err
+	}
+	if err := os.Chmod(p, m.Mode); err != nil {
+		release()
+		return "", nil, err
+	}
+	return p, release, nil
+}
+
+func (b *Builder) readSecret(id, dir string) (string, error) {
+	if b.session != nil {
+		clientDir := filepath.Join(dir, "client")
+		if err := os.Mkdir(clientDir, 0700); err != nil {
+			return "", err
+		}
+		p, err := secrets.Receive(b.clientCtx, b.session, id, clientDir)
+		if err == nil {
+			return p, nil
+		}
+		logrus.Debugf("[BUILDER] secret %s not received from the client: %v", id, err)
+	}
+
+	src, ok := b.secrets[id]
+	if !ok {
+		return "", errors.Errorf("secret %s not found: it must be provided by the client or configured on the daemon", id)
+	}
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read secret %s", id)
+	}
+	p := filepath.Join(dir, "daemon")
+	if err := ioutil.WriteFile(p, content, 0400); err != nil {
+		return "", err
+	}
+	return p, nil
+}
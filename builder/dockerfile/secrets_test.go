@@ -0,0 +1,31 @@
+package dockerfile
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/internal/testutil"
+	"github.com/gotestyourself/gotestyourself/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSecretFromDaemon(t *testing.T) {
+	src := fs.NewDir(t, "secret-src", fs.WithFile("token", "s3cr3t"))
+	defer src.Remove()
+	dest := fs.NewDir(t, "secret-dest")
+	defer dest.Remove()
+
+	b := newBuilderWithMockBackend()
+	b.secrets = map[string]string{"token": filepath.Join(src.Path(), "token")}
+
+	p, err := b.readSecret("token", dest.Path())
+	require.NoError(t, err)
+	content, err := ioutil.ReadFile(p)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", string(content))
+
+	_, err = b.readSecret("missing", dest.Path())
+	testutil.ErrorContains(t, err, "secret missing not found")
+}
@@ -44,6 +44,21 @@ func (s *ShellLex) ProcessWords(word string, env []string) ([]string, error) {
 	return words, err
 }
 
+// ProcessHeredoc will use the 'env' list of environment variables, and
+// replace any env var references in the content of a heredoc. As in the
+// heredocs of a shell, quotes are kept, and the escape token only escapes $
+// and itself.
+func (s *ShellLex) ProcessHeredoc(content string, env []string) (string, error) {
+	sw := &shellWord{
+		envs:        env,
+		escapeToken: s.escapeToken,
+		heredoc:     true,
+	}
+	sw.scanner.Init(strings.NewReader(content))
+	content, _, err := sw.process(content)
+	return content, err
+}
+
 func (s *ShellLex) process(word string, env []string) (string, []string, error) {
 	sw := &shellWord{
 		envs:        env,
@@ -57,6 +72,7 @@ type shellWord struct {
 	scanner     scanner.Scanner
 	envs        []string
 	escapeToken rune
+	heredoc     bool // whether quotes are kept, as in the content of a heredoc
 }
 
 func (sw *shellWord) process(source string) (string, []string, error) {
@@ -125,6 +141,10 @@ func (sw *shellWord) processStopOn(stopChar rune) (string, []string, error) {
 		'"':  sw.processDoubleQuote,
 		'$':  sw.processDollar,
 	}
+	if sw.heredoc {
+		delete(charFuncMapping, '\'')
+		delete(charFuncMapping, '"')
+	}
 
 	for sw.scanner.Peek() != scanner.EOF {
 		ch := sw.scanner.Peek()
@@ -150,7 +170,10 @@ func (sw *shellWord) processStopOn(stopChar rune) (string, []string, error) {
 			// Not special, just add it to the result
 			ch = sw.scanner.Next()
 
-			if ch == sw.escapeToken {
+			if ch == sw.escapeToken && sw.heredoc && sw.scanner.Peek() != '$' && sw.scanner.Peek() != sw.escapeToken {
+				// in a heredoc, the escape token is kept unless it escapes $ or itself
+				words.addChar(ch)
+			} else if ch == sw.escapeToken {
 				// '\' (default escape token, but ` allowed) escapes, except end of line
 				ch = sw.scanner.Next()
 
@@ -149,3 +149,15 @@ func TestGetEnv(t *testing.T) {
 		t.Fatal("8 - 'car' should map to 'hat'")
 	}
 }
+
+func TestProcessHeredoc(t *testing.T) {
+	envs := []string{"NAME=app", "PORT=8080"}
+	for _, escapeToken := range []rune{'\\', '`'} {
+		shlex := NewShellLex(escapeToken)
+		e := string(escapeToken)
+		content := "name = \"$NAME\"\nport = '${PORT}'\nprice = " + e + "$5 " + e + e + " C:" + e + "app\n"
+		actual, err := shlex.ProcessHeredoc(content, envs)
+		assert.NoError(t, err)
+		assert.Equal(t, "name = \"app\"\nport = '8080'\nprice = $5 "+e+" C:"+e+"app\n", actual)
+	}
+}
@@ -0,0 +1,205 @@
+package dockerfile
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/pkg/errors"
+)
+
+// stageGraph records the stages of a Dockerfile each stage depends on, so
+// that the stages not depending on each other can be built concurrently.
+type stageGraph struct {
+	stages []instructions.Stage
+	// deps are the indexes of the stages each stage depends on
+	deps [][]int
+	// needed tells whether each stage has to be built
+	needed []bool
+}
+
+// newStageGraph computes the dependencies between stages from their FROM
+// and COPY --from instructions. References are resolved the way they are
+// when the instructions are dispatched: a stage can only refer to the stages
+// preceding it, by name, or by index for COPY --from. Other references are
+// image references. The base image names are expanded with metaArgs.
+//
+// If pruneUnreachable is true, only the last stage and the stages it depends
+// on, directly or not, are built.
+func newStageGraph(stages []instructions.Stage, shlex *ShellLex, metaArgs []string, pruneUnreachable bool) (*stageGraph, error) {
+	g := &stageGraph{
+		stages: stages,
+		deps:   make([][]int, len(stages)),
+		needed: make([]bool, len(stages)),
+	}
+
+	names := make(map[string]int)
+	for i, stage := range stages {
+		baseName, err := shlex.ProcessWord(stage.BaseName, metaArgs)
+		if err != nil {
+			return nil, err
+		}
+		if j, ok := names[strings.ToLower(baseName)]; ok {
+			g.addDep(i, j)
+		}
+		for _, cmd := range stage.Commands {
+			c, ok := cmd.(*instructions.CopyCommand)
+			if !ok || c.From == "" {
+				continue
+			}
+			if j, ok := names[strings.ToLower(c.From)]; ok {
+				g.addDep(i, j)
+			} else if j, err := strconv.Atoi(c.From); err == nil && j >= 0 && j < i {
+				g.addDep(i, j)
+			}
+		}
+		if stage.Name != "" {
+			if _, ok := names[stage.Name]; ok {
+				return nil, errors.Errorf("%s stage name already used", stage.Name)
+			}
+			names[stage.Name] = i
+		}
+	}
+
+	if !pruneUnreachable {
+		for i := range g.needed {
+			g.needed[i] = true
+		}
+	} else if len(stages) > 0 {
+		g.markNeeded(len(stages) - 1)
+	}
+	return g, nil
+}
+
+func (g *stageGraph) addDep(stage, dep int) {
+	for _, d := range g.deps[stage] {
+		if d == dep {
+			return
+		}
+	}
+	g.deps[stage] = append(g.deps[stage], dep)
+}
+
+func (g *stageGraph) markNeeded(stage int) {
+	if g.needed[stage] {
+		return
+	}
+	g.needed[stage] = true
+	for _, dep := range g.deps[stage] {
+		g.markNeeded(dep)
+	}
+}
+
+// count returns the number of stages to build.
+func (g *stageGraph) count() int {
+	n := 0
+	for _, needed := range g.needed {
+		if needed {
+			n++
+		}
+	}
+	return n
+}
+
+// commandCount returns the number of steps of the stages to build, FROM
+// included.
+func (g *stageGraph) commandCount() int {
+	n := 0
+	for i, stage := range g.stages {
+		if g.needed[i] {
+			n += len(stage.Commands) + 1
+		}
+	}
+	return n
+}
+
+// stageDisplayName returns the name prefixing the output of a stage.
+func stageDisplayName(stage *instructions.Stage, index int) string {
+	if stage.Name != "" {
+		return stage.Name
+	}
+	return "stage-" + strconv.Itoa(index)
+}
+
+// stagesBuildResultsFor returns the results of the stages preceding the stage
+// at index, as seen by that stage. The stages not built yet have no result;
+// the stage does not depend on them.
+func stagesBuildResultsFor(index int, states []*dispatchState) (*stagesBuildResults, error) {
+	results := newStagesBuildResults()
+	for _, state := range states[:index] {
+		if state == nil {
+			results.flat = append(results.flat, nil)
+			continue
+		}
+		if err := commitStage(state, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// prefixWriter prefixes each line written to it, so that the output of stages
+// built concurrently can be told apart. Lines are written at once to the
+// underlying writer once they are complete.
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(w io.Writer, name string) *prefixWriter {
+	return &prefixWriter{w: w, prefix: "[" + name + "] "}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := io.WriteString(p.w, p.prefix+string(p.buf.Next(i+1))); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush writes the last line, if it is not complete.
+func (p *prefixWriter) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	_, err := io.WriteString(p.w, p.prefix+string(p.buf.Next(p.buf.Len()))+"\n")
+	return err
+}
+
+// lockedWriter serializes the writes to a writer shared with the other
+// outputs of a build, which the stages built concurrently write to.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func newLockedWriter(mu *sync.Mutex, w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return &lockedWriter{mu: mu, w: w}
+}
+
+func (l *lockedWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(b)
+}
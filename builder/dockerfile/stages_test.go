@@ -0,0 +1,177 @@
+package dockerfile
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/instructions"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/internal/testutil"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func parseStages(t *testing.T, dockerfile string) ([]instructions.Stage, []instructions.ArgCommand) {
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	require.NoError(t, err)
+	stages, metaArgs, err := instructions.Parse(result.AST)
+	require.NoError(t, err)
+	return stages, metaArgs
+}
+
+func TestStageGraph(t *testing.T) {
+	stages, _ := parseStages(t, `
+FROM busybox AS base
+FROM ${BASE} AS frontend
+FROM busybox AS docs
+COPY --from=later /a /a
+FROM busybox
+COPY --from=0 /a /a
+COPY --from=frontend /b /b
+COPY --from=5 /c /c
+COPY --from=golang /d /d
+FROM busybox AS later
+`)
+	g, err := newStageGraph(stages, NewShellLex('\\'), []string{"BASE=base"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{nil, {0}, nil, {0, 1}, nil}, g.deps)
+	assert.Equal(t, []bool{true, true, true, true, true}, g.needed)
+	assert.Equal(t, 5, g.count())
+	assert.Equal(t, 10, g.commandCount())
+
+	// only the last stage and its dependencies are built
+	g, err = newStageGraph(stages[:4], NewShellLex('\\'), []string{"BASE=base"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false, true}, g.needed)
+	assert.Equal(t, 3, g.count())
+	assert.Equal(t, 7, g.commandCount())
+
+	g, err = newStageGraph(stages[:2], NewShellLex('\\'), nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, g.needed)
+}
+
+func TestStageGraphDuplicateName(t *testing.T) {
+	stages, _ := parseStages(t, "FROM busybox AS base\nFROM busybox AS BASE\n")
+	_, err := newStageGraph(stages, NewShellLex('\\'), nil, false)
+	testutil.ErrorContains(t, err, "base stage name already used")
+}
+
+func TestPrefixWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := newPrefixWriter(buf, "docs")
+	_, err := w.Write([]byte("Step 1/2 : FROM busybox"))
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+	_, err = w.Write([]byte("\n ---> abc\nbuilding"))
+	require.NoError(t, err)
+	assert.Equal(t, "[docs] Step 1/2 : FROM busybox\n[docs]  ---> abc\n", buf.String())
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "[docs] Step 1/2 : FROM busybox\n[docs]  ---> abc\n[docs] building\n", buf.String())
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDispatchStagesInParallel(t *testing.T) {
+	dockerfile := `
+FROM busybox AS base
+ENV BASE=1
+FROM base AS frontend
+ENV FRONTEND=1
+FROM busybox AS docs
+ENV DOCS=1
+FROM frontend
+ENV FINAL=1
+`
+	for _, target := range []string{"", "frontend"} {
+		b := newBuilderWithMockBackend()
+		b.options.Target = target
+		out := &syncBuffer{}
+		b.Stdout = out
+		mockBackend := b.docker.(*MockBackend)
+		mockBackend.getImageFunc = func(name string) (builder.Image, builder.ReleaseableLayer, error) {
+			return &mockImage{id: name, config: &container.Config{}}, nil, nil
+		}
+
+		stages, metaArgs := parseStages(t, dockerfile)
+		if target != "" {
+			targetIx, found := instructions.HasStage(stages, target)
+			require.True(t, found)
+			stages = stages[:targetIx+1]
+		}
+		state, err := b.dispatchDockerfileWithCancellation(stages, metaArgs, '\\', nil)
+		require.NoError(t, err)
+
+		output := out.String()
+		if target == "" {
+			assert.Contains(t, output, "[docs] Step 5/8 : FROM busybox AS docs\n")
+			assert.Contains(t, output, "[stage-3] Step 8/8 : ENV FINAL=1\n")
+			assert.Contains(t, state.runConfig.Env, "FINAL=1")
+		} else {
+			assert.NotContains(t, output, "[docs]")
+			assert.Contains(t, output, "[frontend] Step 4/4 : ENV FRONTEND=1\n")
+			assert.Contains(t, state.runConfig.Env, "FRONTEND=1")
+		}
+		assert.Contains(t, output, "[base] Step 1/")
+	}
+}
+
+func TestNewBuilderSerializesOutput(t *testing.T) {
+	// Stdout and Aux write to the same unsynchronized buffer
+	buf := &bytes.Buffer{}
+	b := newBuilder(context.Background(), builderOptions{
+		Options: &types.ImageBuildOptions{ProgressEvents: true},
+		Backend: &MockBackend{},
+		ProgressWriter: backend.ProgressWriter{
+			Output:          buf,
+			StdoutFormatter: buf,
+			StderrFormatter: buf,
+			AuxFormatter:    &streamformatter.AuxFormatter{Writer: buf},
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			b.Stdout.Write([]byte("Step\n"))
+		}()
+		go func() {
+			defer wg.Done()
+			b.steps.emit(types.BuildStep{Stage: "docs", Status: "completed"})
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 20)
+	for _, line := range lines {
+		if line != "Step" {
+			assert.True(t, strings.HasPrefix(line, `{"aux":`), line)
+		}
+	}
+}
@@ -120,9 +120,25 @@ func (cli *Client) imageBuildOptionsToQuery(options types.ImageBuildOptions) (ur
 		return query, err
 	}
 	query.Set("cachefrom", string(cacheFromJSON))
+	if len(options.CacheTo) > 0 {
+		cacheToJSON, err := json.Marshal(options.CacheTo)
+		if err != nil {
+			return query, err
+		}
+		query.Set("cacheto", string(cacheToJSON))
+	}
 	if options.SessionID != "" {
 		query.Set("session", options.SessionID)
 	}
+	if options.ProgressEvents {
+		query.Set("progressevents", "1")
+	}
+	if options.Check {
+		query.Set("check", "1")
+	}
+	if options.SourceDateEpoch != "" {
+		query.Set("sourcedateepoch", options.SourceDateEpoch)
+	}
 
 	return query, nil
 }
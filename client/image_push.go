@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"golang.org/x/net/context"
 
@@ -35,6 +36,15 @@ func (cli *Client) ImagePush(ctx context.Context, image string, options types.Im
 
 	query := url.Values{}
 	query.Set("tag", tag)
+	if options.Compression != "" {
+		query.Set("compression", options.Compression)
+	}
+	if options.CompressionLevel != 0 {
+		query.Set("compressionlevel", strconv.Itoa(options.CompressionLevel))
+	}
+	if options.CompressionParallel {
+		query.Set("compressionparallel", "1")
+	}
 
 	resp, err := cli.tryImagePush(ctx, name, query, options.RegistryAuth)
 	if resp.statusCode == http.StatusUnauthorized && options.PrivilegeFunc != nil {
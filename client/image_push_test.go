@@ -128,6 +128,60 @@ func TestImagePushWithPrivilegedFuncNoError(t *testing.T) {
 	}
 }
 
+func TestImagePushWithCompression(t *testing.T) {
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			query := req.URL.Query()
+			if compression := query.Get("compression"); compression != "zstd" {
+				return nil, fmt.Errorf("compression not set in URL query properly. Expected 'zstd', got %s", compression)
+			}
+			if level := query.Get("compressionlevel"); level != "19" {
+				return nil, fmt.Errorf("compressionlevel not set in URL query properly. Expected '19', got %s", level)
+			}
+			if parallel := query.Get("compressionparallel"); parallel != "" {
+				return nil, fmt.Errorf("compressionparallel must not be set in URL query, got %s", parallel)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("hello world"))),
+			}, nil
+		}),
+	}
+	resp, err := client.ImagePush(context.Background(), "myimage:tag", types.ImagePushOptions{
+		Compression:      types.ImagePushCompressionZstd,
+		CompressionLevel: 19,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Close()
+}
+
+func TestImagePushWithCompressionParallel(t *testing.T) {
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			query := req.URL.Query()
+			if compression := query.Get("compression"); compression != "" {
+				return nil, fmt.Errorf("compression must not be set in URL query, got %s", compression)
+			}
+			if parallel := query.Get("compressionparallel"); parallel != "1" {
+				return nil, fmt.Errorf("compressionparallel not set in URL query properly. Expected '1', got %s", parallel)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("hello world"))),
+			}, nil
+		}),
+	}
+	resp, err := client.ImagePush(context.Background(), "myimage:tag", types.ImagePushOptions{
+		CompressionParallel: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Close()
+}
+
 func TestImagePushWithoutErrors(t *testing.T) {
 	expectedOutput := "hello world"
 	expectedURLFormat := "/images/%s/push"
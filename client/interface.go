@@ -173,6 +173,9 @@ type VolumeAPIClient interface {
 	VolumeList(ctx context.Context, filter filters.Args) (volumetypes.VolumesListOKBody, error)
 	VolumeRemove(ctx context.Context, volumeID string, force bool) error
 	VolumesPrune(ctx context.Context, pruneFilter filters.Args) (types.VolumesPruneReport, error)
+	VolumeClone(ctx context.Context, volumeID string, options volumetypes.VolumesCreateBody) (types.Volume, error)
+	VolumeExport(ctx context.Context, volumeID string) (io.ReadCloser, error)
+	VolumeImport(ctx context.Context, source io.Reader, options types.VolumeImportOptions) (types.Volume, error)
 }
 
 // SecretAPIClient defines API client methods for secrets
@@ -0,0 +1,109 @@
+// Package secrets transfers the build secrets of a client to the builder
+// over a build session, for the RUN --mount=type=secret instructions.
+//
+// The secrets are synced with the file sync protocol of the session, as
+// directories holding a single file. The client has to share them with the
+// same file sync provider as the build context:
+//
+//	dirs, err := secrets.SyncedDirs(sources)
+//	...
+//	s.Allow(filesync.NewFSSyncProvider(append(contextDirs, dirs...)))
+package secrets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/filesync"
+	"github.com/pkg/errors"
+	"github.com/tonistiigi/fsutil"
+	"golang.org/x/net/context"
+)
+
+// dirNamePrefix prefixes the names of the synced dirs of the secrets, so that
+// they cannot be mistaken for the dirs of the build context.
+const dirNamePrefix = "secret:"
+
+// Source is a build secret of the client, read from a file.
+type Source struct {
+	ID       string
+	FilePath string
+}
+
+// SyncedDirs returns the synced dirs sharing the secrets of sources. Only the
+// file of each secret is shared, not the other files of its directory.
+func SyncedDirs(sources []Source) ([]filesync.SyncedDir, error) {
+	var dirs []filesync.SyncedDir
+	for _, src := range sources {
+		if src.ID == "" {
+			return nil, errors.New("secret requires an id")
+		}
+		p, err := filepath.EvalSymlinks(src.FilePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read secret %s", src.ID)
+		}
+		p, err = filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read secret %s", src.ID)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil, errors.Errorf("secret %s must be a regular file", src.ID)
+		}
+
+		name := filepath.Base(p)
+		dirs = append(dirs, filesync.SyncedDir{
+			Name:     dirNamePrefix + src.ID,
+			Dir:      filepath.Dir(p),
+			Excludes: []string{"*", "!" + escapePattern(name)},
+			Map: func(st *fsutil.Stat) bool {
+				if st.Path != name {
+					return false
+				}
+				st.Uid, st.Gid = 0, 0
+				return true
+			},
+		})
+	}
+	return dirs, nil
+}
+
+// Receive reads the content of the secret id of the client of a session in
+// dir, and returns the path of the file holding it.
+func Receive(ctx context.Context, c session.Caller, id, dir string) (string, error) {
+	if err := filesync.FSSync(ctx, c, filesync.FSSendRequestOpt{
+		Name:    dirNamePrefix + id,
+		DestDir: dir,
+	}); err != nil {
+		return "", errors.Wrapf(err, "failed to receive secret %s", id)
+	}
+	// the dir of the secret holds its file only
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) != 1 || !files[0].Mode().IsRegular() {
+		return "", errors.Errorf("secret %s not provided by the client", id)
+	}
+	return filepath.Join(dir, files[0].Name()), nil
+}
+
+// escapePattern escapes the characters of a file name that have a meaning in
+// the exclude patterns.
+func escapePattern(name string) string {
+	var b bytes.Buffer
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/internal/testutil"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/filesync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestReceive(t *testing.T) {
+	clientDir, err := ioutil.TempDir("", "secrets-client")
+	require.NoError(t, err)
+	defer os.RemoveAll(clientDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(clientDir, ".npmrc"), []byte("token"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(clientDir, "other"), []byte("other"), 0600))
+
+	dirs, err := SyncedDirs([]Source{{ID: "npmrc", FilePath: filepath.Join(clientDir, ".npmrc")}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sm, err := session.NewManager()
+	require.NoError(t, err)
+	s, err := session.NewSession("test", "test")
+	require.NoError(t, err)
+	s.Allow(filesync.NewFSSyncProvider(dirs))
+	go s.Run(ctx, func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
+		clientConn, serverConn := net.Pipe()
+		go sm.HandleConn(ctx, serverConn, meta)
+		return clientConn, nil
+	})
+	defer s.Close()
+
+	caller, err := sm.Get(ctx, s.ID())
+	require.NoError(t, err)
+
+	for _, id := range []string{"npmrc", "aws"} {
+		dest, err := ioutil.TempDir("", "secrets-daemon")
+		require.NoError(t, err)
+		defer os.RemoveAll(dest)
+
+		p, err := Receive(ctx, caller, id, dest)
+		if id == "aws" {
+			testutil.ErrorContains(t, err, "failed to receive secret aws")
+			continue
+		}
+		require.NoError(t, err)
+		content, err := ioutil.ReadFile(p)
+		require.NoError(t, err)
+		assert.Equal(t, "token", string(content))
+
+		// only the file of the secret is shared
+		files, err := ioutil.ReadDir(dest)
+		require.NoError(t, err)
+		assert.Len(t, files, 1)
+	}
+}
+
+func TestSyncedDirsErrors(t *testing.T) {
+	_, err := SyncedDirs([]Source{{FilePath: "/etc/hostname"}})
+	testutil.ErrorContains(t, err, "secret requires an id")
+
+	_, err = SyncedDirs([]Source{{ID: "missing", FilePath: "/does/not/exist"}})
+	testutil.ErrorContains(t, err, "failed to read secret missing")
+
+	_, err = SyncedDirs([]Source{{ID: "dir", FilePath: os.TempDir()}})
+	testutil.ErrorContains(t, err, "must be a regular file")
+}
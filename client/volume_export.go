@@ -0,0 +1,17 @@
+package client
+
+import (
+	"io"
+
+	"golang.org/x/net/context"
+)
+
+// VolumeExport retrieves the content of a volume as a tar archive.
+// It's up to the caller to close the stream.
+func (cli *Client) VolumeExport(ctx context.Context, volumeID string) (io.ReadCloser, error) {
+	resp, err := cli.get(ctx, "/volumes/"+volumeID+"/export", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.body, nil
+}
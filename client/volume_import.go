@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+// VolumeImport extracts the tar archive read from source to a volume in the
+// docker host, creating the volume if it doesn't exist.
+func (cli *Client) VolumeImport(ctx context.Context, source io.Reader, options types.VolumeImportOptions) (types.Volume, error) {
+	var volume types.Volume
+	query := url.Values{}
+	if options.Name != "" {
+		query.Set("name", options.Name)
+	}
+	if options.Driver != "" {
+		query.Set("driver", options.Driver)
+	}
+	if len(options.DriverOpts) > 0 {
+		opts, err := json.Marshal(options.DriverOpts)
+		if err != nil {
+			return volume, err
+		}
+		query.Set("opts", string(opts))
+	}
+	if len(options.Labels) > 0 {
+		labels, err := json.Marshal(options.Labels)
+		if err != nil {
+			return volume, err
+		}
+		query.Set("labels", string(labels))
+	}
+
+	resp, err := cli.postRaw(ctx, "/volumes/import", query, source, nil)
+	if err != nil {
+		return volume, err
+	}
+	err = json.NewDecoder(resp.body).Decode(&volume)
+	ensureReaderClosed(resp)
+	return volume, err
+}
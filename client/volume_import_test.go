@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+func TestVolumeImportError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.VolumeImport(context.Background(), strings.NewReader("source"), types.VolumeImportOptions{})
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server Error, got %v", err)
+	}
+}
+
+func TestVolumeImport(t *testing.T) {
+	expectedURL := "/volumes/import"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != expectedURL {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			query := req.URL.Query()
+			if name := query.Get("name"); name != "myvolume" {
+				return nil, fmt.Errorf("name not set in URL query properly. Expected 'myvolume', got %s", name)
+			}
+			if driver := query.Get("driver"); driver != "mydriver" {
+				return nil, fmt.Errorf("driver not set in URL query properly. Expected 'mydriver', got %s", driver)
+			}
+			if labels := query.Get("labels"); labels != `{"label1":"value1"}` {
+				return nil, fmt.Errorf("labels not set in URL query properly. Expected '{\"label1\":\"value1\"}', got %s", labels)
+			}
+			source, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			if string(source) != "source" {
+				return nil, fmt.Errorf("expected body to be 'source', got %s", source)
+			}
+
+			content, err := json.Marshal(types.Volume{Name: "myvolume", Driver: "mydriver"})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Body:       ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		}),
+	}
+	volume, err := client.VolumeImport(context.Background(), strings.NewReader("source"), types.VolumeImportOptions{
+		Name:   "myvolume",
+		Driver: "mydriver",
+		Labels: map[string]string{"label1": "value1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume.Name != "myvolume" {
+		t.Fatalf("expected volume.Name to be 'myvolume', got %s", volume.Name)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"runtime"
 
+	"github.com/docker/docker/api/server/middleware"
 	"github.com/docker/docker/daemon/config"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/registry"
@@ -53,15 +54,23 @@ func installCommonConfigFlags(conf *config.Config, flags *pflag.FlagSet) {
 	flags.StringVar(&conf.ClusterAdvertise, "cluster-advertise", "", "Address or interface name to advertise")
 	flags.StringVar(&conf.ClusterStore, "cluster-store", "", "URL of the distributed storage backend")
 	flags.Var(opts.NewNamedMapOpts("cluster-store-opts", conf.ClusterOpts, nil), "cluster-store-opt", "Set cluster store options")
+	flags.Var(opts.NewNamedMapOpts("api-rate-limits", conf.APIRateLimits, middleware.ValidateRateLimit), "api-rate-limit", "Limit the rate of API requests per client to a route (e.g. \"POST /build=10/1m\")")
+	flags.Var(opts.NewNamedMapOpts("build-secrets", conf.BuildSecrets, nil), "build-secret", "Set a file that builds can mount as a secret (e.g. \"id=/path/to/file\")")
+	flags.StringVar(&conf.BuildCacheDir, "build-cache-dir", "", "Directory that builds can export their build cache to with type=local")
+	flags.BoolVar(&conf.BuildAllowHostNetwork, "build-allow-host-network", false, "Allow RUN instructions to use the host network with --network=host")
 	flags.StringVar(&conf.CorsHeaders, "api-cors-header", "", "Set CORS headers in the Engine API")
 	flags.IntVar(&maxConcurrentDownloads, "max-concurrent-downloads", config.DefaultMaxConcurrentDownloads, "Set the max concurrent downloads for each pull")
 	flags.IntVar(&maxConcurrentUploads, "max-concurrent-uploads", config.DefaultMaxConcurrentUploads, "Set the max concurrent uploads for each push")
 	flags.IntVar(&conf.ShutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "Set the default shutdown timeout")
+	conf.EventsMaxSize = opts.MemBytes(config.DefaultEventsMaxSize)
+	flags.Var(&conf.EventsMaxSize, "events-max-size", "Maximum size of the on-disk events journal")
+	flags.StringVar(&conf.EventsMaxAge, "events-max-age", "", "Maximum age of events kept in the on-disk events journal")
 
 	flags.StringVar(&conf.SwarmDefaultAdvertiseAddr, "swarm-default-advertise-addr", "", "Set default address or interface for swarm advertised address")
 	flags.BoolVar(&conf.Experimental, "experimental", false, "Enable experimental features")
 
 	flags.StringVar(&conf.MetricsAddress, "metrics-addr", "", "Set default address and port to serve the metrics api on")
+	flags.Var(opts.NewNamedListOptsRef("metrics-container-labels", &conf.MetricsContainerLabels, nil), "metrics-container-label", "Container label to add to the per-container metrics")
 
 	flags.StringVar(&conf.NodeGenericResources, "node-generic-resources", "", "user defined resources (e.g. fpga=2;gpu={UUID1,UUID2,UUID3})")
 	flags.IntVar(&conf.NetworkControlPlaneMTU, "network-control-plane-mtu", config.DefaultNetworkMtu, "Network Control plane MTU")
@@ -81,10 +90,14 @@ func installRegistryServiceFlags(options *registry.ServiceOptions, flags *pflag.
 	ana := opts.NewNamedListOptsRef("allow-nondistributable-artifacts", &options.AllowNondistributableArtifacts, registry.ValidateIndexName)
 	mirrors := opts.NewNamedListOptsRef("registry-mirrors", &options.Mirrors, registry.ValidateMirror)
 	insecureRegistries := opts.NewNamedListOptsRef("insecure-registries", &options.InsecureRegistries, registry.ValidateIndexName)
+	hostMirrors := opts.NewNamedListOptsRef("registry-host-mirrors", &options.RegistryMirrors, registry.ValidateRegistryMirror)
+	rewrites := opts.NewNamedListOptsRef("registry-rewrites", &options.RegistryRewrites, registry.ValidateRegistryRewrite)
 
 	flags.Var(ana, "allow-nondistributable-artifacts", "Allow push of nondistributable artifacts to registry")
 	flags.Var(mirrors, "registry-mirror", "Preferred Docker registry mirror")
 	flags.Var(insecureRegistries, "insecure-registry", "Enable insecure registry communication")
+	flags.Var(hostMirrors, "registry-host-mirror", "Mirror of a registry other than the Docker Hub, as host=mirror")
+	flags.Var(rewrites, "registry-rewrite", "Pull the repositories starting with a prefix from a mirror, as prefix=target")
 
 	if runtime.GOOS != "windows" {
 		flags.BoolVar(&options.V2Only, "disable-legacy-registry", true, "Disable contacting legacy registries")
@@ -26,6 +26,7 @@ import (
 	swarmrouter "github.com/docker/docker/api/server/router/swarm"
 	systemrouter "github.com/docker/docker/api/server/router/system"
 	"github.com/docker/docker/api/server/router/volume"
+	"github.com/docker/docker/builder/cachemount"
 	"github.com/docker/docker/builder/dockerfile"
 	"github.com/docker/docker/builder/fscache"
 	"github.com/docker/docker/cli/debug"
@@ -63,6 +64,8 @@ type DaemonCli struct {
 	api             *apiserver.Server
 	d               *daemon.Daemon
 	authzMiddleware *authorization.Middleware // authzMiddleware enables to dynamically reload the authorization plugins
+
+	rateLimitMiddleware *middleware.RateLimitMiddleware // rateLimitMiddleware enables to dynamically reload the API rate limits
 }
 
 // NewDaemonCli returns a daemon CLI
@@ -325,6 +328,7 @@ type routerOptions struct {
 	sessionManager *session.Manager
 	buildBackend   *buildbackend.Backend
 	buildCache     *fscache.FSCache
+	cacheMounts    *cachemount.Store
 	daemon         *daemon.Daemon
 	api            *apiserver.Server
 	cluster        *cluster.Cluster
@@ -351,12 +355,17 @@ func newRouterOptions(config *config.Config, daemon *daemon.Daemon) (routerOptio
 		return opts, errors.Wrap(err, "failed to create fscache")
 	}
 
-	manager, err := dockerfile.NewBuildManager(daemon, sm, buildCache, daemon.IDMappings())
+	cacheMounts, err := cachemount.NewStore(filepath.Join(builderStateDir, "cachemounts"), daemon.IDMappings().RootPair())
+	if err != nil {
+		return opts, err
+	}
+
+	manager, err := dockerfile.NewBuildManager(daemon, sm, buildCache, cacheMounts, config.BuildSecrets, config.BuildAllowHostNetwork, daemon.IDMappings())
 	if err != nil {
 		return opts, err
 	}
 
-	bb, err := buildbackend.NewBackend(daemon, manager, buildCache)
+	bb, err := buildbackend.NewBackend(daemon, manager, buildCache, cacheMounts)
 	if err != nil {
 		return opts, errors.Wrap(err, "failed to create buildmanager")
 	}
@@ -365,6 +374,7 @@ func newRouterOptions(config *config.Config, daemon *daemon.Daemon) (routerOptio
 		sessionManager: sm,
 		buildBackend:   bb,
 		buildCache:     buildCache,
+		cacheMounts:    cacheMounts,
 		daemon:         daemon,
 	}, nil
 }
@@ -379,6 +389,13 @@ func (cli *DaemonCli) reloadConfig() {
 		}
 		cli.authzMiddleware.SetPlugins(config.AuthorizationPlugins)
 
+		if config.IsValueSet("api-rate-limits") {
+			if err := cli.rateLimitMiddleware.SetLimits(config.APIRateLimits); err != nil {
+				logrus.Errorf("Error reconfiguring the API rate limits: %v", err)
+				return
+			}
+		}
+
 		if err := cli.d.Reload(config); err != nil {
 			logrus.Errorf("Error reconfiguring the daemon: %v", err)
 			return
@@ -472,6 +489,13 @@ func loadDaemonCliConfig(opts *daemonOptions) (*config.Config, error) {
 		return nil, err
 	}
 
+	// validate API rate limits
+	for route, budget := range conf.APIRateLimits {
+		if _, err := middleware.ValidateRateLimit(route + "=" + budget); err != nil {
+			return nil, err
+		}
+	}
+
 	if !conf.V2Only {
 		logrus.Warnf(`The "disable-legacy-registry" option is deprecated and wil be removed in Docker v17.12. Interacting with legacy (v1) registries will no longer be supported in Docker v17.12"`)
 	}
@@ -517,7 +541,7 @@ func initRouter(opts routerOptions) {
 		checkpointrouter.NewRouter(opts.daemon, decoder),
 		container.NewRouter(opts.daemon, decoder),
 		image.NewRouter(opts.daemon, decoder),
-		systemrouter.NewRouter(opts.daemon, opts.cluster, opts.buildCache),
+		systemrouter.NewRouter(opts.daemon, opts.cluster, opts.buildCache, opts.cacheMounts),
 		volume.NewRouter(opts.daemon),
 		build.NewRouter(opts.buildBackend, opts.daemon),
 		sessionrouter.NewRouter(opts.sessionManager),
@@ -558,6 +582,13 @@ func (cli *DaemonCli) initMiddlewares(s *apiserver.Server, cfg *apiserver.Config
 		s.UseMiddleware(c)
 	}
 
+	rl, err := middleware.NewRateLimitMiddleware(cli.Config.APIRateLimits)
+	if err != nil {
+		return err
+	}
+	cli.rateLimitMiddleware = rl
+	s.UseMiddleware(rl)
+
 	cli.authzMiddleware = authorization.NewMiddleware(cli.Config.AuthorizationPlugins, pluginStore)
 	cli.Config.AuthzMiddleware = cli.authzMiddleware
 	s.UseMiddleware(cli.authzMiddleware)
@@ -22,6 +22,7 @@ import (
 	"github.com/docker/docker/container/stream"
 	"github.com/docker/docker/daemon/exec"
 	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/composite"
 	"github.com/docker/docker/daemon/logger/jsonfilelog"
 	"github.com/docker/docker/daemon/network"
 	"github.com/docker/docker/image"
@@ -378,8 +379,9 @@ func (container *Container) StartLogger() (logger.Logger, error) {
 		DaemonName:          "docker",
 	}
 
-	// Set logging file for "json-logger"
-	if cfg.Type == jsonfilelog.Name {
+	// Set logging file for "json-logger", either used directly or as one
+	// of the drivers of the composite logger
+	if cfg.Type == jsonfilelog.Name || (cfg.Type == composite.Name && hasLogDriver(composite.Drivers(cfg.Config), jsonfilelog.Name)) {
 		info.LogPath, err = container.GetRootResourcePath(fmt.Sprintf("%s-json.log", container.ID))
 		if err != nil {
 			return nil, err
@@ -952,6 +954,21 @@ func (container *Container) CancelAttachContext() {
 	container.attachContext.mu.Unlock()
 }
 
+// logPather is implemented by the log drivers writing to a json-file log,
+// either directly or through the composite logger.
+type logPather interface {
+	LogPath() string
+}
+
+func hasLogDriver(drivers []string, name string) bool {
+	for _, d := range drivers {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (container *Container) startLogging() error {
 	if container.HostConfig.LogConfig.Type == "none" {
 		return nil // do not start logging routines
@@ -962,13 +979,19 @@ func (container *Container) startLogging() error {
 		return fmt.Errorf("failed to initialize logging driver: %v", err)
 	}
 
-	copier := logger.NewCopier(map[string]io.Reader{"stdout": container.StdoutPipe(), "stderr": container.StderrPipe()}, l)
+	multiline, err := logger.ParseMultilineConfig(container.HostConfig.LogConfig.Config)
+	if err != nil {
+		l.Close()
+		return fmt.Errorf("failed to initialize logging driver: %v", err)
+	}
+
+	copier := logger.NewMultilineCopier(map[string]io.Reader{"stdout": container.StdoutPipe(), "stderr": container.StderrPipe()}, l, multiline)
 	container.LogCopier = copier
 	copier.Run()
 	container.LogDriver = l
 
 	// set LogPath field only for json-file logdriver
-	if jl, ok := l.(*jsonfilelog.JSONFileLogger); ok {
+	if jl, ok := l.(logPather); ok {
 		container.LogPath = jl.LogPath()
 	}
 
@@ -3,6 +3,7 @@ package daemon
 import (
 	"io"
 	"runtime"
+	"time"
 
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
@@ -10,6 +11,7 @@ import (
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/containerfs"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/stringid"
@@ -20,10 +22,11 @@ import (
 )
 
 type releaseableLayer struct {
-	released   bool
-	layerStore layer.Store
-	roLayer    layer.Layer
-	rwLayer    layer.RWLayer
+	released        bool
+	layerStore      layer.Store
+	roLayer         layer.Layer
+	rwLayer         layer.RWLayer
+	sourceDateEpoch *time.Time
 }
 
 func (rl *releaseableLayer) Mount() (containerfs.ContainerFS, error) {
@@ -65,6 +68,9 @@ func (rl *releaseableLayer) Commit(platform string) (builder.ReleaseableLayer, e
 	if err != nil {
 		return nil, err
 	}
+	if rl.sourceDateEpoch != nil {
+		stream = archive.ClampTimesTarWrapper(stream, *rl.sourceDateEpoch)
+	}
 	defer stream.Close()
 
 	newLayer, err := rl.layerStore.Register(stream, chainID, layer.Platform(platform))
@@ -74,9 +80,9 @@ func (rl *releaseableLayer) Commit(platform string) (builder.ReleaseableLayer, e
 
 	if layer.IsEmpty(newLayer.DiffID()) {
 		_, err := rl.layerStore.Release(newLayer)
-		return &releaseableLayer{layerStore: rl.layerStore}, err
+		return &releaseableLayer{layerStore: rl.layerStore, sourceDateEpoch: rl.sourceDateEpoch}, err
 	}
-	return &releaseableLayer{layerStore: rl.layerStore, roLayer: newLayer}, nil
+	return &releaseableLayer{layerStore: rl.layerStore, roLayer: newLayer, sourceDateEpoch: rl.sourceDateEpoch}, nil
 }
 
 func (rl *releaseableLayer) DiffID() layer.DiffID {
@@ -132,9 +138,9 @@ func (rl *releaseableLayer) releaseROLayer() error {
 	return err
 }
 
-func newReleasableLayerForImage(img *image.Image, layerStore layer.Store) (builder.ReleaseableLayer, error) {
+func newReleasableLayerForImage(img *image.Image, layerStore layer.Store, sourceDateEpoch *time.Time) (builder.ReleaseableLayer, error) {
 	if img == nil || img.RootFS.ChainID() == "" {
-		return &releaseableLayer{layerStore: layerStore}, nil
+		return &releaseableLayer{layerStore: layerStore, sourceDateEpoch: sourceDateEpoch}, nil
 	}
 	// Hold a reference to the image layer so that it can't be removed before
 	// it is released
@@ -142,7 +148,7 @@ func newReleasableLayerForImage(img *image.Image, layerStore layer.Store) (build
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get layer for image %s", img.ImageID())
 	}
-	return &releaseableLayer{layerStore: layerStore, roLayer: roLayer}, nil
+	return &releaseableLayer{layerStore: layerStore, roLayer: roLayer, sourceDateEpoch: sourceDateEpoch}, nil
 }
 
 // TODO: could this use the regular daemon PullImage ? id:47 gh:48
@@ -176,7 +182,7 @@ func (daemon *Daemon) pullForBuilder(ctx context.Context, name string, authConfi
 // leaking of layers.
 func (daemon *Daemon) GetImageAndReleasableLayer(ctx context.Context, refOrID string, opts backend.GetImageAndLayerOptions) (builder.Image, builder.ReleaseableLayer, error) {
 	if refOrID == "" {
-		layer, err := newReleasableLayerForImage(nil, daemon.stores[opts.Platform].layerStore)
+		layer, err := newReleasableLayerForImage(nil, daemon.stores[opts.Platform].layerStore, opts.SourceDateEpoch)
 		return nil, layer, err
 	}
 
@@ -187,7 +193,7 @@ func (daemon *Daemon) GetImageAndReleasableLayer(ctx context.Context, refOrID st
 		}
 		// TODO: shouldn't we error out if error is different from "not found" ? id:72 gh:73
 		if image != nil {
-			layer, err := newReleasableLayerForImage(image, daemon.stores[opts.Platform].layerStore)
+			layer, err := newReleasableLayerForImage(image, daemon.stores[opts.Platform].layerStore, opts.SourceDateEpoch)
 			return image, layer, err
 		}
 	}
@@ -196,10 +202,40 @@ func (daemon *Daemon) GetImageAndReleasableLayer(ctx context.Context, refOrID st
 	if err != nil {
 		return nil, nil, err
 	}
-	layer, err := newReleasableLayerForImage(image, daemon.stores[opts.Platform].layerStore)
+	layer, err := newReleasableLayerForImage(image, daemon.stores[opts.Platform].layerStore, opts.SourceDateEpoch)
 	return image, layer, err
 }
 
+// ImageLayerSize returns the size of the layer created by the last step of the
+// history of an image, or 0 if that step created no layer.
+func (daemon *Daemon) ImageLayerSize(imageID string) (int64, error) {
+	img, err := daemon.GetImage(imageID)
+	if err != nil {
+		return 0, err
+	}
+	if len(img.RootFS.DiffIDs) == 0 {
+		return 0, nil
+	}
+	if n := len(img.History); n > 0 && img.History[n-1].EmptyLayer {
+		return 0, nil
+	}
+	platform := img.OS
+	if platform == "" {
+		platform = runtime.GOOS
+	}
+	store, ok := daemon.stores[platform]
+	if !ok {
+		return 0, errors.Errorf("no layer store for platform %s of image %s", platform, imageID)
+	}
+	layerStore := store.layerStore
+	l, err := layerStore.Get(img.RootFS.ChainID())
+	if err != nil {
+		return 0, err
+	}
+	defer layer.ReleaseAndLog(layerStore, l)
+	return l.DiffSize()
+}
+
 // CreateImage creates a new image by adding a config and ID to the image store.
 // This is similar to LoadImage() except that it receives JSON encoded bytes of
 // an image instead of a tar archive.
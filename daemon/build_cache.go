@@ -0,0 +1,336 @@
+package daemon
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/builder"
+	dockerdistribution "github.com/docker/docker/distribution"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/image/cache"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/registry"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// buildCacheManifestFile is the name of the build cache manifest in the
+// tarball of a local build cache. The layers are stored as
+// "blobs/<algorithm>/<hex>".
+const buildCacheManifestFile = "buildcache.json"
+
+// ImportBuildCache returns an image cache matching the build cache stored at
+// src. The layers of the build cache are only read when they are matched.
+func (daemon *Daemon) ImportBuildCache(ctx context.Context, src builder.CacheSpec, opts backend.BuildCacheOptions) (builder.ImageCache, error) {
+	var (
+		config   []byte
+		openBlob cache.BlobOpener
+	)
+	switch src.Type {
+	case builder.CacheTypeRegistry:
+		ref, distConfig, err := daemon.buildCacheRegistryConfig(src, opts)
+		if err != nil {
+			return nil, err
+		}
+		var blobs distribution.BlobProvider
+		config, blobs, err = dockerdistribution.PullBuildCache(ctx, ref, cache.MediaTypeManifest, distConfig)
+		if err != nil {
+			return nil, err
+		}
+		openBlob = func(dgst digest.Digest) (io.ReadCloser, error) {
+			return blobs.Open(ctx, dgst)
+		}
+	case builder.CacheTypeLocal:
+		p, err := daemon.buildCachePath(src.Path)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := openBuildCacheFile(p, buildCacheManifestFile)
+		if err != nil {
+			return nil, err
+		}
+		config, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		openBlob = func(dgst digest.Digest) (io.ReadCloser, error) {
+			return openBuildCacheFile(p, buildCacheBlobPath(dgst))
+		}
+	default:
+		return nil, errors.Errorf("unsupported build cache type %q", src.Type)
+	}
+
+	var m cache.Manifest
+	if err := json.Unmarshal(config, &m); err != nil {
+		return nil, errors.Wrap(err, "invalid build cache manifest")
+	}
+	return cache.NewImported(daemon.stores[opts.Platform].imageStore, daemon.stores[opts.Platform].layerStore, opts.Platform, &m, openBlob)
+}
+
+// ExportBuildCache stores the build cache of the images of chains at dest.
+func (daemon *Daemon) ExportBuildCache(ctx context.Context, dest builder.CacheSpec, chains []builder.CacheChain, opts backend.BuildCacheOptions) error {
+	var destPath string
+	if dest.Type == builder.CacheTypeLocal {
+		p, err := daemon.buildCachePath(dest.Path)
+		if err != nil {
+			return err
+		}
+		destPath = p
+	}
+
+	imageStore := daemon.stores[opts.Platform].imageStore
+	layerStore := daemon.stores[opts.Platform].layerStore
+
+	m := cache.Manifest{SchemaVersion: cache.ManifestSchemaVersion}
+	seen := make(map[digest.Digest]bool)
+	for _, chain := range chains {
+		records, err := cache.Records(imageStore, image.ID(chain.Top), image.ID(chain.Base))
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			dgst := digest.FromBytes(r.Config)
+			if !seen[dgst] {
+				seen[dgst] = true
+				m.Records = append(m.Records, r)
+			}
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "docker-buildcache-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// compress the layers of the build cache
+	blobs := make(map[layer.DiffID]dockerdistribution.BuildCacheBlob)
+	var order []layer.DiffID
+	for i, r := range m.Records {
+		if r.Layer == nil {
+			continue
+		}
+		blob, ok := blobs[r.Layer.DiffID]
+		if !ok {
+			img, err := image.NewFromJSON(r.Config)
+			if err != nil {
+				return err
+			}
+			blob, err = compressBuildCacheLayer(layerStore, img.RootFS.ChainID(), tmpDir)
+			if err != nil {
+				return err
+			}
+			blobs[r.Layer.DiffID] = blob
+			order = append(order, r.Layer.DiffID)
+		}
+		m.Records[i].Layer.Digest = blob.Digest
+		m.Records[i].Layer.Size = blob.Size
+	}
+
+	config, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	switch dest.Type {
+	case builder.CacheTypeRegistry:
+		ref, distConfig, err := daemon.buildCacheRegistryConfig(dest, opts)
+		if err != nil {
+			return err
+		}
+		var pushed []dockerdistribution.BuildCacheBlob
+		for _, diffID := range order {
+			pushed = append(pushed, blobs[diffID])
+		}
+		return dockerdistribution.PushBuildCache(ctx, ref, cache.MediaTypeManifest, config, pushed, distConfig)
+	case builder.CacheTypeLocal:
+		return writeBuildCacheFile(destPath, config, blobs)
+	default:
+		return errors.Errorf("unsupported build cache type %q", dest.Type)
+	}
+}
+
+// buildCachePath returns the path on the daemon host of the tarball of a
+// local build cache. Local build caches are only stored in the build cache
+// directory of the daemon, so that builds can't read or write other files.
+func (daemon *Daemon) buildCachePath(p string) (string, error) {
+	dir := daemon.configStore.BuildCacheDir
+	if dir == "" {
+		return "", validationError{errors.New("local build caches are disabled, the daemon must be started with --build-cache-dir")}
+	}
+	if clean := filepath.Clean(p); filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", validationError{errors.Errorf("build cache path %s must be relative to the build cache directory", p)}
+	}
+	return symlink.FollowSymlinkInScope(filepath.Join(dir, p), dir)
+}
+
+func (daemon *Daemon) buildCacheRegistryConfig(spec builder.CacheSpec, opts backend.BuildCacheOptions) (reference.NamedTagged, *dockerdistribution.Config, error) {
+	named, err := reference.ParseNormalizedNamed(spec.Ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	ref, ok := reference.TagNameOnly(named).(reference.NamedTagged)
+	if !ok {
+		return nil, nil, errors.Errorf("build cache reference %s must be a tag", spec.Ref)
+	}
+
+	authConfig := &types.AuthConfig{}
+	if len(opts.AuthConfig) > 0 {
+		repoInfo, err := daemon.RegistryService.ResolveRepository(ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolvedConfig := registry.ResolveAuthConfig(opts.AuthConfig, repoInfo.Index)
+		authConfig = &resolvedConfig
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = ioutil.Discard
+	}
+	return ref, &dockerdistribution.Config{
+		AuthConfig:      authConfig,
+		ProgressOutput:  streamformatter.NewJSONProgressOutput(output, false),
+		RegistryService: daemon.RegistryService,
+	}, nil
+}
+
+// compressBuildCacheLayer writes the diff of a layer, gzipped, in dir.
+func compressBuildCacheLayer(ls layer.Store, chainID layer.ChainID, dir string) (dockerdistribution.BuildCacheBlob, error) {
+	var blob dockerdistribution.BuildCacheBlob
+
+	l, err := ls.Get(chainID)
+	if err != nil {
+		return blob, err
+	}
+	defer layer.ReleaseAndLog(ls, l)
+
+	tarStream, err := l.TarStream()
+	if err != nil {
+		return blob, err
+	}
+	defer tarStream.Close()
+
+	f, err := ioutil.TempFile(dir, "layer-")
+	if err != nil {
+		return blob, err
+	}
+	defer f.Close()
+
+	digester := digest.Canonical.Digester()
+	counter := ioutils.NewWriteCounter(io.MultiWriter(f, digester.Hash()))
+	compressed, err := archive.CompressStream(counter, archive.Gzip)
+	if err != nil {
+		return blob, err
+	}
+	if _, err := io.Copy(compressed, tarStream); err != nil {
+		compressed.Close()
+		return blob, err
+	}
+	if err := compressed.Close(); err != nil {
+		return blob, err
+	}
+
+	name := f.Name()
+	blob.Descriptor = distribution.Descriptor{
+		MediaType: schema2.MediaTypeLayer,
+		Digest:    digester.Digest(),
+		Size:      counter.Count,
+	}
+	blob.Open = func() (io.ReadCloser, error) {
+		return os.Open(name)
+	}
+	return blob, nil
+}
+
+func buildCacheBlobPath(dgst digest.Digest) string {
+	return path.Join("blobs", dgst.Algorithm().String(), dgst.Hex())
+}
+
+// writeBuildCacheFile writes the tarball of a local build cache.
+func writeBuildCacheFile(dest string, config []byte, blobs map[layer.DiffID]dockerdistribution.BuildCacheBlob) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	f, err := ioutils.NewAtomicFileWriter(dest, 0644)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(f)
+
+	err = func() error {
+		if err := tw.WriteHeader(&tar.Header{Name: buildCacheManifestFile, Mode: 0644, Size: int64(len(config))}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(config); err != nil {
+			return err
+		}
+
+		var sorted []dockerdistribution.BuildCacheBlob
+		for _, blob := range blobs {
+			sorted = append(sorted, blob)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Digest < sorted[j].Digest })
+		for _, blob := range sorted {
+			if err := tw.WriteHeader(&tar.Header{Name: buildCacheBlobPath(blob.Digest), Mode: 0644, Size: blob.Size}); err != nil {
+				return err
+			}
+			rc, err := blob.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	}()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openBuildCacheFile returns the content of a file of the tarball of a local
+// build cache.
+func openBuildCacheFile(src, name string) (io.ReadCloser, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			f.Close()
+			return nil, errors.Errorf("%s not found in build cache %s", name, src)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if path.Clean(hdr.Name) == name {
+			return ioutils.NewReadCloserWrapper(tr, f.Close), nil
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/config"
+	"github.com/docker/docker/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCachePath(t *testing.T) {
+	d := &Daemon{configStore: &config.Config{}}
+	_, err := d.buildCachePath("app.tar")
+	testutil.ErrorContains(t, err, "local build caches are disabled")
+
+	dir, err := ioutil.TempDir("", "build-cache-dir-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	d.configStore.BuildCacheDir = dir
+
+	p, err := d.buildCachePath("app/cache.tar")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "app", "cache.tar"), p)
+
+	for _, p := range []string{"/etc/passwd", "../app.tar", "app/../../app.tar"} {
+		_, err := d.buildCachePath(p)
+		testutil.ErrorContains(t, err, "must be relative to the build cache directory")
+	}
+
+	// symlinks are resolved within the build cache directory
+	require.NoError(t, os.Symlink("/etc", filepath.Join(dir, "link")))
+	p, err = d.buildCachePath("link/passwd")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "etc", "passwd"), p)
+}
@@ -54,7 +54,7 @@ type Backend interface {
 	DaemonJoinsCluster(provider cluster.Provider)
 	DaemonLeavesCluster()
 	IsSwarmCompatible() error
-	SubscribeToEvents(since, until time.Time, filter filters.Args) ([]events.Message, chan interface{})
+	SubscribeToEvents(since, until time.Time, filter filters.Args) (func(func(events.Message) bool) error, chan interface{})
 	UnsubscribeFromEvents(listener chan interface{})
 	UpdateAttachment(string, string, string, *network.NetworkingConfig) error
 	WaitForDetachment(context.Context, string, string, string, string) error
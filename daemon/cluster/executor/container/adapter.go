@@ -313,12 +313,9 @@ func (c *containerAdapter) inspect(ctx context.Context) (types.ContainerJSON, er
 // events. The stream of events can be shutdown by cancelling the context.
 func (c *containerAdapter) events(ctx context.Context) <-chan events.Message {
 	log.G(ctx).Debugf("waiting on events")
-	buffer, l := c.backend.SubscribeToEvents(time.Time{}, time.Time{}, c.container.eventFilter())
-	eventsq := make(chan events.Message, len(buffer))
-
-	for _, event := range buffer {
-		eventsq <- event
-	}
+	// there are no past events to replay without a time range
+	_, l := c.backend.SubscribeToEvents(time.Time{}, time.Time{}, c.container.eventFilter())
+	eventsq := make(chan events.Message)
 
 	go func() {
 		defer c.backend.UnsubscribeFromEvents(l)
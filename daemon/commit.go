@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/pkg/errors"
 )
@@ -86,7 +87,10 @@ func merge(userConf, imageConf *containertypes.Config) error {
 			userConf.Healthcheck = imageConf.Healthcheck
 		} else {
 			if len(userConf.Healthcheck.Test) == 0 {
+				// the HTTP and TCP settings belong to the test
 				userConf.Healthcheck.Test = imageConf.Healthcheck.Test
+				userConf.Healthcheck.HTTP = imageConf.Healthcheck.HTTP
+				userConf.Healthcheck.TCP = imageConf.Healthcheck.TCP
 			}
 			if userConf.Healthcheck.Interval == 0 {
 				userConf.Healthcheck.Interval = imageConf.Healthcheck.Interval
@@ -164,6 +168,9 @@ func (daemon *Daemon) Commit(name string, c *backend.ContainerCommitConfig) (str
 	if err != nil {
 		return "", err
 	}
+	if c.SourceDateEpoch != nil {
+		rwTar = archive.ClampTimesTarWrapper(rwTar, *c.SourceDateEpoch)
+	}
 	defer func() {
 		if rwTar != nil {
 			rwTar.Close()
@@ -199,6 +206,11 @@ func (daemon *Daemon) Commit(name string, c *backend.ContainerCommitConfig) (str
 		Config:          newConfig,
 		DiffID:          l.DiffID(),
 	}
+	if c.SourceDateEpoch != nil {
+		// the ID of the container is random
+		cc.ContainerID = ""
+		cc.Created = *c.SourceDateEpoch
+	}
 	config, err := json.Marshal(image.NewChildImage(parent, cc, container.Platform))
 	if err != nil {
 		return "", err
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	daemondiscovery "github.com/docker/docker/daemon/discovery"
 	"github.com/docker/docker/opts"
@@ -42,6 +44,8 @@ const (
 	DisableNetworkBridge = "none"
 	// DefaultInitBinary is the name of the default init binary
 	DefaultInitBinary = "docker-init"
+	// DefaultEventsMaxSize is the default maximum size of the events journal
+	DefaultEventsMaxSize = int64(64 * 1024 * 1024)
 )
 
 // flatOptions contains configuration keys
@@ -53,6 +57,8 @@ var flatOptions = map[string]bool{
 	"log-opts":           true,
 	"runtimes":           true,
 	"default-ulimits":    true,
+	"api-rate-limits":    true,
+	"build-secrets":      true,
 }
 
 // LogConfig represents the default log configuration.
@@ -140,6 +146,15 @@ type CommonConfig struct {
 	// to stop when daemon is being shutdown
 	ShutdownTimeout int `json:"shutdown-timeout,omitempty"`
 
+	// EventsMaxSize is the maximum size on disk of the journal of events
+	// kept by the daemon. Oldest events are discarded first.
+	EventsMaxSize opts.MemBytes `json:"events-max-size,omitempty"`
+
+	// EventsMaxAge is the duration (e.g. "72h") after which events are
+	// discarded from the journal. Events are kept regardless of their age
+	// when it is not set.
+	EventsMaxAge string `json:"events-max-age,omitempty"`
+
 	Debug     bool     `json:"debug,omitempty"`
 	Hosts     []string `json:"hosts,omitempty"`
 	LogLevel  string   `json:"log-level,omitempty"`
@@ -157,6 +172,22 @@ type CommonConfig struct {
 	SwarmDefaultAdvertiseAddr string `json:"swarm-default-advertise-addr"`
 	MetricsAddress            string `json:"metrics-addr"`
 
+	// APIRateLimits are the budgets of API requests allowed per client,
+	// keyed by route.
+	APIRateLimits map[string]string `json:"api-rate-limits,omitempty"`
+	// BuildSecrets are the files on the daemon host that builds can mount
+	// with RUN --mount=type=secret, keyed by secret id.
+	BuildSecrets map[string]string `json:"build-secrets,omitempty"`
+	// BuildCacheDir is the directory on the daemon host that builds can
+	// export their build cache to, and import it from, with type=local.
+	BuildCacheDir string `json:"build-cache-dir,omitempty"`
+	// BuildAllowHostNetwork allows the RUN instructions of builds to use
+	// the network namespace of the host with --network=host.
+	BuildAllowHostNetwork bool `json:"build-allow-host-network,omitempty"`
+	// MetricsContainerLabels are the container labels added as labels to
+	// the per-container metrics.
+	MetricsContainerLabels []string `json:"metrics-container-labels,omitempty"`
+
 	LogConfig
 	BridgeConfig // bridgeConfig holds bridge network specific configuration.
 	registry.ServiceOptions
@@ -189,6 +220,8 @@ func New() *Config {
 	config := Config{}
 	config.LogConfig.Config = make(map[string]string)
 	config.ClusterOpts = make(map[string]string)
+	config.APIRateLimits = make(map[string]string)
+	config.BuildSecrets = make(map[string]string)
 
 	if runtime.GOOS != "linux" {
 		config.V2Only = true
@@ -494,6 +527,26 @@ func Validate(config *Config) error {
 		return fmt.Errorf("invalid max concurrent uploads: %d", *config.MaxConcurrentUploads)
 	}
 
+	// validate events journal retention
+	if config.EventsMaxSize < 0 {
+		return fmt.Errorf("invalid events max size: %d", config.EventsMaxSize)
+	}
+	if config.EventsMaxAge != "" {
+		if d, err := time.ParseDuration(config.EventsMaxAge); err != nil || d < 0 {
+			return fmt.Errorf("invalid events max age: %s", config.EventsMaxAge)
+		}
+	}
+
+	// validate build secrets
+	for id, p := range config.BuildSecrets {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("build secret %s must be an absolute path: %s", id, p)
+		}
+	}
+	if config.BuildCacheDir != "" && !filepath.IsAbs(config.BuildCacheDir) {
+		return fmt.Errorf("build cache directory must be an absolute path: %s", config.BuildCacheDir)
+	}
+
 	// validate that "default" runtime is not reset
 	if runtimes := config.GetAllRuntimes(); len(runtimes) > 0 {
 		if _, ok := runtimes[StockRuntimeName]; ok {
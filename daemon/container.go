@@ -282,6 +282,10 @@ func (daemon *Daemon) verifyContainerSettings(platform string, hostConfig *conta
 			if config.Healthcheck.StartPeriod != 0 && config.Healthcheck.StartPeriod < containertypes.MinimumDuration {
 				return nil, errors.Errorf("StartPeriod in Healthcheck cannot be less than %s", containertypes.MinimumDuration)
 			}
+
+			if err := validateNetworkHealthcheck(config.Healthcheck); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -91,6 +91,7 @@ type Daemon struct {
 	idIndex               *truncindex.TruncIndex
 	configStore           *config.Config
 	statsCollector        *stats.Collector
+	containerMetrics      *stats.ContainerMetrics // nil unless metrics-addr is set
 	defaultLogConfig      containertypes.LogConfig
 	RegistryService       registry.Service
 	EventsService         *events.Events
@@ -731,7 +732,11 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 		return nil, err
 	}
 
-	eventsService := events.New()
+	eventsJournal, err := events.OpenJournal(filepath.Join(config.Root, "events"), eventsJournalConfig(config))
+	if err != nil {
+		return nil, err
+	}
+	eventsService := events.NewWithJournal(eventsJournal)
 
 	// We have a single tag/reference store for the daemon globally. However, it's
 	// stored under the graphdriver. On host platforms which only support a single
@@ -792,6 +797,9 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 	d.trustKey = trustKey
 	d.idIndex = truncindex.NewTruncIndex([]string{})
 	d.statsCollector = d.newStatsCollector(1 * time.Second)
+	if config.MetricsAddress != "" {
+		d.containerMetrics = d.newContainerMetrics(config.MetricsContainerLabels)
+	}
 	d.defaultLogConfig = containertypes.LogConfig{
 		Type:   config.LogConfig.Type,
 		Config: config.LogConfig.Config,
@@ -950,6 +958,14 @@ func (daemon *Daemon) Shutdown() error {
 
 	daemon.cleanupMetricsPlugins()
 
+	if daemon.EventsService != nil {
+		if j := daemon.EventsService.Journal(); j != nil {
+			if err := j.Close(); err != nil {
+				logrus.Errorf("Error closing events journal: %v", err)
+			}
+		}
+	}
+
 	// Shutdown plugins after containers and layerstore. Don't change the order.
 	daemon.pluginShutdown()
 
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	containertypes "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/container"
@@ -304,3 +305,34 @@ func TestMerge(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeHealthcheck(t *testing.T) {
+	configImage := &containertypes.Config{
+		Healthcheck: &containertypes.HealthConfig{
+			Test:    []string{"HTTP"},
+			HTTP:    &containertypes.HealthHTTPConfig{Port: 8080, Path: "/health"},
+			Retries: 3,
+		},
+	}
+	// only the timing is overridden, the test comes from the image
+	configUser := &containertypes.Config{
+		Healthcheck: &containertypes.HealthConfig{
+			Interval: time.Second,
+		},
+	}
+
+	if err := merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+
+	hc := configUser.Healthcheck
+	if len(hc.Test) != 1 || hc.Test[0] != "HTTP" {
+		t.Fatalf("Expected the HTTP test of the image, found %v", hc.Test)
+	}
+	if hc.HTTP == nil || hc.HTTP.Port != 8080 || hc.HTTP.Path != "/health" {
+		t.Fatalf("Expected the HTTP settings of the image, found %+v", hc.HTTP)
+	}
+	if hc.Interval != time.Second || hc.Retries != 3 {
+		t.Fatalf("Expected interval 1s and 3 retries, found %s and %d", hc.Interval, hc.Retries)
+	}
+}
@@ -95,7 +95,7 @@ func (daemon *Daemon) SystemDiskUsage(ctx context.Context) (*types.DiskUsage, er
 	}
 
 	// Get total layers size on disk
-	var allLayersSize int64
+	var allLayersSize, allLayersSharedSize int64
 	for platform := range daemon.stores {
 		layerRefs := daemon.getLayerRefs(platform)
 		allLayers := daemon.stores[platform].layerStore.Map()
@@ -116,12 +116,20 @@ func (daemon *Daemon) SystemDiskUsage(ctx context.Context) (*types.DiskUsage, er
 				}
 			}
 		}
+		if s, ok := daemon.stores[platform].layerStore.(layer.SharedSizer); ok {
+			size, err := s.SharedSize()
+			if err != nil {
+				logrus.Warnf("failed to get shared size of layers %s: %v", platform, err)
+			}
+			allLayersSharedSize += size
+		}
 	}
 
 	return &types.DiskUsage{
-		LayersSize: allLayersSize,
-		Containers: allContainers,
-		Volumes:    allVolumes,
-		Images:     allImages,
+		LayersSize:       allLayersSize,
+		LayersSharedSize: allLayersSharedSize,
+		Containers:       allContainers,
+		Volumes:          allVolumes,
+		Images:           allImages,
 	}, nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/daemon/config"
 	daemonevents "github.com/docker/docker/daemon/events"
 	"github.com/docker/libnetwork"
 	swarmapi "github.com/docker/swarmkit/api"
@@ -122,8 +123,8 @@ func (daemon *Daemon) LogDaemonEventWithAttributes(action string, attributes map
 	}
 }
 
-// SubscribeToEvents returns the currently record of events, a channel to stream new events from, and a function to cancel the stream of events.
-func (daemon *Daemon) SubscribeToEvents(since, until time.Time, filter filters.Args) ([]events.Message, chan interface{}) {
+// SubscribeToEvents returns a function replaying the recorded events, and a channel to stream new events from.
+func (daemon *Daemon) SubscribeToEvents(since, until time.Time, filter filters.Args) (func(func(events.Message) bool) error, chan interface{}) {
 	ef := daemonevents.NewFilter(filter)
 	return daemon.EventsService.SubscribeTopic(since, until, ef)
 }
@@ -330,3 +331,14 @@ func eventTimestamp(meta swarmapi.Meta, action swarmapi.WatchActionKind) time.Ti
 	}
 	return eventTime
 }
+
+// eventsJournalConfig returns the retention settings of the events journal
+// from the daemon configuration. The configuration is expected to have been
+// validated already.
+func eventsJournalConfig(conf *config.Config) daemonevents.JournalConfig {
+	jc := daemonevents.JournalConfig{MaxSize: conf.EventsMaxSize.Value()}
+	if conf.EventsMaxAge != "" {
+		jc.MaxAge, _ = time.ParseDuration(conf.EventsMaxAge)
+	}
+	return jc
+}
@@ -6,6 +6,7 @@ import (
 
 	eventtypes "github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/pkg/pubsub"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -15,9 +16,10 @@ const (
 
 // Events is pubsub channel for events generated by the engine.
 type Events struct {
-	mu     sync.Mutex
-	events []eventtypes.Message
-	pub    *pubsub.Publisher
+	mu      sync.Mutex
+	events  []eventtypes.Message
+	pub     *pubsub.Publisher
+	journal *Journal
 }
 
 // New returns new *Events instance
@@ -28,6 +30,19 @@ func New() *Events {
 	}
 }
 
+// NewWithJournal returns new *Events instance which persists every event
+// to the journal, and replays past events from it.
+func NewWithJournal(j *Journal) *Events {
+	e := New()
+	e.journal = j
+	return e
+}
+
+// Journal returns the on-disk journal of the events, if any.
+func (e *Events) Journal() *Journal {
+	return e.journal
+}
+
 // Subscribe adds new listener to events, returns slice of 64 stored
 // last events, a channel in which you can expect new events (in form
 // of interface{}, so you need type assertion), and a function to call
@@ -46,10 +61,13 @@ func (e *Events) Subscribe() ([]eventtypes.Message, chan interface{}, func()) {
 	return current, l, cancel
 }
 
-// SubscribeTopic adds new listener to events, returns slice of 64 stored
-// last events, a channel in which you can expect new events (in form
-// of interface{}, so you need type assertion).
-func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) ([]eventtypes.Message, chan interface{}) {
+// SubscribeTopic adds new listener to events, returns a function which
+// replays the past events emitted between since and until, and a channel
+// in which you can expect new events (in form of interface{}, so you need
+// type assertion). The past events are read from the journal when the
+// replay function is called, so it must be called before draining the
+// channel to keep the events in order.
+func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) (func(func(eventtypes.Message) bool) error, chan interface{}) {
 	eventSubscribers.Inc()
 	e.mu.Lock()
 
@@ -58,7 +76,7 @@ func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) ([]eventtype
 		topic = func(m interface{}) bool { return ef.Include(m.(eventtypes.Message)) }
 	}
 
-	buffered := e.loadBufferedEvents(since, until, topic)
+	replay := e.replayBufferedEvents(since, until, topic)
 
 	var ch chan interface{}
 	if topic != nil {
@@ -69,7 +87,7 @@ func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) ([]eventtype
 	}
 
 	e.mu.Unlock()
-	return buffered, ch
+	return replay, ch
 }
 
 // Evict evicts listener from pubsub
@@ -117,6 +135,13 @@ func (e *Events) PublishMessage(jm eventtypes.Message) {
 	} else {
 		e.events = append(e.events, jm)
 	}
+	if e.journal != nil {
+		// the event is only queued, the journal writes it to disk in the
+		// background
+		if err := e.journal.Append(jm); err != nil {
+			logrus.WithError(err).Error("error writing event to journal")
+		}
+	}
 	e.mu.Unlock()
 	e.pub.Publish(jm)
 }
@@ -126,6 +151,60 @@ func (e *Events) SubscribersCount() int {
 	return e.pub.Len()
 }
 
+// replayBufferedEvents returns a function which calls fn for every past
+// event emitted between since and until, and accepted by the topic function
+// if it's not nil. It must be called with e.mu held, so that the journal is
+// cut at the point where the subscription starts, but the returned function
+// reads the journal without holding any lock, and without loading all the
+// events in memory.
+func (e *Events) replayBufferedEvents(since, until time.Time, topic func(interface{}) bool) func(func(eventtypes.Message) bool) error {
+	if since.IsZero() && until.IsZero() {
+		return func(func(eventtypes.Message) bool) error { return nil }
+	}
+
+	buffered := e.loadBufferedEvents(since, until, topic)
+	if e.journal == nil {
+		return func(fn func(eventtypes.Message) bool) error {
+			for _, ev := range buffered {
+				if !fn(ev) {
+					return nil
+				}
+			}
+			return nil
+		}
+	}
+
+	var sinceNano, untilNano int64
+	if !since.IsZero() {
+		sinceNano = since.UnixNano()
+	}
+	if !until.IsZero() {
+		untilNano = until.UnixNano()
+	}
+	snapshot := e.journal.snapshot()
+
+	return func(fn func(eventtypes.Message) bool) error {
+		var sent bool
+		err := snapshot.read(sinceNano, untilNano, func(ev eventtypes.Message) bool {
+			if topic != nil && !topic(ev) {
+				return true
+			}
+			sent = true
+			return fn(ev)
+		})
+		if err == nil || sent {
+			return err
+		}
+		logrus.WithError(err).Error("error reading events from journal, falling back to buffered events")
+		for _, ev := range buffered {
+			if !fn(ev) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
 // loadBufferedEvents iterates over the cached events in the buffer
 // and returns those that were emitted between two specific dates.
 // It uses `time.Unix(seconds, nanoseconds)` to generate valid dates with those arguments.
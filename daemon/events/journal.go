@@ -0,0 +1,622 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	segmentExt = ".log"
+	indexExt   = ".idx"
+
+	// defaultSegmentSize is the size after which the journal starts writing
+	// to a new segment file.
+	defaultSegmentSize = 8 * 1024 * 1024
+	// minSegmentSize is the smallest segment size used when the configured
+	// journal size is too small to hold several default sized segments.
+	minSegmentSize = 64 * 1024
+	// indexInterval is the number of events between two time index entries.
+	indexInterval = 64
+	// indexEntrySize is the on-disk size of a time index entry: the time of
+	// the event (in nanoseconds) followed by its offset in the segment file.
+	indexEntrySize = 16
+)
+
+// JournalConfig holds the retention settings of an events journal.
+type JournalConfig struct {
+	// MaxSize is the maximum number of bytes kept on disk across all
+	// segments. Zero means no size limit.
+	MaxSize int64
+	// MaxAge is how long events are kept. Zero means no age limit.
+	MaxAge time.Duration
+}
+
+// indexEntry points to the offset of an event in a segment file.
+type indexEntry struct {
+	timeNano int64
+	offset   int64
+}
+
+// segment is a single append-only file of JSON encoded events, along
+// with a sparse index of event times to file offsets.
+type segment struct {
+	path    string
+	first   int64 // time of the first event in the segment, in nanoseconds
+	size    int64
+	count   int
+	entries []indexEntry
+}
+
+// Journal is a size and age bounded on-disk log of events. Events are
+// stored in segment files named after the time of their first event, so
+// that a time range can be located without opening every segment.
+//
+// Appended events are queued in memory and written to disk by a separate
+// goroutine, so that publishing an event never waits for the disk. Readers
+// see the queued events as if they were already written.
+type Journal struct {
+	// mu protects segments, the state of the segments and pending. It is
+	// never held while accessing the disk.
+	mu       sync.Mutex
+	segments []*segment
+	pending  []eventtypes.Message // events appended but not written yet
+	closed   bool
+
+	// writeMu serializes the writes to the disk, and protects the fields
+	// below.
+	writeMu     sync.Mutex
+	root        string
+	config      JournalConfig
+	segmentSize int64
+	active      *os.File
+	activeIndex *os.File
+
+	wake chan struct{} // signals the writer that events are pending
+	done chan struct{} // closed when the writer exits
+}
+
+// OpenJournal opens the events journal stored in root, creating the
+// directory if it does not exist yet. Events written by a previous daemon
+// run are kept, subject to the retention settings in config.
+func OpenJournal(root string, config JournalConfig) (*Journal, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating events journal directory")
+	}
+
+	j := &Journal{
+		root: root,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	j.setConfig(config)
+
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	if err := j.enforceRetention(time.Now()); err != nil {
+		logrus.WithError(err).Warn("error applying events journal retention")
+	}
+	go j.run()
+	return j, nil
+}
+
+// SetConfig updates the retention settings of the journal, and drops the
+// segments which fall outside of the new limits.
+func (j *Journal) SetConfig(config JournalConfig) error {
+	j.writeMu.Lock()
+	defer j.writeMu.Unlock()
+	j.flush()
+	j.setConfig(config)
+	return j.enforceRetention(time.Now())
+}
+
+func (j *Journal) setConfig(config JournalConfig) {
+	j.config = config
+	j.segmentSize = defaultSegmentSize
+	// keep at least a few segments around so that retention does not
+	// drop the whole journal at once
+	if config.MaxSize > 0 && config.MaxSize/4 < j.segmentSize {
+		j.segmentSize = config.MaxSize / 4
+		if j.segmentSize < minSegmentSize {
+			j.segmentSize = minSegmentSize
+		}
+	}
+}
+
+// Append queues an event to be written at the end of the journal.
+func (j *Journal) Append(ev eventtypes.Message) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return errors.New("events journal is closed")
+	}
+	j.pending = append(j.pending, ev)
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run writes the pending events to disk until the journal is closed.
+func (j *Journal) run() {
+	defer close(j.done)
+	for range j.wake {
+		j.writeMu.Lock()
+		j.flush()
+		j.writeMu.Unlock()
+	}
+	// write the events appended before the journal was closed
+	j.writeMu.Lock()
+	j.flush()
+	j.writeMu.Unlock()
+}
+
+// flush writes the pending events to disk. It must be called with
+// writeMu held. Events which cannot be written are dropped.
+func (j *Journal) flush() {
+	for {
+		j.mu.Lock()
+		if len(j.pending) == 0 {
+			j.mu.Unlock()
+			return
+		}
+		ev := j.pending[0]
+		j.mu.Unlock()
+
+		if err := j.write(ev); err != nil {
+			logrus.WithError(err).Error("error writing event to journal")
+			j.mu.Lock()
+			j.pending = j.pending[1:]
+			j.mu.Unlock()
+		}
+	}
+}
+
+// write writes the first pending event, ev, at the end of the journal, and
+// removes it from the pending events once it can be read from the disk. It
+// must be called with writeMu held.
+func (j *Journal) write(ev eventtypes.Message) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s := j.activeSegment()
+	if s == nil || s.size >= j.segmentSize {
+		if err := j.rotate(ev.TimeNano); err != nil {
+			return err
+		}
+		s = j.activeSegment()
+	}
+
+	var entry *indexEntry
+	if s.count%indexInterval == 0 {
+		entry = &indexEntry{timeNano: ev.TimeNano, offset: s.size}
+		if err := writeIndexEntry(j.activeIndex, *entry); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.active.Write(b)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if entry != nil {
+		s.entries = append(s.entries, *entry)
+	}
+	s.size += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "error writing to events journal")
+	}
+	s.count++
+	j.pending = j.pending[1:]
+	return nil
+}
+
+// Read calls fn for every event in the journal that was emitted between
+// since and until, in the order they were written. A zero since or until
+// leaves that side of the range open. Reading stops early if fn returns
+// false.
+func (j *Journal) Read(since, until time.Time, fn func(eventtypes.Message) bool) error {
+	var sinceNano, untilNano int64
+	if !since.IsZero() {
+		sinceNano = since.UnixNano()
+	}
+	if !until.IsZero() {
+		untilNano = until.UnixNano()
+	}
+
+	return j.snapshot().read(sinceNano, untilNano, fn)
+}
+
+// journalSnapshot is a copy of the segments of a journal and of its
+// pending events at some point in time.
+type journalSnapshot struct {
+	segments []segment
+	pending  []eventtypes.Message
+}
+
+// snapshot returns a copy of the segments and pending events as they are
+// now. The events appended up to this point can then be read without
+// holding the lock, while new events keep being appended.
+func (j *Journal) snapshot() journalSnapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	segments := make([]segment, len(j.segments))
+	for i, s := range j.segments {
+		segments[i] = *s
+	}
+	pending := make([]eventtypes.Message, len(j.pending))
+	copy(pending, j.pending)
+	return journalSnapshot{segments: segments, pending: pending}
+}
+
+// read calls fn for every event of the snapshot emitted between sinceNano
+// and untilNano. Segments removed from the disk in the meantime are
+// skipped.
+func (js journalSnapshot) read(sinceNano, untilNano int64, fn func(eventtypes.Message) bool) error {
+	for i, s := range js.segments {
+		// every event of this segment was emitted before the first
+		// event of the next one
+		if i+1 < len(js.segments) && js.segments[i+1].first < sinceNano {
+			continue
+		}
+		if untilNano > 0 && s.first > untilNano {
+			break
+		}
+		more, err := s.read(sinceNano, untilNano, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	for _, ev := range js.pending {
+		if ev.TimeNano < sinceNano || (untilNano > 0 && ev.TimeNano > untilNano) {
+			continue
+		}
+		if !fn(ev) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Close writes the pending events to disk, and closes the files held open
+// by the journal.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return nil
+	}
+	j.closed = true
+	close(j.wake)
+	j.mu.Unlock()
+
+	<-j.done
+	j.writeMu.Lock()
+	defer j.writeMu.Unlock()
+	return j.closeActive()
+}
+
+// activeSegment returns the segment being written. It must be called with
+// writeMu held.
+func (j *Journal) activeSegment() *segment {
+	if j.active == nil || len(j.segments) == 0 {
+		return nil
+	}
+	return j.segments[len(j.segments)-1]
+}
+
+func (j *Journal) closeActive() error {
+	if j.active == nil {
+		return nil
+	}
+	err := j.active.Close()
+	if ierr := j.activeIndex.Close(); err == nil {
+		err = ierr
+	}
+	j.active, j.activeIndex = nil, nil
+	return err
+}
+
+// rotate starts a new segment whose first event was emitted at timeNano.
+func (j *Journal) rotate(timeNano int64) error {
+	if err := j.closeActive(); err != nil {
+		logrus.WithError(err).Warn("error closing events journal segment")
+	}
+
+	// segment names must be unique and sorted, so never go back in time
+	if last := j.lastSegment(); last != nil && timeNano <= last.first {
+		timeNano = last.first + 1
+	}
+
+	s := &segment{
+		path:  filepath.Join(j.root, strconv.FormatInt(timeNano, 10)+segmentExt),
+		first: timeNano,
+	}
+	if err := j.openSegment(s); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.segments = append(j.segments, s)
+	j.mu.Unlock()
+
+	return j.enforceRetention(time.Now())
+}
+
+func (j *Journal) lastSegment() *segment {
+	if len(j.segments) == 0 {
+		return nil
+	}
+	return j.segments[len(j.segments)-1]
+}
+
+// openSegment opens the segment and its index for appending.
+func (j *Journal) openSegment(s *segment) error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error opening events journal segment")
+	}
+	idx, err := os.OpenFile(s.indexPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "error opening events journal index")
+	}
+	j.active, j.activeIndex = f, idx
+	return nil
+}
+
+// enforceRetention removes the oldest segments until the journal fits in
+// the configured size and age. The active segment is never removed.
+func (j *Journal) enforceRetention(now time.Time) error {
+	var expiry int64
+	if j.config.MaxAge > 0 {
+		expiry = now.Add(-j.config.MaxAge).UnixNano()
+	}
+
+	j.mu.Lock()
+	var total int64
+	for _, s := range j.segments {
+		total += s.size
+	}
+	var removed []*segment
+	for len(j.segments) > 1 {
+		s := j.segments[0]
+		overSize := j.config.MaxSize > 0 && total > j.config.MaxSize
+		// the last event of a segment is older than the first one of
+		// the segment that follows it
+		expired := expiry > 0 && j.segments[1].first < expiry
+		if !overSize && !expired {
+			break
+		}
+		removed = append(removed, s)
+		total -= s.size
+		j.segments = j.segments[1:]
+	}
+	j.mu.Unlock()
+
+	// readers skip the segments removed while they read the journal
+	for _, s := range removed {
+		if err := s.remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads the list of segments from disk, and reopens the last one for
+// appending.
+func (j *Journal) load() error {
+	fis, err := ioutil.ReadDir(j.root)
+	if err != nil {
+		return errors.Wrap(err, "error reading events journal directory")
+	}
+
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		first, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			logrus.Warnf("ignoring unexpected file in events journal: %s", name)
+			continue
+		}
+		j.segments = append(j.segments, &segment{
+			path:  filepath.Join(j.root, name),
+			first: first,
+			size:  fi.Size(),
+		})
+	}
+	sort.Slice(j.segments, func(a, b int) bool { return j.segments[a].first < j.segments[b].first })
+
+	for i, s := range j.segments {
+		// the last segment may have been cut short by a crash, so check
+		// and repair it before appending to it
+		if err := s.loadIndex(i == len(j.segments)-1); err != nil {
+			return err
+		}
+	}
+
+	if s := j.lastSegment(); s != nil {
+		return j.openSegment(s)
+	}
+	return nil
+}
+
+func (s *segment) indexPath() string {
+	return strings.TrimSuffix(s.path, segmentExt) + indexExt
+}
+
+func (s *segment) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error removing events journal segment")
+	}
+	if err := os.Remove(s.indexPath()); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error removing events journal index")
+	}
+	return nil
+}
+
+// loadIndex reads the time index of the segment. If the index is missing
+// or repair is set, the events following the last index entry are scanned
+// to complete the index and drop a partially written trailing event.
+func (s *segment) loadIndex(repair bool) error {
+	b, err := ioutil.ReadFile(s.indexPath())
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error reading events journal index")
+	}
+	for len(b) >= indexEntrySize {
+		e := indexEntry{
+			timeNano: int64(binary.LittleEndian.Uint64(b[:8])),
+			offset:   int64(binary.LittleEndian.Uint64(b[8:16])),
+		}
+		b = b[indexEntrySize:]
+		if e.offset >= s.size {
+			break
+		}
+		s.entries = append(s.entries, e)
+	}
+	s.count = len(s.entries) * indexInterval
+
+	if !repair && len(s.entries) > 0 && len(b) == 0 {
+		return nil
+	}
+	return s.rebuildIndex()
+}
+
+// rebuildIndex scans the segment from its last index entry, rewriting the
+// index and truncating the segment after its last complete event.
+func (s *segment) rebuildIndex() error {
+	var offset int64
+	if n := len(s.entries); n > 0 {
+		offset = s.entries[n-1].offset
+		s.entries = s.entries[:n-1]
+		s.count = (n - 1) * indexInterval
+	}
+
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error opening events journal segment")
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	rd := bufio.NewReader(f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "error reading events journal segment")
+		}
+		var ev eventtypes.Message
+		if err := json.Unmarshal(line, &ev); err != nil {
+			logrus.WithError(err).Warnf("discarding corrupted events in journal segment %s", s.path)
+			break
+		}
+		if s.count%indexInterval == 0 {
+			s.entries = append(s.entries, indexEntry{timeNano: ev.TimeNano, offset: offset})
+		}
+		offset += int64(len(line))
+		s.count++
+	}
+
+	if offset != s.size {
+		if err := f.Truncate(offset); err != nil {
+			return errors.Wrap(err, "error truncating events journal segment")
+		}
+		s.size = offset
+	}
+
+	var buf bytes.Buffer
+	for _, e := range s.entries {
+		writeIndexEntry(&buf, e)
+	}
+	if err := ioutil.WriteFile(s.indexPath(), buf.Bytes(), 0600); err != nil {
+		return errors.Wrap(err, "error writing events journal index")
+	}
+	return nil
+}
+
+// read calls fn for each event of the segment within the time range, and
+// returns false if fn asked to stop reading.
+func (s *segment) read(sinceNano, untilNano int64, fn func(eventtypes.Message) bool) (bool, error) {
+	// start from the last index entry strictly before since; there is no
+	// event in the range before it
+	var offset int64
+	if sinceNano > 0 {
+		i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].timeNano >= sinceNano })
+		if i > 0 {
+			offset = s.entries[i-1].offset
+		}
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, errors.Wrap(err, "error opening events journal segment")
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	rd := bufio.NewReader(io.LimitReader(f, s.size-offset))
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "error reading events journal segment")
+		}
+		var ev eventtypes.Message
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return false, fmt.Errorf("error decoding event from journal segment %s: %v", s.path, err)
+		}
+		if ev.TimeNano < sinceNano || (untilNano > 0 && ev.TimeNano > untilNano) {
+			continue
+		}
+		if !fn(ev) {
+			return false, nil
+		}
+	}
+}
+
+func writeIndexEntry(w io.Writer, e indexEntry) error {
+	var b [indexEntrySize]byte
+	binary.LittleEndian.PutUint64(b[:8], uint64(e.timeNano))
+	binary.LittleEndian.PutUint64(b[8:], uint64(e.offset))
+	if _, err := w.Write(b[:]); err != nil {
+		return errors.Wrap(err, "error writing events journal index")
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	eventtypes "github.com/docker/docker/api/types/events"
+)
+
+func newJournalMessage(i int, t time.Time) eventtypes.Message {
+	return eventtypes.Message{
+		Type:     eventtypes.ContainerEventType,
+		Action:   fmt.Sprintf("action_%d", i),
+		Actor:    eventtypes.Actor{ID: fmt.Sprintf("cont_%d", i)},
+		Time:     t.Unix(),
+		TimeNano: t.UnixNano(),
+	}
+}
+
+// flushJournal waits for the events appended to j to be written to disk.
+func flushJournal(j *Journal) {
+	j.writeMu.Lock()
+	j.flush()
+	j.writeMu.Unlock()
+}
+
+func readJournal(t *testing.T, j *Journal, since, until time.Time) []eventtypes.Message {
+	var out []eventtypes.Message
+	err := j.Read(since, until, func(ev eventtypes.Message) bool {
+		out = append(out, ev)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestJournalReadRange(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	j, err := OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	// force several segments
+	j.segmentSize = 4096
+
+	start := time.Unix(1500000000, 0)
+	for i := 0; i < 1000; i++ {
+		if err := j.Append(newJournalMessage(i, start.Add(time.Duration(i)*time.Second))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flushJournal(j)
+	if len(j.segments) < 2 {
+		t.Fatalf("expected several segments, got %d", len(j.segments))
+	}
+
+	out := readJournal(t, j, start.Add(500*time.Second), start.Add(509*time.Second))
+	if len(out) != 10 {
+		t.Fatalf("expected 10 events, got %d", len(out))
+	}
+	if out[0].Action != "action_500" || out[9].Action != "action_509" {
+		t.Fatalf("unexpected events range: %s..%s", out[0].Action, out[9].Action)
+	}
+
+	if out := readJournal(t, j, time.Time{}, time.Time{}); len(out) != 1000 {
+		t.Fatalf("expected 1000 events, got %d", len(out))
+	}
+}
+
+func TestJournalReopen(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	j, err := OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1500000000, 0)
+	for i := 0; i < 100; i++ {
+		if err := j.Append(newJournalMessage(i, start.Add(time.Duration(i)*time.Second))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash in the middle of writing an event
+	last := filepath.Join(root, fmt.Sprintf("%d%s", start.UnixNano(), segmentExt))
+	f, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"Type":"container","Act`)
+	f.Close()
+
+	j, err = OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if err := j.Append(newJournalMessage(100, start.Add(100*time.Second))); err != nil {
+		t.Fatal(err)
+	}
+
+	out := readJournal(t, j, start.Add(90*time.Second), time.Time{})
+	if len(out) != 11 {
+		t.Fatalf("expected 11 events, got %d", len(out))
+	}
+	if out[10].Action != "action_100" {
+		t.Fatalf("expected action_100, got %s", out[10].Action)
+	}
+}
+
+func TestJournalRetention(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	j, err := OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	j.segmentSize = 4096
+
+	start := time.Now().Add(-48 * time.Hour)
+	for i := 0; i < 1000; i++ {
+		if err := j.Append(newJournalMessage(i, start.Add(time.Duration(i)*3*time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := j.SetConfig(JournalConfig{MaxAge: 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	out := readJournal(t, j, time.Time{}, time.Time{})
+	if len(out) == 0 || len(out) == 1000 {
+		t.Fatalf("expected old events to be discarded, got %d events", len(out))
+	}
+	// only whole segments are discarded, so the oldest event left may be
+	// older than the limit, but not older than a segment
+	if oldest := time.Unix(0, out[0].TimeNano); oldest.Before(time.Now().Add(-25 * time.Hour)) {
+		t.Fatalf("unexpected oldest event: %s", oldest)
+	}
+
+	if err := j.SetConfig(JournalConfig{MaxSize: 16 * 1024}); err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, s := range j.segments {
+		total += s.size
+	}
+	if total > 16*1024 {
+		t.Fatalf("expected journal to be smaller than 16KB, got %d bytes", total)
+	}
+	out = readJournal(t, j, time.Time{}, time.Time{})
+	if last := out[len(out)-1]; last.Action != "action_999" {
+		t.Fatalf("expected newest event to be kept, got %s", last.Action)
+	}
+}
+
+func TestEventsWithJournalReplay(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	j, err := OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewWithJournal(j)
+	since := time.Now()
+	for i := 0; i < eventsLimit+16; i++ {
+		e.Log(fmt.Sprintf("action_%d", i), eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont"})
+	}
+	j.Close()
+
+	// events survive a restart
+	j, err = OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	e = NewWithJournal(j)
+
+	replay, l := e.SubscribeTopic(since, time.Time{}, nil)
+	defer e.Evict(l)
+	var buffered []eventtypes.Message
+	err = replay(func(ev eventtypes.Message) bool {
+		buffered = append(buffered, ev)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buffered) != eventsLimit+16 {
+		t.Fatalf("expected %d events, got %d", eventsLimit+16, len(buffered))
+	}
+	if buffered[0].Action != "action_0" {
+		t.Fatalf("expected action_0, got %s", buffered[0].Action)
+	}
+}
+
+func TestEventsWithJournalReplayDoesNotBlockPublish(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	j, err := OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	e := NewWithJournal(j)
+	since := time.Now()
+	for i := 0; i < 4; i++ {
+		e.Log(fmt.Sprintf("action_%d", i), eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont"})
+	}
+
+	replay, l := e.SubscribeTopic(since, time.Time{}, nil)
+	defer e.Evict(l)
+
+	// events published while replaying are not part of the replay, but
+	// are sent to the subscription channel
+	var replayed int
+	err = replay(func(ev eventtypes.Message) bool {
+		replayed++
+		e.Log("during_replay", eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont"})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 4 {
+		t.Fatalf("expected 4 replayed events, got %d", replayed)
+	}
+
+	select {
+	case ev := <-l:
+		if ev.(eventtypes.Message).Action != "during_replay" {
+			t.Fatalf("unexpected event %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the events published during the replay")
+	}
+}
+
+func TestJournalAppendDoesNotWaitForDisk(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	j, err := OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewWithJournal(j)
+	since := time.Now()
+
+	// block the writes to the disk
+	j.writeMu.Lock()
+	logged := make(chan struct{})
+	go func() {
+		for i := 0; i < 4; i++ {
+			e.Log(fmt.Sprintf("action_%d", i), eventtypes.ContainerEventType, eventtypes.Actor{ID: "cont"})
+		}
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the events to be published")
+	}
+
+	// the pending events are read along with the written ones
+	if out := readJournal(t, j, since, time.Time{}); len(out) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(out))
+	}
+	j.writeMu.Unlock()
+
+	// pending events are written on close
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	j, err = OpenJournal(root, JournalConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	out := readJournal(t, j, since, time.Time{})
+	if len(out) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(out))
+	}
+	if out[3].Action != "action_3" {
+		t.Fatalf("expected action_3, got %s", out[3].Action)
+	}
+}
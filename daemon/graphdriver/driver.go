@@ -139,6 +139,14 @@ type DiffGetterDriver interface {
 	DiffGetter(id string) (FileGetCloser, error)
 }
 
+// SharedSizer is the interface for layered file system drivers that share
+// identical file content between layers.
+type SharedSizer interface {
+	// SharedSize returns the number of bytes of file content which are
+	// stored once but used by more than one layer.
+	SharedSize() (int64, error)
+}
+
 // FileGetCloser extends the storage.FileGetter interface with a Close method
 // for cleaning up.
 type FileGetCloser interface {
@@ -0,0 +1,343 @@
+// +build linux
+
+package overlay2
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// When the "overlay2.dedup" option is set, the regular files of the layers
+// created by ApplyDiff are shared with the files of identical content in the
+// other layers. The first file seen for a content is hard linked under the
+// "dedup" directory at the root, named after its key, and the next ones are
+// replaced by a hard link to it, or by a reflink of it when the backing
+// filesystem supports cloning extents. Hard links share the inode, so the key
+// of a file also contains its mode, owner and modification time.
+
+// Each layer lists the keys of its shared files in its "dedup" file, which
+// is used to release them when the layer is removed. An entry of the "dedup"
+// directory is removed once no layer uses it anymore. Layers only ever hold
+// their own link to the content, so removing an entry never affects them.
+
+const (
+	dedupDir  = "dedup"
+	dedupFile = "dedup"
+
+	// ficlone is the FICLONE ioctl, _IOW(0x94, 9, int)
+	ficlone = 0x40049409
+)
+
+type dedupEntry struct {
+	refs int
+	size int64
+}
+
+// dedupStore indexes the shared files by key, and counts the layers files
+// using them.
+type dedupStore struct {
+	mu      sync.Mutex
+	root    string
+	reflink bool
+	entries map[string]*dedupEntry
+}
+
+// newDedupStore creates the store of the shared files of the driver at home,
+// and counts the references of the existing layers to them.
+func newDedupStore(home string, rootUID, rootGID int) (*dedupStore, error) {
+	root := path.Join(home, dedupDir)
+	if err := idtools.MkdirAllAs(root, 0700, rootUID, rootGID); err != nil {
+		return nil, err
+	}
+	s := &dedupStore{
+		root:    root,
+		entries: make(map[string]*dedupEntry),
+	}
+	if backingFs == "xfs" || backingFs == "btrfs" {
+		s.reflink = supportsReflink(root)
+	}
+
+	files, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range files {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		s.entries[fi.Name()] = &dedupEntry{size: fi.Size()}
+	}
+
+	records, err := filepath.Glob(path.Join(home, "*", dedupFile))
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		keys, err := readDedupRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if e, ok := s.entries[key]; ok {
+				e.refs++
+			}
+		}
+	}
+
+	// Drop the entries of the layers removed while the daemon was stopped
+	for key, e := range s.entries {
+		if e.refs == 0 {
+			s.remove(key)
+		}
+	}
+	return s, nil
+}
+
+// supportsReflink checks whether the files in dir can be cloned.
+func supportsReflink(dir string) bool {
+	src, err := ioutil.TempFile(dir, "reflink-check-")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(src.Name())
+	defer src.Close()
+	if _, err := src.Write([]byte("reflink")); err != nil {
+		return false
+	}
+
+	dst, err := ioutil.TempFile(dir, "reflink-check-")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+
+	if err := unix.IoctlSetInt(int(dst.Fd()), ficlone, int(src.Fd())); err != nil {
+		logrus.Debugf("overlay2: reflinks not supported on %s: %v", backingFs, err)
+		return false
+	}
+	return true
+}
+
+// mode returns how the files are shared, for the driver status
+func (s *dedupStore) mode() string {
+	if s == nil {
+		return "false"
+	}
+	if s.reflink {
+		return "reflink"
+	}
+	return "hardlink"
+}
+
+// key returns the key of the file at p, or "" if the file can't be shared.
+func (s *dedupStore) key(p string, fi os.FileInfo) (string, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink > 1 {
+		// Keep the hard links of the layer as they are
+		return "", nil
+	}
+	// Writing the content of a file, as cloning does, drops its setuid bits
+	// and capabilities, and hard links would mix up the extended attributes,
+	// so these files are left alone.
+	if fi.Mode()&(os.ModeSetuid|os.ModeSetgid) != 0 {
+		return "", nil
+	}
+	if sz, err := unix.Llistxattr(p, nil); err == nil && sz > 0 {
+		return "", nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	dgst, err := digest.Canonical.FromReader(f)
+	if err != nil {
+		return "", err
+	}
+	if s.reflink {
+		return dgst.Hex(), nil
+	}
+	return fmt.Sprintf("%s-%o-%d-%d-%d", dgst.Hex(), st.Mode, st.Uid, st.Gid, fi.ModTime().UnixNano()), nil
+}
+
+// share replaces the file at p with the shared file for key, or makes it the
+// shared file if there is none yet.
+func (s *dedupStore) share(p, key string, fi os.FileInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shared := path.Join(s.root, key)
+	e, ok := s.entries[key]
+	if !ok {
+		if err := os.Link(p, shared); err != nil {
+			return err
+		}
+		s.entries[key] = &dedupEntry{refs: 1, size: fi.Size()}
+		return nil
+	}
+
+	var err error
+	if s.reflink {
+		err = reflinkFile(shared, p, fi)
+	} else {
+		err = linkFile(shared, p)
+	}
+	if err != nil {
+		return err
+	}
+	e.refs++
+	return nil
+}
+
+// release drops the references of a removed layer to the shared files.
+func (s *dedupStore) release(keys []string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		e, ok := s.entries[key]
+		if !ok {
+			continue
+		}
+		e.refs--
+		if e.refs <= 0 {
+			s.remove(key)
+		}
+	}
+}
+
+func (s *dedupStore) remove(key string) {
+	if err := os.Remove(path.Join(s.root, key)); err != nil && !os.IsNotExist(err) {
+		logrus.Debugf("overlay2: failed to remove shared file %s: %v", key, err)
+	}
+	delete(s.entries, key)
+}
+
+// sharedSize returns the number of bytes saved by sharing the files.
+func (s *dedupStore) sharedSize() int64 {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var size int64
+	for _, e := range s.entries {
+		if e.refs > 1 {
+			size += int64(e.refs-1) * e.size
+		}
+	}
+	return size
+}
+
+// linkFile atomically replaces dst with a hard link to src.
+func linkFile(src, dst string) error {
+	tmp := dst + ".dedup"
+	if err := os.Link(src, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// reflinkFile replaces the content of dst with a clone of the content of
+// src, and restores the times of dst.
+func reflinkFile(src, dst string, fi os.FileInfo) error {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	d, err := os.OpenFile(dst, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlSetInt(int(d.Fd()), ficlone, int(s.Fd()))
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	atime := fi.ModTime()
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		atime = time.Unix(st.Atim.Unix())
+	}
+	return os.Chtimes(dst, atime, fi.ModTime())
+}
+
+func readDedupRecord(record string) ([]string, error) {
+	content, err := ioutil.ReadFile(record)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return strings.Fields(string(content)), nil
+}
+
+// dedupLayer shares the regular files of the layer id with the other layers.
+func (d *Driver) dedupLayer(id string) error {
+	var keys []string
+	seen := make(map[string]bool)
+	err := filepath.Walk(d.getDiffPath(id), func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() || fi.Size() == 0 {
+			return nil
+		}
+		key, err := d.dedup.key(p, fi)
+		if err != nil || key == "" {
+			return err
+		}
+		if seen[key] {
+			// Sharing within the layer would add hard links to it
+			return nil
+		}
+		seen[key] = true
+		if err := d.dedup.share(p, key, fi); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+
+	// Record the files shared so far even on failure, so that they are
+	// released with the layer.
+	if len(keys) > 0 {
+		if rerr := ioutils.AtomicWriteFile(path.Join(d.dir(id), dedupFile), []byte(strings.Join(keys, "\n")), 0600); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// SharedSize returns the number of bytes of the files which are stored once
+// but used by more than one layer.
+func (d *Driver) SharedSize() (int64, error) {
+	return d.dedup.sharedSize(), nil
+}
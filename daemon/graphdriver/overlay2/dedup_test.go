@@ -0,0 +1,95 @@
+// +build linux
+
+package overlay2
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/locker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLayerFile(t *testing.T, d *Driver, id, name, content string, mtime time.Time) string {
+	p := path.Join(d.getDiffPath(id), name)
+	require.NoError(t, os.MkdirAll(path.Dir(p), 0755))
+	require.NoError(t, ioutil.WriteFile(p, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(p, mtime, mtime))
+	return p
+}
+
+func TestDedupLayers(t *testing.T) {
+	home, err := ioutil.TempDir("", "overlay2-dedup-")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	d := &Driver{home: home, locker: locker.New()}
+	d.dedup, err = newDedupStore(home, os.Getuid(), os.Getgid())
+	require.NoError(t, err)
+	if d.dedup.reflink {
+		t.Skip("test requires hard links")
+	}
+
+	mtime := time.Unix(1500000000, 0)
+	content := "shared content"
+	a := writeLayerFile(t, d, "a", "lib/libc.so", content, mtime)
+	b := writeLayerFile(t, d, "b", "usr/lib/libc.so", content, mtime)
+	c := writeLayerFile(t, d, "c", "lib/libc.so", content, mtime.Add(time.Second))
+	writeLayerFile(t, d, "b", "other", "other content", mtime)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, d.dedupLayer(id))
+	}
+
+	fa, err := os.Stat(a)
+	require.NoError(t, err)
+	fb, err := os.Stat(b)
+	require.NoError(t, err)
+	fc, err := os.Stat(c)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(fa, fb))
+	assert.False(t, os.SameFile(fa, fc), "files with different times must not be linked")
+	assert.Equal(t, mtime, fb.ModTime())
+
+	data, err := ioutil.ReadFile(b)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	size, err := d.SharedSize()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size)
+
+	// The references are counted again from the layers on restart
+	d.dedup, err = newDedupStore(home, os.Getuid(), os.Getgid())
+	require.NoError(t, err)
+	size, err = d.SharedSize()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size)
+
+	require.NoError(t, d.Remove("a"))
+	size, err = d.SharedSize()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+	data, err = ioutil.ReadFile(b)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	require.NoError(t, d.Remove("b"))
+	require.NoError(t, d.Remove("c"))
+	entries, err := ioutil.ReadDir(path.Join(home, dedupDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+}
+
+func TestParseDedupOption(t *testing.T) {
+	opts, err := parseOptions([]string{"overlay2.dedup=true"})
+	require.NoError(t, err)
+	assert.True(t, opts.dedup)
+
+	_, err = parseOptions([]string{"overlay2.dedup=maybe"})
+	assert.Error(t, err)
+}
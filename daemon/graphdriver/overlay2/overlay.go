@@ -89,6 +89,7 @@ const (
 type overlayOptions struct {
 	overrideKernelCheck bool
 	quota               quota.Quota
+	dedup               bool
 }
 
 // Driver contains information about the home directory and the list of active mounts that are created using this driver.
@@ -102,6 +103,7 @@ type Driver struct {
 	naiveDiff     graphdriver.DiffDriver
 	supportsDType bool
 	locker        *locker.Locker
+	dedup         *dedupStore
 }
 
 var (
@@ -212,7 +214,13 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 		return nil, fmt.Errorf("Storage Option overlay2.size only supported for backingFS XFS. Found %v", backingFs)
 	}
 
-	logrus.Debugf("backingFs=%s,  projectQuotaSupported=%v", backingFs, projectQuotaSupported)
+	if opts.dedup {
+		if d.dedup, err = newDedupStore(home, rootUID, rootGID); err != nil {
+			return nil, err
+		}
+	}
+
+	logrus.Debugf("backingFs=%s,  projectQuotaSupported=%v, dedup=%s", backingFs, projectQuotaSupported, d.dedup.mode())
 
 	return d, nil
 }
@@ -237,6 +245,11 @@ func parseOptions(options []string) (*overlayOptions, error) {
 				return nil, err
 			}
 			o.quota.Size = uint64(size)
+		case "overlay2.dedup":
+			o.dedup, err = strconv.ParseBool(val)
+			if err != nil {
+				return nil, err
+			}
 		default:
 			return nil, fmt.Errorf("overlay2: unknown option %s", key)
 		}
@@ -286,6 +299,7 @@ func (d *Driver) Status() [][2]string {
 		{"Backing Filesystem", backingFs},
 		{"Supports d_type", strconv.FormatBool(d.supportsDType)},
 		{"Native Overlay Diff", strconv.FormatBool(!useNaiveDiff(d.home))},
+		{"File Deduplication", d.dedup.mode()},
 	}
 }
 
@@ -508,9 +522,17 @@ func (d *Driver) Remove(id string) error {
 		}
 	}
 
+	var shared []string
+	if d.dedup != nil {
+		if shared, err = readDedupRecord(path.Join(dir, dedupFile)); err != nil {
+			logrus.Debugf("Failed to read shared files of %s: %v", id, err)
+		}
+	}
+
 	if err := system.EnsureRemoveAll(dir); err != nil && !os.IsNotExist(err) {
 		return err
 	}
+	d.dedup.release(shared)
 	return nil
 }
 
@@ -658,8 +680,20 @@ func (d *Driver) isParent(id, parent string) bool {
 // ApplyDiff applies the new layer into a root
 func (d *Driver) ApplyDiff(id string, parent string, diff io.Reader) (size int64, err error) {
 	if !d.isParent(id, parent) {
-		return d.naiveDiff.ApplyDiff(id, parent, diff)
+		size, err = d.naiveDiff.ApplyDiff(id, parent, diff)
+	} else {
+		size, err = d.applyDiff(id, diff)
+	}
+	if err == nil && d.dedup != nil {
+		// The layer is complete without sharing its files
+		if err := d.dedupLayer(id); err != nil {
+			logrus.Warnf("overlay2: failed to share the files of %s: %v", id, err)
+		}
 	}
+	return size, err
+}
+
+func (d *Driver) applyDiff(id string, diff io.Reader) (size int64, err error) {
 
 	applyDir := d.getDiffPath(id)
 
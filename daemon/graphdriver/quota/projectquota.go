@@ -54,6 +54,7 @@ import (
 	"io/ioutil"
 	"path"
 	"path/filepath"
+	"sync"
 	"unsafe"
 
 	"github.com/sirupsen/logrus"
@@ -69,6 +70,7 @@ type Quota struct {
 // who wants to apply project quotas to container dirs
 type Control struct {
 	backingFsBlockDev string
+	mu                sync.Mutex // protects nextProjectID and quotas
 	nextProjectID     uint32
 	quotas            map[string]uint32
 }
@@ -124,7 +126,7 @@ func NewControl(basePath string) (*Control, error) {
 		return nil, err
 	}
 
-	q := Control{
+	q := &Control{
 		backingFsBlockDev: backingFsBlockDev,
 		nextProjectID:     minProjectID + 1,
 		quotas:            make(map[string]uint32),
@@ -139,29 +141,30 @@ func NewControl(basePath string) (*Control, error) {
 	}
 
 	logrus.Debugf("NewControl(%s): nextProjectID = %d", basePath, q.nextProjectID)
-	return &q, nil
+	return q, nil
 }
 
 // SetQuota - assign a unique project id to directory and set the quota limits
 // for that project id
 func (q *Control) SetQuota(targetPath string, quota Quota) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
 	projectID, ok := q.quotas[targetPath]
 	if !ok {
 		projectID = q.nextProjectID
-
-		//
-		// assign project id to new container directory
-		//
-		err := setProjectID(targetPath, projectID)
-		if err != nil {
-			return err
-		}
-
 		q.quotas[targetPath] = projectID
 		q.nextProjectID++
 	}
 
+	//
+	// assign project id to the directory, which may have been
+	// re-created since the project id was allocated
+	//
+	if err := setProjectID(targetPath, projectID); err != nil {
+		return err
+	}
+
 	//
 	// set the quota limit for the container's project id
 	//
@@ -169,6 +172,15 @@ func (q *Control) SetQuota(targetPath string, quota Quota) error {
 	return setProjectQuota(q.backingFsBlockDev, projectID, quota)
 }
 
+// ClearQuota - forget the project id of a directory that was configured with
+// SetQuota, once it is removed, so that a directory created at the same path
+// gets a new project id rather than the usage left by the removed one
+func (q *Control) ClearQuota(targetPath string) {
+	q.mu.Lock()
+	delete(q.quotas, targetPath)
+	q.mu.Unlock()
+}
+
 // setProjectQuota - set the quota for project id on xfs block device
 func setProjectQuota(backingFsBlockDev string, projectID uint32, quota Quota) error {
 	var d C.fs_disk_quota_t
@@ -196,15 +208,35 @@ func setProjectQuota(backingFsBlockDev string, projectID uint32, quota Quota) er
 
 // GetQuota - get the quota limits of a directory that was configured with SetQuota
 func (q *Control) GetQuota(targetPath string, quota *Quota) error {
+	d, err := q.getDiskQuota(targetPath)
+	if err != nil {
+		return err
+	}
+	quota.Size = uint64(d.d_blk_hardlimit) * 512
+
+	return nil
+}
+
+// GetUsage - get the number of bytes used by a directory that was configured
+// with SetQuota
+func (q *Control) GetUsage(targetPath string) (uint64, error) {
+	d, err := q.getDiskQuota(targetPath)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(d.d_bcount) * 512, nil
+}
 
+// getDiskQuota - get the quota limits and usage of the project id of a
+// directory
+func (q *Control) getDiskQuota(targetPath string) (*C.fs_disk_quota_t, error) {
+	q.mu.Lock()
 	projectID, ok := q.quotas[targetPath]
+	q.mu.Unlock()
 	if !ok {
-		return fmt.Errorf("quota not found for path : %s", targetPath)
+		return nil, fmt.Errorf("quota not found for path : %s", targetPath)
 	}
 
-	//
-	// get the quota limit for the container's project id
-	//
 	var d C.fs_disk_quota_t
 
 	var cs = C.CString(q.backingFsBlockDev)
@@ -214,12 +246,11 @@ func (q *Control) GetQuota(targetPath string, quota *Quota) error {
 		uintptr(unsafe.Pointer(cs)), uintptr(C.__u32(projectID)),
 		uintptr(unsafe.Pointer(&d)), 0, 0)
 	if errno != 0 {
-		return fmt.Errorf("Failed to get quota limit for projid %d on %s: %v",
+		return nil, fmt.Errorf("Failed to get quota limit for projid %d on %s: %v",
 			projectID, q.backingFsBlockDev, errno.Error())
 	}
-	quota.Size = uint64(d.d_blk_hardlimit) * 512
 
-	return nil
+	return &d, nil
 }
 
 // getProjectID - get the project id of path on xfs
@@ -272,6 +303,8 @@ func (q *Control) findNextProjectID(home string) error {
 	if err != nil {
 		return fmt.Errorf("read directory failed : %s", home)
 	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	for _, file := range files {
 		if !file.IsDir() {
 			continue
@@ -0,0 +1,47 @@
+// +build !linux
+
+package quota
+
+import "errors"
+
+// errQuotaNotSupported is returned by NewControl on platforms without
+// project quota support.
+var errQuotaNotSupported = errors.New("project quotas are not supported on this platform")
+
+// Quota limit params - currently we only control blocks hard limit
+type Quota struct {
+	Size uint64
+}
+
+// Control - Context to be used by storage driver (e.g. overlay)
+// who wants to apply project quotas to container dirs
+type Control struct {
+}
+
+// NewControl - project quotas are not supported on this platform, so it
+// always returns an error.
+func NewControl(basePath string) (*Control, error) {
+	return nil, errQuotaNotSupported
+}
+
+// SetQuota - assign a unique project id to directory and set the quota limits
+// for that project id
+func (q *Control) SetQuota(targetPath string, quota Quota) error {
+	return errQuotaNotSupported
+}
+
+// ClearQuota - forget the project id of a directory that was configured with
+// SetQuota
+func (q *Control) ClearQuota(targetPath string) {
+}
+
+// GetQuota - get the quota limits of a directory that was configured with SetQuota
+func (q *Control) GetQuota(targetPath string, quota *Quota) error {
+	return errQuotaNotSupported
+}
+
+// GetUsage - get the number of bytes used by a directory that was configured
+// with SetQuota
+func (q *Control) GetUsage(targetPath string) (uint64, error) {
+	return 0, errQuotaNotSupported
+}
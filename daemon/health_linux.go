@@ -0,0 +1,64 @@
+// +build linux
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/docker/docker/container"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netns"
+	"golang.org/x/net/context"
+)
+
+// dialInContainer connects to the address from the network namespace of
+// the container, so that ports which are not published can be probed.
+func dialInContainer(ctx context.Context, c *container.Container, address string) (net.Conn, error) {
+	pid := c.State.GetPID()
+	if pid == 0 {
+		return nil, fmt.Errorf("container %s is not running", c.ID)
+	}
+	ns, err := netns.GetFromPath(fmt.Sprintf("/proc/%d/ns/net", pid))
+	if err != nil {
+		return nil, fmt.Errorf("could not get network namespace of container %s: %v", c.ID, err)
+	}
+	defer ns.Close()
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan dialResult, 1)
+	go func() {
+		// The socket is created in the namespace of the current thread, so
+		// keep the goroutine on this thread until it is switched back.
+		runtime.LockOSThread()
+		origin, err := netns.Get()
+		if err != nil {
+			runtime.UnlockOSThread()
+			results <- dialResult{err: err}
+			return
+		}
+		defer origin.Close()
+
+		if err := netns.Set(ns); err != nil {
+			runtime.UnlockOSThread()
+			results <- dialResult{err: fmt.Errorf("could not enter network namespace of container %s: %v", c.ID, err)}
+			return
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err := netns.Set(origin); err != nil {
+			// Leave the thread locked, so that it is terminated with the
+			// goroutine rather than reused in the wrong namespace.
+			logrus.Errorf("Failed to restore network namespace after health check of container %s: %v", c.ID, err)
+		} else {
+			runtime.UnlockOSThread()
+		}
+		results <- dialResult{conn: conn, err: err}
+	}()
+	r := <-results
+	return r.conn, r.err
+}
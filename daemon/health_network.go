@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/container"
+	"golang.org/x/net/context"
+)
+
+const (
+	// Range of HTTP status codes considered healthy by default.
+	defaultHTTPStatusMin = 200
+	defaultHTTPStatusMax = 399
+)
+
+// containerDialer opens a TCP connection to the address from within the
+// container's network namespace.
+type containerDialer func(ctx context.Context, c *container.Container, address string) (net.Conn, error)
+
+// httpProbe implements the "HTTP" probe type.
+type httpProbe struct {
+	dial containerDialer
+}
+
+// Send the configured request to the container, and report it as healthy
+// if the response has an expected status code.
+func (p *httpProbe) run(ctx context.Context, d *Daemon, cntr *container.Container) (*types.HealthcheckResult, error) {
+	config := cntr.Config.Healthcheck.HTTP
+	if config == nil {
+		return nil, fmt.Errorf("healthcheck for container %s has no HTTP configuration", cntr.ID)
+	}
+
+	address := probeAddress(config.Port)
+	scheme := config.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := config.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	method := config.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequest(method, scheme+"://"+address+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range config.Headers {
+		req.Header.Set(k, v)
+	}
+	if host, ok := config.Headers["Host"]; ok {
+		req.Host = host
+	}
+	req = req.WithContext(ctx)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			// The probe context bounds the dial as well as the request.
+			Dial: func(network, addr string) (net.Conn, error) {
+				return p.dial(ctx, cntr, addr)
+			},
+			// Containers commonly serve self-signed certificates, and the
+			// request never leaves the host.
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		// Report redirects as they are, rather than probing another URL.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return probeFailure(err), nil
+	}
+	defer resp.Body.Close()
+
+	output := &limitedBuffer{}
+	fmt.Fprintf(output, "%s %s: %s\n", method, req.URL, resp.Status)
+	io.Copy(output, io.LimitReader(resp.Body, maxOutputLen))
+
+	min, max := httpStatusRange(config)
+	exitCode := exitStatusHealthy
+	if resp.StatusCode < min || resp.StatusCode > max {
+		exitCode = exitStatusUnhealthy
+	}
+	return &types.HealthcheckResult{
+		End:      time.Now(),
+		ExitCode: exitCode,
+		Output:   output.String(),
+	}, nil
+}
+
+// tcpProbe implements the "TCP" probe type.
+type tcpProbe struct {
+	dial containerDialer
+}
+
+// Connect to the configured port of the container, and report it as healthy
+// if the connection is accepted.
+func (p *tcpProbe) run(ctx context.Context, d *Daemon, cntr *container.Container) (*types.HealthcheckResult, error) {
+	config := cntr.Config.Healthcheck.TCP
+	if config == nil {
+		return nil, fmt.Errorf("healthcheck for container %s has no TCP configuration", cntr.ID)
+	}
+
+	address := probeAddress(config.Port)
+	conn, err := p.dial(ctx, cntr, address)
+	if err != nil {
+		return probeFailure(err), nil
+	}
+	conn.Close()
+	return &types.HealthcheckResult{
+		End:      time.Now(),
+		ExitCode: exitStatusHealthy,
+		Output:   "connected to " + address,
+	}, nil
+}
+
+// probeAddress returns the address network probes connect to. Probes run in
+// the container's network namespace, so the loopback interface is the
+// container's own.
+func probeAddress(port int) string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
+
+// probeFailure returns the result of a probe which could not reach the
+// container. Unlike errors returned by a probe, this is the container's
+// fault and not the daemon's.
+func probeFailure(err error) *types.HealthcheckResult {
+	return &types.HealthcheckResult{
+		End:      time.Now(),
+		ExitCode: exitStatusUnhealthy,
+		Output:   err.Error(),
+	}
+}
+
+// httpStatusRange returns the range of status codes considered healthy by
+// the HTTP healthcheck, applying the defaults.
+func httpStatusRange(config *containertypes.HealthHTTPConfig) (int, int) {
+	min, max := config.StatusMin, config.StatusMax
+	if min == 0 {
+		min = defaultHTTPStatusMin
+	}
+	if max == 0 {
+		max = defaultHTTPStatusMax
+		if max < min {
+			max = min
+		}
+	}
+	return min, max
+}
+
+// validateNetworkHealthcheck checks the configuration of "HTTP" and "TCP"
+// healthchecks.
+func validateNetworkHealthcheck(config *containertypes.HealthConfig) error {
+	if len(config.Test) == 0 {
+		return nil
+	}
+	switch config.Test[0] {
+	case "HTTP":
+		if config.HTTP == nil {
+			return fmt.Errorf("HTTP healthcheck requires an HTTP configuration")
+		}
+		if err := validateProbePort(config.HTTP.Port); err != nil {
+			return err
+		}
+		switch config.HTTP.Scheme {
+		case "", "http", "https":
+		default:
+			return fmt.Errorf("invalid scheme in HTTP healthcheck: %s", config.HTTP.Scheme)
+		}
+		min, max := httpStatusRange(config.HTTP)
+		if min < 100 || max > 599 || min > max {
+			return fmt.Errorf("invalid status range in HTTP healthcheck: %d-%d", min, max)
+		}
+	case "TCP":
+		if config.TCP == nil {
+			return fmt.Errorf("TCP healthcheck requires a TCP configuration")
+		}
+		return validateProbePort(config.TCP.Port)
+	}
+	return nil
+}
+
+func validateProbePort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port in healthcheck: %d", port)
+	}
+	return nil
+}
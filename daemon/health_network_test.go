@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/container"
+	"golang.org/x/net/context"
+)
+
+// dialTo returns a containerDialer which always connects to address,
+// instead of entering the container's network namespace.
+func dialTo(address string) containerDialer {
+	return func(ctx context.Context, c *container.Container, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", address)
+	}
+}
+
+func TestHTTPProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Probe") != "docker" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+
+	c := &container.Container{
+		ID: "container_id",
+		Config: &containertypes.Config{
+			Healthcheck: &containertypes.HealthConfig{
+				Test: []string{"HTTP"},
+				HTTP: &containertypes.HealthHTTPConfig{
+					Port:    8080,
+					Path:    "/up",
+					Headers: map[string]string{"X-Probe": "docker"},
+				},
+			},
+		},
+	}
+	probe := &httpProbe{dial: dialTo(srv.Listener.Addr().String())}
+
+	result, err := probe.run(context.Background(), nil, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusHealthy {
+		t.Fatalf("expected healthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+	if !strings.Contains(result.Output, "GET http://127.0.0.1:8080/up: 200 OK") || !strings.HasSuffix(result.Output, "body") {
+		t.Fatalf("unexpected output: %q", result.Output)
+	}
+
+	c.Config.Healthcheck.HTTP.Path = "/down"
+	result, err = probe.run(context.Background(), nil, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusUnhealthy {
+		t.Fatalf("expected unhealthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+
+	c.Config.Healthcheck.HTTP.StatusMin = 500
+	c.Config.Healthcheck.HTTP.StatusMax = 503
+	result, err = probe.run(context.Background(), nil, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusHealthy {
+		t.Fatalf("expected healthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+
+	c := &container.Container{
+		ID: "container_id",
+		Config: &containertypes.Config{
+			Healthcheck: &containertypes.HealthConfig{
+				Test: []string{"TCP"},
+				TCP:  &containertypes.HealthTCPConfig{Port: 5432},
+			},
+		},
+	}
+	probe := &tcpProbe{dial: dialTo(address)}
+
+	result, err := probe.run(context.Background(), nil, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusHealthy {
+		t.Fatalf("expected healthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+
+	l.Close()
+	result, err = probe.run(context.Background(), nil, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != exitStatusUnhealthy {
+		t.Fatalf("expected unhealthy result, got %d: %s", result.ExitCode, result.Output)
+	}
+}
+
+func TestValidateNetworkHealthcheck(t *testing.T) {
+	invalid := []*containertypes.HealthConfig{
+		{Test: []string{"HTTP"}},
+		{Test: []string{"HTTP"}, HTTP: &containertypes.HealthHTTPConfig{}},
+		{Test: []string{"HTTP"}, HTTP: &containertypes.HealthHTTPConfig{Port: 80, Scheme: "ftp"}},
+		{Test: []string{"HTTP"}, HTTP: &containertypes.HealthHTTPConfig{Port: 80, StatusMin: 300, StatusMax: 200}},
+		{Test: []string{"TCP"}},
+		{Test: []string{"TCP"}, TCP: &containertypes.HealthTCPConfig{Port: 70000}},
+	}
+	for _, config := range invalid {
+		if err := validateNetworkHealthcheck(config); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+
+	valid := []*containertypes.HealthConfig{
+		{Test: []string{"CMD", "true"}},
+		{Test: []string{"HTTP"}, HTTP: &containertypes.HealthHTTPConfig{Port: 80}},
+		{Test: []string{"HTTP"}, HTTP: &containertypes.HealthHTTPConfig{Port: 443, Scheme: "https", StatusMin: 204}},
+		{Test: []string{"TCP"}, TCP: &containertypes.HealthTCPConfig{Port: 5432}},
+	}
+	for _, config := range valid {
+		if err := validateNetworkHealthcheck(config); err != nil {
+			t.Errorf("unexpected error for %+v: %v", config, err)
+		}
+	}
+}
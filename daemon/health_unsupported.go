@@ -0,0 +1,30 @@
+// +build !linux
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/docker/docker/container"
+	"golang.org/x/net/context"
+)
+
+// dialInContainer connects to the port of the address on the first IP
+// address of the container, as network namespaces cannot be entered on
+// this platform.
+func dialInContainer(ctx context.Context, c *container.Container, address string) (net.Conn, error) {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if c.NetworkSettings != nil {
+		for _, ep := range c.NetworkSettings.Networks {
+			if ep.EndpointSettings != nil && ep.IPAddress != "" {
+				var d net.Dialer
+				return d.DialContext(ctx, "tcp", net.JoinHostPort(ep.IPAddress, port))
+			}
+		}
+	}
+	return nil, fmt.Errorf("container %s has no IP address to probe", c.ID)
+}
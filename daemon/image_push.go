@@ -9,13 +9,14 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/distribution"
 	progressutils "github.com/docker/docker/distribution/utils"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/system"
 	"golang.org/x/net/context"
 )
 
 // PushImage initiates a push operation on the repository named localName.
-func (daemon *Daemon) PushImage(ctx context.Context, image, tag string, metaHeaders map[string][]string, authConfig *types.AuthConfig, outStream io.Writer) error {
+func (daemon *Daemon) PushImage(ctx context.Context, image, tag string, metaHeaders map[string][]string, authConfig *types.AuthConfig, compression *archive.CompressionOptions, outStream io.Writer) error {
 	ref, err := reference.ParseNormalizedNamed(image)
 	if err != nil {
 		return err
@@ -58,10 +59,11 @@ func (daemon *Daemon) PushImage(ctx context.Context, image, tag string, metaHead
 			ImageStore:       distribution.NewImageConfigStoreFromStore(daemon.stores[platform].imageStore),
 			ReferenceStore:   daemon.referenceStore,
 		},
-		ConfigMediaType: schema2.MediaTypeImageConfig,
-		LayerStore:      distribution.NewLayerProviderFromStore(daemon.stores[platform].layerStore),
-		TrustKey:        daemon.trustKey,
-		UploadManager:   daemon.uploadManager,
+		ConfigMediaType:  schema2.MediaTypeImageConfig,
+		LayerStore:       distribution.NewLayerProviderFromStore(daemon.stores[platform].layerStore),
+		TrustKey:         daemon.trustKey,
+		UploadManager:    daemon.uploadManager,
+		LayerCompression: compression,
 	}
 
 	err = distribution.Push(ctx, ref, imagePushConfig)
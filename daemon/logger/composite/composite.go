@@ -0,0 +1,241 @@
+// Package composite provides a log driver that fans container logs out to
+// several other log drivers at once.
+//
+// The drivers are listed with the "drivers" option. Options for a single
+// driver are passed by prefixing them with the driver name, for example
+// "json-file.max-size=10m" or "splunk.splunk-url=https://...". The first
+// listed driver is the primary one and keeps its configured logging mode;
+// every other driver defaults to the non-blocking mode so that a slow or
+// failing sink cannot stall the container. Logs are read back from the
+// first driver that supports reading.
+package composite
+
+import (
+	"fmt"
+	"strings"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/daemon/logger"
+	units "github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Name is the name of the composite log driver.
+const Name = "composite"
+
+const driversKey = "drivers"
+
+type compositeLogger struct {
+	info  logger.Info
+	sinks []logger.Logger
+}
+
+type compositeWithReader struct {
+	*compositeLogger
+	reader logger.LogReader
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(Name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(Name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// New creates a composite logger which forwards every message to each of
+// the log drivers configured in the "drivers" option.
+func New(info logger.Info) (logger.Logger, error) {
+	drivers, err := parseDrivers(info.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &compositeLogger{info: info}
+	var reader logger.LogReader
+	for i, name := range drivers {
+		sink, err := newSink(info, name, sinkConfig(info.Config, drivers, i))
+		if err != nil {
+			l.Close()
+			return nil, errors.Wrapf(err, "failed to initialize %s log driver", name)
+		}
+		l.sinks = append(l.sinks, sink)
+		if r, ok := sink.(logger.LogReader); ok && reader == nil {
+			reader = r
+		}
+	}
+
+	if reader != nil {
+		return &compositeWithReader{compositeLogger: l, reader: reader}, nil
+	}
+	return l, nil
+}
+
+func newSink(info logger.Info, name string, cfg map[string]string) (logger.Logger, error) {
+	initDriver, err := logger.GetLogDriver(name)
+	if err != nil {
+		return nil, err
+	}
+	info.Config = cfg
+	l, err := initDriver(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if containertypes.LogMode(cfg["mode"]) == containertypes.LogModeNonBlock {
+		bufferSize := int64(-1)
+		if s, exists := cfg["max-buffer-size"]; exists {
+			bufferSize, err = units.RAMInBytes(s)
+			if err != nil {
+				l.Close()
+				return nil, err
+			}
+		}
+		l = logger.NewRingLogger(l, info, bufferSize)
+	}
+	return l, nil
+}
+
+// Log sends a copy of the message to every sink. An error from one sink
+// does not prevent the message from reaching the others; the first error
+// is returned.
+func (l *compositeLogger) Log(msg *logger.Message) error {
+	var firstErr error
+	last := len(l.sinks) - 1
+	for i, sink := range l.sinks {
+		m := msg
+		if i != last {
+			m = copyMessage(msg)
+		}
+		if err := sink.Log(m); err != nil {
+			logrus.WithField("driver", sink.Name()).WithField("container", l.info.ContainerID).Debugf("Error writing log message: %v", err)
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "%s log driver", sink.Name())
+			}
+		}
+	}
+	return firstErr
+}
+
+// Name returns the name of the log driver.
+func (l *compositeLogger) Name() string {
+	return Name
+}
+
+// Close closes all sinks and returns the first error encountered.
+func (l *compositeLogger) Close() error {
+	var firstErr error
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// LogPath returns the path of the json-file log of the container, if one
+// of the sinks writes it.
+func (l *compositeLogger) LogPath() string {
+	return l.info.LogPath
+}
+
+// ReadLogs reads the logs from the first sink supporting reads.
+func (l *compositeWithReader) ReadLogs(config logger.ReadConfig) *logger.LogWatcher {
+	return l.reader.ReadLogs(config)
+}
+
+// copyMessage returns a copy of msg taken from the message pool, as every
+// sink takes ownership of the message it is given.
+func copyMessage(msg *logger.Message) *logger.Message {
+	m := logger.NewMessage()
+	m.Line = append(m.Line, msg.Line...)
+	m.Source = msg.Source
+	m.Timestamp = msg.Timestamp
+	m.Partial = msg.Partial
+	m.Err = msg.Err
+	if msg.Attrs != nil {
+		m.Attrs = append(m.Attrs[:0:0], msg.Attrs...)
+	}
+	return m
+}
+
+// Drivers returns the names of the log drivers configured in cfg.
+func Drivers(cfg map[string]string) []string {
+	drivers, _ := parseDrivers(cfg)
+	return drivers
+}
+
+func parseDrivers(cfg map[string]string) ([]string, error) {
+	s := strings.TrimSpace(cfg[driversKey])
+	if s == "" {
+		return nil, fmt.Errorf("the %s log driver requires the '%s' option", Name, driversKey)
+	}
+
+	var drivers []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+			return nil, fmt.Errorf("invalid '%s' option for %s log driver: %q", driversKey, Name, s)
+		case Name, "none":
+			return nil, fmt.Errorf("the %s log driver cannot send logs to the %s log driver", Name, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("log driver %s is listed more than once in '%s'", name, driversKey)
+		}
+		seen[name] = true
+		drivers = append(drivers, name)
+	}
+	return drivers, nil
+}
+
+// sinkConfig returns the options of the i-th driver, with the driver name
+// prefix stripped. Drivers other than the primary one default to the
+// non-blocking mode.
+func sinkConfig(cfg map[string]string, drivers []string, i int) map[string]string {
+	sub := make(map[string]string)
+	for k, v := range cfg {
+		if d := driverFor(k, drivers); d == drivers[i] {
+			sub[strings.TrimPrefix(k, d+".")] = v
+		}
+	}
+	if _, ok := sub["mode"]; !ok && i > 0 {
+		sub["mode"] = string(containertypes.LogModeNonBlock)
+	}
+	return sub
+}
+
+// driverFor returns the driver an option key is prefixed with, preferring
+// the longest match so that driver names containing dots are handled.
+func driverFor(key string, drivers []string) string {
+	var match string
+	for _, d := range drivers {
+		if strings.HasPrefix(key, d+".") && len(d) > len(match) {
+			match = d
+		}
+	}
+	return match
+}
+
+// ValidateLogOpt checks the options of the composite log driver and of each
+// of the drivers it sends logs to.
+func ValidateLogOpt(cfg map[string]string) error {
+	drivers, err := parseDrivers(cfg)
+	if err != nil {
+		return err
+	}
+	for key := range cfg {
+		if key != driversKey && driverFor(key, drivers) == "" {
+			return fmt.Errorf("unknown log opt '%s' for %s log driver", key, Name)
+		}
+	}
+	for i, name := range drivers {
+		if err := logger.ValidateLogOpts(name, sinkConfig(cfg, drivers, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package composite
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+)
+
+type testLogger struct {
+	name   string
+	mu     sync.Mutex
+	lines  []string
+	block  chan struct{}
+	closed bool
+}
+
+func (l *testLogger) Log(msg *logger.Message) error {
+	if l.block != nil {
+		<-l.block
+	}
+	l.mu.Lock()
+	l.lines = append(l.lines, string(msg.Line))
+	l.mu.Unlock()
+	logger.PutMessage(msg)
+	return nil
+}
+
+func (l *testLogger) Name() string { return l.name }
+
+func (l *testLogger) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *testLogger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+type testReader struct {
+	*testLogger
+}
+
+func (l *testReader) ReadLogs(logger.ReadConfig) *logger.LogWatcher {
+	return logger.NewLogWatcher()
+}
+
+var (
+	testLoggersMu sync.Mutex
+	testLoggers   = map[string]*testLogger{}
+)
+
+func registerTestDriver(t *testing.T, name string, reader bool, block chan struct{}) {
+	creator := func(info logger.Info) (logger.Logger, error) {
+		l := &testLogger{name: name, block: block}
+		testLoggersMu.Lock()
+		testLoggers[info.ContainerID+"/"+name] = l
+		testLoggersMu.Unlock()
+		if reader {
+			return &testReader{l}, nil
+		}
+		return l, nil
+	}
+	if err := logger.RegisterLogDriver(name, creator); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(name, func(cfg map[string]string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getTestLogger(id, name string) *testLogger {
+	testLoggersMu.Lock()
+	defer testLoggersMu.Unlock()
+	return testLoggers[id+"/"+name]
+}
+
+func TestCompositeLogger(t *testing.T) {
+	registerTestDriver(t, "test-primary", false, nil)
+	registerTestDriver(t, "test-reader", true, nil)
+
+	l, err := New(logger.Info{
+		ContainerID: "c1",
+		Config:      map[string]string{"drivers": "test-primary,test-reader"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.(logger.LogReader); !ok {
+		t.Fatal("expected composite logger to support reading")
+	}
+
+	for _, line := range []string{"a", "b"} {
+		if err := l.Log(&logger.Message{Line: []byte(line), Source: "stdout", Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"test-primary", "test-reader"} {
+		sink := getTestLogger("c1", name)
+		if lines := sink.Lines(); len(lines) != 2 || lines[0] != "a" || lines[1] != "b" {
+			t.Fatalf("unexpected lines for %s: %v", name, lines)
+		}
+		if !sink.closed {
+			t.Fatalf("expected %s to be closed", name)
+		}
+	}
+}
+
+func TestCompositeLoggerSlowSink(t *testing.T) {
+	block := make(chan struct{})
+	registerTestDriver(t, "test-fast", false, nil)
+	registerTestDriver(t, "test-slow", false, block)
+
+	l, err := New(logger.Info{
+		ContainerID: "c2",
+		Config:      map[string]string{"drivers": "test-fast,test-slow"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.(logger.LogReader); ok {
+		t.Fatal("expected composite logger without a reader sink to not support reading")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			l.Log(&logger.Message{Line: []byte("x"), Source: "stdout"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked on the slow sink")
+	}
+
+	if lines := getTestLogger("c2", "test-fast").Lines(); len(lines) != 10 {
+		t.Fatalf("expected 10 lines on the fast sink, got %d", len(lines))
+	}
+	close(block)
+	l.Close()
+}
+
+func TestSinkConfig(t *testing.T) {
+	cfg := map[string]string{
+		"drivers":             "json-file,splunk",
+		"json-file.max-size":  "10m",
+		"splunk.splunk-url":   "https://splunk.example.com",
+		"splunk.mode":         "blocking",
+		"json-file.max-file":  "3",
+		"splunk.splunk-token": "t",
+	}
+	drivers := Drivers(cfg)
+	if len(drivers) != 2 || drivers[0] != "json-file" || drivers[1] != "splunk" {
+		t.Fatalf("unexpected drivers: %v", drivers)
+	}
+
+	jcfg := sinkConfig(cfg, drivers, 0)
+	if len(jcfg) != 2 || jcfg["max-size"] != "10m" || jcfg["max-file"] != "3" {
+		t.Fatalf("unexpected json-file config: %v", jcfg)
+	}
+	scfg := sinkConfig(cfg, drivers, 1)
+	if len(scfg) != 3 || scfg["mode"] != "blocking" || scfg["splunk-url"] != "https://splunk.example.com" {
+		t.Fatalf("unexpected splunk config: %v", scfg)
+	}
+
+	delete(cfg, "splunk.mode")
+	if scfg := sinkConfig(cfg, drivers, 1); scfg["mode"] != "non-blocking" {
+		t.Fatalf("expected secondary sink to default to non-blocking, got %q", scfg["mode"])
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	registerTestDriver(t, "test-valid", false, nil)
+
+	for _, cfg := range []map[string]string{
+		{},
+		{"drivers": ""},
+		{"drivers": "test-valid,"},
+		{"drivers": "test-valid,test-valid"},
+		{"drivers": "test-valid,composite"},
+		{"drivers": "test-valid,none"},
+		{"drivers": "test-valid", "max-size": "10m"},
+		{"drivers": "test-valid,not-registered"},
+		{"drivers": "test-valid", "test-valid.mode": "bogus"},
+	} {
+		if err := ValidateLogOpt(cfg); err == nil {
+			t.Fatalf("expected error for %v", cfg)
+		}
+	}
+
+	if err := ValidateLogOpt(map[string]string{"drivers": "test-valid", "test-valid.mode": "non-blocking", "test-valid.max-buffer-size": "1m"}); err != nil {
+		t.Fatal(err)
+	}
+}
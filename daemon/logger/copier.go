@@ -20,6 +20,7 @@ type Copier struct {
 	// srcs is map of name -> reader pairs, for example "stdout", "stderr"
 	srcs      map[string]io.Reader
 	dst       Logger
+	multiline *MultilineConfig
 	copyJobs  sync.WaitGroup
 	closeOnce sync.Once
 	closed    chan struct{}
@@ -34,6 +35,14 @@ func NewCopier(srcs map[string]io.Reader, dst Logger) *Copier {
 	}
 }
 
+// NewMultilineCopier creates a new Copier which aggregates consecutive lines
+// of each source into a single message according to config.
+func NewMultilineCopier(srcs map[string]io.Reader, dst Logger, config *MultilineConfig) *Copier {
+	c := NewCopier(srcs, dst)
+	c.multiline = config
+	return c
+}
+
 // Run starts logs copying
 func (c *Copier) Run() {
 	for src, w := range c.srcs {
@@ -48,6 +57,14 @@ func (c *Copier) copySrc(name string, src io.Reader) {
 	n := 0
 	eof := false
 
+	var ml *multilineBuffer
+	if c.multiline != nil {
+		ml = newMultilineBuffer(name, c.dst, c.multiline)
+		defer func() {
+			ml.close(eof)
+		}()
+	}
+
 	for {
 		select {
 		case <-c.closed:
@@ -81,6 +98,10 @@ func (c *Copier) copySrc(name string, src io.Reader) {
 				case <-c.closed:
 					return
 				default:
+					if ml != nil {
+						ml.add(buf[p:p+q], time.Now().UTC())
+						break
+					}
 					msg := NewMessage()
 					msg.Source = name
 					msg.Timestamp = time.Now().UTC()
@@ -96,7 +117,17 @@ func (c *Copier) copySrc(name string, src io.Reader) {
 			// has no newlines, log whatever we haven't logged yet,
 			// noting that it's a partial log line.
 			if eof || (p == 0 && n == len(buf)) {
+				if p < n && eof && ml != nil {
+					// the last line of the stream is complete
+					ml.add(buf[p:n], time.Now().UTC())
+					p = n
+				}
 				if p < n {
+					// partial lines are not aggregated, but must
+					// not be logged before the lines preceding them
+					if ml != nil {
+						ml.flush()
+					}
 					msg := NewMessage()
 					msg.Source = name
 					msg.Timestamp = time.Now().UTC()
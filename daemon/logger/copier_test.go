@@ -205,6 +205,119 @@ func TestCopierSlow(t *testing.T) {
 	}
 }
 
+func TestCopierMultiline(t *testing.T) {
+	input := `2017-10-01 12:00:00 ERROR request failed
+java.lang.NullPointerException
+	at com.example.Handler.handle(Handler.java:42)
+	at com.example.Server.run(Server.java:12)
+2017-10-01 12:00:01 INFO request served
+2017-10-01 12:00:02 ERROR request failed
+	at com.example.Handler.handle(Handler.java:42)`
+
+	expected := []string{
+		"2017-10-01 12:00:00 ERROR request failed\njava.lang.NullPointerException\n\tat com.example.Handler.handle(Handler.java:42)\n\tat com.example.Server.run(Server.java:12)",
+		"2017-10-01 12:00:01 INFO request served",
+		"2017-10-01 12:00:02 ERROR request failed\n\tat com.example.Handler.handle(Handler.java:42)",
+	}
+
+	for _, cfg := range []map[string]string{
+		{"multiline-start-regex": `^\d{4}-\d{2}-\d{2}`},
+		{"multiline-pattern": `^\d{4}-\d{2}-\d{2}`},
+	} {
+		config, err := ParseMultilineConfig(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var jsonBuf bytes.Buffer
+		jsonLog := &TestLoggerJSON{Encoder: json.NewEncoder(&jsonBuf)}
+		c := NewMultilineCopier(map[string]io.Reader{"stdout": strings.NewReader(input)}, jsonLog, config)
+		c.Run()
+		c.Wait()
+
+		dec := json.NewDecoder(&jsonBuf)
+		for _, line := range expected {
+			var msg Message
+			if err := dec.Decode(&msg); err != nil {
+				t.Fatalf("%v: %v", cfg, err)
+			}
+			if string(msg.Line) != line {
+				t.Fatalf("%v: expected %q, got %q", cfg, line, msg.Line)
+			}
+		}
+		if dec.More() {
+			t.Fatalf("%v: unexpected messages", cfg)
+		}
+	}
+}
+
+func TestCopierMultilineFlushTimeout(t *testing.T) {
+	config, err := ParseMultilineConfig(map[string]string{
+		"multiline-pattern":       `^panic`,
+		"multiline-flush-timeout": "50ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, w := io.Pipe()
+	defer w.Close()
+	msgs := make(chan *Message, 10)
+	c := NewMultilineCopier(map[string]io.Reader{"stderr": r}, &mockLogger{c: msgs}, config)
+	c.Run()
+	defer c.Close()
+
+	if _, err := w.Write([]byte("panic: oops\n\tmain.go:10\n")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-msgs:
+		if string(msg.Line) != "panic: oops\n\tmain.go:10" {
+			t.Fatalf("unexpected message: %q", msg.Line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected message to be flushed after the timeout")
+	}
+}
+
+func TestMultilineBufferClosed(t *testing.T) {
+	config, err := ParseMultilineConfig(map[string]string{"multiline-pattern": `^\S`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make(chan *Message, 10)
+	b := newMultilineBuffer("stdout", &mockLogger{c: msgs}, config)
+	b.add([]byte("panic: oops"), time.Now())
+	b.close(false)
+
+	// a flush timer firing after close logs nothing
+	b.flush()
+	select {
+	case msg := <-msgs:
+		t.Fatalf("unexpected message after close: %q", msg.Line)
+	default:
+	}
+}
+
+func TestParseMultilineConfig(t *testing.T) {
+	for _, cfg := range []map[string]string{
+		{"multiline-start-regex": "^a", "multiline-pattern": "^b"},
+		{"multiline-start-regex": "("},
+		{"multiline-pattern": "^b", "multiline-flush-timeout": "soon"},
+		{"multiline-pattern": "^b", "multiline-flush-timeout": "-1s"},
+		{"multiline-flush-timeout": "1s"},
+	} {
+		if _, err := ParseMultilineConfig(cfg); err == nil {
+			t.Fatalf("expected %v to cause error", cfg)
+		}
+	}
+
+	config, err := ParseMultilineConfig(map[string]string{"max-size": "10m"})
+	if err != nil || config != nil {
+		t.Fatalf("expected no multiline config, got %v, %v", config, err)
+	}
+}
+
 type BenchmarkLoggerDummy struct {
 }
 
@@ -117,8 +117,11 @@ func GetLogDriver(name string) (Creator, error) {
 }
 
 var builtInLogOpts = map[string]bool{
-	"mode":            true,
-	"max-buffer-size": true,
+	"mode":                   true,
+	"max-buffer-size":        true,
+	multilineStartKey:        true,
+	multilinePatternKey:      true,
+	multilineFlushTimeoutKey: true,
 }
 
 // ValidateLogOpts checks the options for the given log driver. The
@@ -143,6 +146,10 @@ func ValidateLogOpts(name string, cfg map[string]string) error {
 		}
 	}
 
+	if _, err := ParseMultilineConfig(cfg); err != nil {
+		return err
+	}
+
 	if !factory.driverRegistered(name) {
 		return fmt.Errorf("logger: no log driver named '%s' is registered", name)
 	}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// ReadFilter selects the messages returned by a LogReader. A message is
+// selected if its line matches any of the patterns, it was written to any of
+// the streams, and for every attribute key, it has any of the values.
+// Empty criteria select all the messages.
+type ReadFilter struct {
+	Patterns []string            `json:",omitempty"`
+	Streams  []string            `json:",omitempty"`
+	Attrs    map[string][]string `json:",omitempty"`
+
+	regexps []*regexp.Regexp
+}
+
+// NewReadFilter returns a filter selecting messages by their line, stream
+// and attributes. An error is returned if a pattern is not a valid regular
+// expression.
+func NewReadFilter(patterns, streams []string, attrs map[string][]string) (*ReadFilter, error) {
+	f := &ReadFilter{
+		Patterns: patterns,
+		Streams:  streams,
+		Attrs:    attrs,
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid log filter pattern %q", p)
+		}
+		f.regexps = append(f.regexps, re)
+	}
+	return f, nil
+}
+
+// Match returns true if the message is selected by the filter. A nil filter
+// selects all the messages.
+func (f *ReadFilter) Match(msg *Message) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Streams) > 0 && !contains(f.Streams, msg.Source) {
+		return false
+	}
+	for key, values := range f.Attrs {
+		var found bool
+		for _, attr := range msg.Attrs {
+			if attr.Key == key && contains(values, attr.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.regexps) == 0 {
+		return true
+	}
+	for _, re := range f.regexps {
+		if re.Match(msg.Line) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
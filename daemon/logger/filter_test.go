@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/backend"
+)
+
+func TestReadFilterMatch(t *testing.T) {
+	filter, err := NewReadFilter(
+		[]string{"^error", "panic"},
+		[]string{"stderr"},
+		map[string][]string{"env": {"prod", "staging"}, "app": {"web"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := []backend.LogAttr{{Key: "env", Value: "prod"}, {Key: "app", Value: "web"}}
+	cases := []struct {
+		msg   Message
+		match bool
+	}{
+		{Message{Line: []byte("error: failed"), Source: "stderr", Attrs: attrs}, true},
+		{Message{Line: []byte("goroutine panic"), Source: "stderr", Attrs: attrs}, true},
+		{Message{Line: []byte("no error"), Source: "stderr", Attrs: attrs}, false},
+		{Message{Line: []byte("error: failed"), Source: "stdout", Attrs: attrs}, false},
+		{Message{Line: []byte("error: failed"), Source: "stderr", Attrs: attrs[:1]}, false},
+		{Message{Line: []byte("error: failed"), Source: "stderr", Attrs: []backend.LogAttr{{Key: "env", Value: "staging"}, {Key: "app", Value: "web"}}}, true},
+		{Message{Line: []byte("error: failed"), Source: "stderr", Attrs: []backend.LogAttr{{Key: "env", Value: "dev"}, {Key: "app", Value: "web"}}}, false},
+	}
+	for _, c := range cases {
+		if match := filter.Match(&c.msg); match != c.match {
+			t.Fatalf("expected match to be %v for %q on %s with %v", c.match, c.msg.Line, c.msg.Source, c.msg.Attrs)
+		}
+	}
+
+	var nilFilter *ReadFilter
+	if !nilFilter.Match(&Message{Line: []byte("anything")}) {
+		t.Fatal("expected nil filter to match all messages")
+	}
+
+	if _, err := NewReadFilter([]string{"("}, nil, nil); err == nil {
+		t.Fatal("expected invalid pattern to cause error")
+	}
+}
@@ -220,13 +220,16 @@ drain:
 				kv := strings.SplitN(C.GoStringN(data, C.int(length)), "=", 2)
 				attrs = append(attrs, backend.LogAttr{Key: kv[0], Value: kv[1]})
 			}
-			// Send the log message.
-			logWatcher.Msg <- &logger.Message{
+			m := &logger.Message{
 				Line:      line,
 				Source:    source,
 				Timestamp: timestamp.In(time.UTC),
 				Attrs:     attrs,
 			}
+			// Send the log message, if it is selected by the filter.
+			if config.Filter.Match(m) {
+				logWatcher.Msg <- m
+			}
 		}
 		// If we're at the end of the journal, we're done (for now).
 		if C.sd_journal_next(j) <= 0 {
@@ -338,6 +341,21 @@ func (s *journald) readLogs(logWatcher *logger.LogWatcher, config logger.ReadCon
 		logWatcher.Err <- fmt.Errorf("error setting journal match")
 		return
 	}
+	// Let the library select the stream too, by the priority we assigned
+	// to its messages.
+	if config.Filter != nil && len(config.Filter.Streams) == 1 {
+		priority := journal.PriInfo
+		if config.Filter.Streams[0] == "stderr" {
+			priority = journal.PriErr
+		}
+		cpriority := C.CString(fmt.Sprintf("PRIORITY=%d", priority))
+		defer C.free(unsafe.Pointer(cpriority))
+		rc = C.sd_journal_add_match(j, unsafe.Pointer(cpriority), C.strlen(cpriority))
+		if rc != 0 {
+			logWatcher.Err <- fmt.Errorf("error setting journal match")
+			return
+		}
+	}
 	// If we have a cutoff time, convert it to Unix time once.
 	if !config.Since.IsZero() {
 		nano := config.Since.UnixNano()
@@ -7,9 +7,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/daemon/logger/jsonfilelog/jsonlog"
@@ -65,7 +65,19 @@ func New(info logger.Info) (logger.Logger, error) {
 		}
 	}
 
-	writer, err := loggerutils.NewRotateFileWriter(info.LogPath, capval, maxFiles)
+	var compress bool
+	if compressString, ok := info.Config["compress"]; ok {
+		var err error
+		compress, err = strconv.ParseBool(compressString)
+		if err != nil {
+			return nil, err
+		}
+		if compress && (maxFiles == 1 || capval == -1) {
+			return nil, fmt.Errorf("compress cannot be true when max-file is less than 2 or max-size is not set")
+		}
+	}
+
+	writer, err := loggerutils.NewRotateFileWriter(info.LogPath, capval, maxFiles, compress, getTimestamp)
 	if err != nil {
 		return nil, err
 	}
@@ -100,13 +112,14 @@ func (l *JSONFileLogger) Log(msg *logger.Message) error {
 	return err
 }
 
-func writeMessageBuf(w io.Writer, m *logger.Message, extra json.RawMessage, buf *bytes.Buffer) error {
+func writeMessageBuf(w *loggerutils.RotateFileWriter, m *logger.Message, extra json.RawMessage, buf *bytes.Buffer) error {
 	if err := marshalMessage(m, extra, buf); err != nil {
 		logger.PutMessage(m)
 		return err
 	}
+	timestamp := m.Timestamp
 	logger.PutMessage(m)
-	_, err := w.Write(buf.Bytes())
+	_, err := w.WriteLog(buf.Bytes(), timestamp)
 	return errors.Wrap(err, "error writing log entry")
 }
 
@@ -128,12 +141,22 @@ func marshalMessage(msg *logger.Message, extra json.RawMessage, buf *bytes.Buffe
 	return errors.Wrap(err, "error finalizing log buffer")
 }
 
-// ValidateLogOpt looks for json specific log options max-file & max-size.
+// getTimestamp returns the time of a message written to the log file.
+func getTimestamp(line []byte) (time.Time, error) {
+	var l jsonlog.JSONLog
+	if err := json.Unmarshal(line, &l); err != nil {
+		return time.Time{}, err
+	}
+	return l.Created, nil
+}
+
+// ValidateLogOpt looks for json specific log options max-file, max-size & compress.
 func ValidateLogOpt(cfg map[string]string) error {
 	for key := range cfg {
 		switch key {
 		case "max-file":
 		case "max-size":
+		case "compress":
 		case "labels":
 		case "env":
 		case "env-regex":
@@ -141,6 +164,16 @@ func ValidateLogOpt(cfg map[string]string) error {
 			return fmt.Errorf("unknown log opt '%s' for json-file log driver", key)
 		}
 	}
+	if compressString, ok := cfg["compress"]; ok {
+		compress, err := strconv.ParseBool(compressString)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' for log opt 'compress' of json-file log driver", compressString)
+		}
+		maxFiles, _ := strconv.Atoi(cfg["max-file"])
+		if compress && (maxFiles < 2 || cfg["max-size"] == "") {
+			return fmt.Errorf("compress cannot be true when max-file is less than 2 or max-size is not set")
+		}
+	}
 	return nil
 }
 
@@ -205,3 +205,44 @@ func TestJSONFileLoggerWithLabelsEnv(t *testing.T) {
 		t.Fatalf("Wrong log attrs: %q, expected %q", extra, expected)
 	}
 }
+
+func TestValidateLogOptCompress(t *testing.T) {
+	cases := []struct {
+		config map[string]string
+		err    string
+	}{
+		{config: map[string]string{"compress": "true", "max-size": "1k", "max-file": "2"}},
+		{config: map[string]string{"compress": "false"}},
+		{config: map[string]string{"compress": "0", "max-file": "1"}},
+		{
+			config: map[string]string{"compress": "yes", "max-size": "1k", "max-file": "2"},
+			err:    "invalid value 'yes' for log opt 'compress'",
+		},
+		{
+			config: map[string]string{"compress": "", "max-size": "1k", "max-file": "2"},
+			err:    "invalid value '' for log opt 'compress'",
+		},
+		{
+			config: map[string]string{"compress": "true", "max-size": "1k"},
+			err:    "compress cannot be true",
+		},
+		{
+			config: map[string]string{"compress": "true", "max-size": "1k", "max-file": "1"},
+			err:    "compress cannot be true",
+		},
+		{
+			config: map[string]string{"compress": "true", "max-file": "2"},
+			err:    "compress cannot be true",
+		},
+	}
+
+	for _, c := range cases {
+		err := ValidateLogOpt(c.config)
+		if c.err == "" {
+			require.NoError(t, err, "%v", c.config)
+			continue
+		}
+		require.Error(t, err, "%v", c.config)
+		require.Contains(t, err.Error(), c.err)
+	}
+}
@@ -3,10 +3,8 @@ package jsonfilelog
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"os"
-	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"golang.org/x/net/context"
@@ -14,9 +12,8 @@ import (
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/daemon/logger/jsonfilelog/jsonlog"
-	"github.com/docker/docker/daemon/logger/jsonfilelog/multireader"
+	"github.com/docker/docker/daemon/logger/loggerutils"
 	"github.com/docker/docker/pkg/filenotify"
-	"github.com/docker/docker/pkg/tailfile"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -59,56 +56,33 @@ func (l *JSONFileLogger) readLogs(logWatcher *logger.LogWatcher, config logger.R
 	// lock so the read stream doesn't get corrupted due to rotations or other log data written while we open these files
 	// This will block writes!!!
 	l.mu.RLock()
-
-	// TODO it would be nice to move a lot of this reader implementation to the rotate logger object id:226 gh:227
-	pth := l.writer.LogPath()
-	var files []io.ReadSeeker
-	for i := l.writer.MaxFiles(); i > 1; i-- {
-		f, err := os.Open(fmt.Sprintf("%s.%d", pth, i-1))
-		if err != nil {
-			if !os.IsNotExist(err) {
-				logWatcher.Err <- err
-				l.mu.RUnlock()
-				return
-			}
-			continue
-		}
-		defer f.Close()
-		files = append(files, f)
-	}
-
-	latestFile, err := os.Open(pth)
-	if err != nil {
-		logWatcher.Err <- errors.Wrap(err, "error opening latest log file")
-		l.mu.RUnlock()
-		return
-	}
-	defer latestFile.Close()
-
-	latestChunk, err := newSectionReader(latestFile)
-
-	// Now we have the reader sectioned, all fd's opened, we can unlock.
-	// New writes/rotates will not affect seeking through these files
+	files, err := l.writer.OpenFiles()
+	// Now all fd's are opened and limited to their current size, we can
+	// unlock. New writes/rotates will not affect reading these files
 	l.mu.RUnlock()
 
 	if err != nil {
 		logWatcher.Err <- err
 		return
 	}
+	latest := files[len(files)-1]
+	rotated := files[:len(files)-1]
 
 	if config.Tail != 0 {
-		tailer := multireader.MultiReadSeeker(append(files, latestChunk)...)
-		tailFile(tailer, logWatcher, config.Tail, config.Since)
+		if err := tailFiles(files, logWatcher, config); err != nil {
+			logWatcher.Err <- err
+		}
 	}
 
 	// close all the rotated files
-	for _, f := range files {
-		if err := f.(io.Closer).Close(); err != nil {
+	for _, f := range rotated {
+		if err := f.Close(); err != nil {
 			logrus.WithField("logger", "json-file").Warnf("error closing tailed log file: %v", err)
 		}
 	}
 
 	if !config.Follow || l.closed {
+		latest.Close()
 		return
 	}
 
@@ -119,48 +93,66 @@ func (l *JSONFileLogger) readLogs(logWatcher *logger.LogWatcher, config logger.R
 	l.readers[logWatcher] = struct{}{}
 	l.mu.Unlock()
 
-	followLogs(latestFile, logWatcher, notifyRotate, config.Since)
+	followLogs(latest.File(), logWatcher, notifyRotate, config)
 
 	l.mu.Lock()
 	delete(l.readers, logWatcher)
 	l.mu.Unlock()
 }
 
-func newSectionReader(f *os.File) (*io.SectionReader, error) {
-	// seek to the end to get the size
-	// we'll leave this at the end of the file since section reader does not advance the reader
-	size, err := f.Seek(0, os.SEEK_END)
-	if err != nil {
-		return nil, errors.Wrap(err, "error getting current file size")
-	}
-	return io.NewSectionReader(f, 0, size), nil
-}
-
-func tailFile(f io.ReadSeeker, logWatcher *logger.LogWatcher, tail int, since time.Time) {
-	rdr := io.Reader(f)
-	if tail > 0 {
-		ls, err := tailfile.TailFile(f, tail)
-		if err != nil {
-			logWatcher.Err <- err
-			return
+// tailFiles sends the messages of the log files to the watcher. Files are
+// read from the first message which may have been emitted after
+// config.Since according to their index, and only the end of the files
+// needed to get config.Tail lines is read.
+func tailFiles(files []*loggerutils.LogFile, logWatcher *logger.LogWatcher, config logger.ReadConfig) error {
+	var rdr io.Reader
+	if config.Tail > 0 {
+		var lines [][]byte
+		for i := len(files) - 1; i >= 0 && len(lines) < config.Tail; i-- {
+			ls, err := files[i].Tail(config.Tail - len(lines))
+			if err != nil {
+				return err
+			}
+			lines = append(ls, lines...)
 		}
-		rdr = bytes.NewBuffer(bytes.Join(ls, []byte("\n")))
+		rdr = bytes.NewBuffer(bytes.Join(lines, []byte("\n")))
+	} else {
+		var readers []io.Reader
+		for i, f := range files {
+			var next *loggerutils.LogFile
+			if i+1 < len(files) {
+				next = files[i+1]
+			}
+			if f.OlderThan(config.Since, next) {
+				continue
+			}
+			r, err := f.ReadFrom(config.Since)
+			if err != nil {
+				return err
+			}
+			readers = append(readers, r)
+		}
+		rdr = io.MultiReader(readers...)
 	}
+
 	dec := json.NewDecoder(rdr)
 	for {
 		msg, err := decodeLogLine(dec, &jsonlog.JSONLog{})
 		if err != nil {
 			if err != io.EOF {
-				logWatcher.Err <- err
+				return err
 			}
-			return
+			return nil
 		}
-		if !since.IsZero() && msg.Timestamp.Before(since) {
+		if !config.Since.IsZero() && msg.Timestamp.Before(config.Since) {
+			continue
+		}
+		if !config.Filter.Match(msg) {
 			continue
 		}
 		select {
 		case <-logWatcher.WatchClose():
-			return
+			return nil
 		case logWatcher.Msg <- msg:
 		}
 	}
@@ -186,7 +178,8 @@ func watchFile(name string) (filenotify.FileWatcher, error) {
 	return fileWatcher, nil
 }
 
-func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan interface{}, since time.Time) {
+func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan interface{}, config logger.ReadConfig) {
+	since := config.Since
 	dec := json.NewDecoder(f)
 	l := &jsonlog.JSONLog{}
 
@@ -328,6 +321,9 @@ func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan int
 		if !since.IsZero() && msg.Timestamp.Before(since) {
 			continue
 		}
+		if !config.Filter.Match(msg) {
+			continue
+		}
 		select {
 		case logWatcher.Msg <- msg:
 		case <-ctx.Done():
@@ -340,6 +336,9 @@ func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan int
 				if !since.IsZero() && msg.Timestamp.Before(since) {
 					continue
 				}
+				if !config.Filter.Match(msg) {
+					continue
+				}
 				logWatcher.Msg <- msg
 			}
 		}
@@ -2,6 +2,7 @@ package jsonfilelog
 
 import (
 	"bytes"
+	"os"
 	"testing"
 	"time"
 
@@ -62,3 +63,106 @@ func BenchmarkJSONFileLoggerReadLogs(b *testing.B) {
 		}
 	}
 }
+
+func readAllLogs(t *testing.T, l logger.Logger, config logger.ReadConfig) []*logger.Message {
+	lw := l.(logger.LogReader).ReadLogs(config)
+	defer lw.Close()
+	var msgs []*logger.Message
+	for {
+		select {
+		case msg, ok := <-lw.Msg:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		case err := <-lw.Err:
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout reading logs")
+		}
+	}
+}
+
+func TestJSONFileLoggerReadCompressedLogs(t *testing.T) {
+	tmp := fs.NewDir(t, "jsonfilelog-compress")
+	defer tmp.Remove()
+
+	l, err := New(logger.Info{
+		ContainerID: "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		LogPath:     tmp.Join("container.log"),
+		Config: map[string]string{
+			"max-size": "256k",
+			"max-file": "3",
+			"compress": "true",
+		},
+	})
+	require.NoError(t, err)
+	defer l.Close()
+
+	start := time.Unix(1500000000, 0).UTC()
+	line := bytes.Repeat([]byte("x"), 100)
+	const count = 5000
+	for i := 0; i < count; i++ {
+		msg := &logger.Message{Line: line, Source: "stdout", Timestamp: start.Add(time.Duration(i) * time.Second)}
+		require.NoError(t, l.Log(msg))
+	}
+	// wait for the compression of the rotated file
+	require.NoError(t, l.Close())
+
+	_, err = os.Stat(tmp.Join("container.log.1.gz"))
+	require.NoError(t, err)
+	_, err = os.Stat(tmp.Join("container.log.1"))
+	require.True(t, os.IsNotExist(err))
+
+	msgs := readAllLogs(t, l, logger.ReadConfig{
+		Tail:  -1,
+		Since: start.Add(3000 * time.Second),
+	})
+	require.Len(t, msgs, count-3000)
+	require.Equal(t, start.Add(3000*time.Second), msgs[0].Timestamp)
+
+	msgs = readAllLogs(t, l, logger.ReadConfig{Tail: 5})
+	require.Len(t, msgs, 5)
+	require.Equal(t, start.Add((count-1)*time.Second), msgs[4].Timestamp)
+
+	all := readAllLogs(t, l, logger.ReadConfig{Tail: -1})
+	require.Equal(t, all[len(all)-1].Timestamp, start.Add((count-1)*time.Second))
+	for i := 1; i < len(all); i++ {
+		require.Equal(t, all[i-1].Timestamp.Add(time.Second), all[i].Timestamp)
+	}
+}
+
+func TestJSONFileLoggerReadSinceAfterRestart(t *testing.T) {
+	tmp := fs.NewDir(t, "jsonfilelog-restart")
+	defer tmp.Remove()
+
+	info := logger.Info{
+		ContainerID: "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		LogPath:     tmp.Join("container.log"),
+	}
+	start := time.Unix(1500000000, 0).UTC()
+	line := []byte("line")
+
+	l, err := New(info)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		msg := &logger.Message{Line: line, Source: "stdout", Timestamp: start.Add(time.Duration(i) * time.Second)}
+		require.NoError(t, l.Log(msg))
+	}
+	require.NoError(t, l.Close())
+
+	// the messages written before the restart are after the last index
+	// entry, and must not be skipped by the entry of the next message
+	l, err = New(info)
+	require.NoError(t, err)
+	defer l.Close()
+	msg := &logger.Message{Line: line, Source: "stdout", Timestamp: start.Add(10 * time.Second)}
+	require.NoError(t, l.Log(msg))
+
+	msgs := readAllLogs(t, l, logger.ReadConfig{
+		Tail:  -1,
+		Since: start.Add(5 * time.Second),
+	})
+	require.Len(t, msgs, 6)
+	require.Equal(t, start.Add(5*time.Second), msgs[0].Timestamp)
+}
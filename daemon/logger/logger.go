@@ -83,6 +83,9 @@ type ReadConfig struct {
 	Since  time.Time
 	Tail   int
 	Follow bool
+	// Filter selects the messages to read. When Tail is set, it applies to
+	// the last Tail messages.
+	Filter *ReadFilter `json:",omitempty"`
 }
 
 // LogReader is the interface for reading log messages for loggers that support reading.
@@ -0,0 +1,325 @@
+package loggerutils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/tailfile"
+	"github.com/pkg/errors"
+)
+
+const (
+	// compressedExt is the extension of rotated files compressed with gzip.
+	compressedExt = ".gz"
+	// indexExt is the extension of the time index of a log file.
+	indexExt = ".idx"
+	// indexInterval is the minimum number of bytes written to a log file
+	// between two entries of its time index. In compressed files, each
+	// entry also starts a new gzip member, which can be decompressed
+	// without reading the rest of the file.
+	indexInterval = 64 * 1024
+	// indexEntrySize is the on-disk size of an index entry.
+	indexEntrySize = 16
+)
+
+// indexEntry points to the offset of a message in a log file. timeNano is
+// the latest timestamp of all the messages written before that offset, in
+// any file of the log.
+type indexEntry struct {
+	timeNano int64
+	offset   int64
+}
+
+func indexPath(logPath string) string {
+	return strings.TrimSuffix(logPath, compressedExt) + indexExt
+}
+
+func readIndex(path string) ([]indexEntry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "error reading log file index")
+	}
+	entries := make([]indexEntry, 0, len(b)/indexEntrySize)
+	for ; len(b) >= indexEntrySize; b = b[indexEntrySize:] {
+		entries = append(entries, indexEntry{
+			timeNano: int64(binary.LittleEndian.Uint64(b[:8])),
+			offset:   int64(binary.LittleEndian.Uint64(b[8:16])),
+		})
+	}
+	return entries, nil
+}
+
+func writeIndex(path string, entries []indexEntry) error {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		appendIndexEntry(&buf, e)
+	}
+	return errors.Wrap(ioutil.WriteFile(path, buf.Bytes(), 0640), "error writing log file index")
+}
+
+func appendIndexEntry(w io.Writer, e indexEntry) error {
+	var b [indexEntrySize]byte
+	binary.LittleEndian.PutUint64(b[:8], uint64(e.timeNano))
+	binary.LittleEndian.PutUint64(b[8:], uint64(e.offset))
+	_, err := w.Write(b[:])
+	return err
+}
+
+// compressFile replaces the log file at path with a gzip compressed copy.
+// Every section of the file between two index entries is compressed as a
+// separate gzip member, and the index is updated to point to the members.
+func compressFile(path string, filesMu *sync.RWMutex) error {
+	entries, err := readIndex(indexPath(path))
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 || entries[0].offset != 0 {
+		entries = append([]indexEntry{{offset: 0}}, entries...)
+	}
+
+	dst, err := os.OpenFile(path+compressedExt+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+
+	compressed := make([]indexEntry, 0, len(entries))
+	var written int64
+	for i, e := range entries {
+		end := fi.Size()
+		if i+1 < len(entries) {
+			end = entries[i+1].offset
+		}
+		compressed = append(compressed, indexEntry{timeNano: e.timeNano, offset: written})
+
+		cw := &countingWriter{w: dst}
+		zw := gzip.NewWriter(cw)
+		if _, err := io.Copy(zw, io.NewSectionReader(src, e.offset, end-e.offset)); err != nil {
+			dst.Close()
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			dst.Close()
+			return err
+		}
+		written += cw.n
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := writeIndex(indexPath(path)+".tmp", compressed); err != nil {
+		return err
+	}
+	defer os.Remove(indexPath(path) + ".tmp")
+
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	if err := os.Rename(dst.Name(), path+compressedExt); err != nil {
+		return err
+	}
+	if err := os.Rename(indexPath(path)+".tmp", indexPath(path)); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// LogFile is a log file opened for reading, along with its time index.
+type LogFile struct {
+	f          *os.File
+	size       int64
+	compressed bool
+	index      []indexEntry
+}
+
+// OpenFiles opens the rotated files of the log, oldest first, followed by
+// the latest file. The content of the files is limited to what has been
+// written at the time they are opened. The caller must close all the
+// returned files.
+func (w *RotateFileWriter) OpenFiles() ([]*LogFile, error) {
+	w.filesMu.RLock()
+	defer w.filesMu.RUnlock()
+
+	name := w.LogPath()
+	var files []*LogFile
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	for i := w.maxFiles; i > 1; i-- {
+		lf, err := openLogFile(name + "." + strconv.Itoa(i-1))
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		if lf != nil {
+			files = append(files, lf)
+		}
+	}
+
+	latest, err := openLogFile(name)
+	if err == nil && latest == nil {
+		err = errors.New("log file does not exist")
+	}
+	if err != nil {
+		closeAll()
+		return nil, errors.Wrap(err, "error opening latest log file")
+	}
+	return append(files, latest), nil
+}
+
+// openLogFile opens the log file at path, or its compressed version. It
+// returns nil if neither exist.
+func openLogFile(path string) (*LogFile, error) {
+	lf := &LogFile{}
+	f, err := os.Open(path + compressedExt)
+	if err == nil {
+		lf.compressed = true
+	} else if os.IsNotExist(err) {
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	lf.f = f
+
+	// seek to the end to get the size
+	// the file is left at the end, so that followers can keep reading
+	// from there
+	if lf.size, err = f.Seek(0, os.SEEK_END); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "error getting current file size")
+	}
+	if lf.index, err = readIndex(indexPath(path)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// an index entry may be written before the message it points to
+	for len(lf.index) > 0 && lf.index[len(lf.index)-1].offset >= lf.size {
+		lf.index = lf.index[:len(lf.index)-1]
+	}
+	return lf, nil
+}
+
+// File returns the underlying file, positioned after the content
+// available for reading.
+func (lf *LogFile) File() *os.File {
+	return lf.f
+}
+
+// Close closes the underlying file.
+func (lf *LogFile) Close() error {
+	return lf.f.Close()
+}
+
+// OlderThan returns true if every message in the file was written before
+// t, according to the index of next, the file that follows this one.
+func (lf *LogFile) OlderThan(t time.Time, next *LogFile) bool {
+	if t.IsZero() || next == nil || len(next.index) == 0 || next.index[0].offset != 0 {
+		return false
+	}
+	return next.index[0].timeNano < t.UnixNano()
+}
+
+// ReadFrom returns a reader of the uncompressed content of the file. The
+// reader starts at the last indexed offset before which all the messages
+// were written before since, so messages written before since may still
+// be returned.
+func (lf *LogFile) ReadFrom(since time.Time) (io.Reader, error) {
+	var offset int64
+	if !since.IsZero() {
+		sinceNano := since.UnixNano()
+		i := sort.Search(len(lf.index), func(i int) bool { return lf.index[i].timeNano >= sinceNano })
+		if i > 0 {
+			offset = lf.index[i-1].offset
+		}
+	}
+
+	r := io.NewSectionReader(lf.f, offset, lf.size-offset)
+	if !lf.compressed {
+		return r, nil
+	}
+	if lf.size == 0 {
+		return bytes.NewReader(nil), nil
+	}
+	return gzip.NewReader(r)
+}
+
+// Tail returns the last n lines of the file. Only the end of compressed
+// files is decompressed.
+func (lf *LogFile) Tail(n int) ([][]byte, error) {
+	if !lf.compressed {
+		return tailfile.TailFile(io.NewSectionReader(lf.f, 0, lf.size), n)
+	}
+
+	offsets := []int64{0}
+	for _, e := range lf.index {
+		if e.offset > 0 {
+			offsets = append(offsets, e.offset)
+		}
+	}
+
+	var lines [][]byte
+	end := lf.size
+	for i := len(offsets) - 1; i >= 0 && len(lines) < n; i-- {
+		start := offsets[i]
+		zr, err := gzip.NewReader(io.NewSectionReader(lf.f, start, end-start))
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 0 {
+			ls, err := tailfile.TailFile(bytes.NewReader(b), n-len(lines))
+			if err != nil {
+				return nil, err
+			}
+			lines = append(ls, lines...)
+		}
+		end = start
+	}
+	return lines, nil
+}
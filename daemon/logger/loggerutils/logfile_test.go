@@ -0,0 +1,266 @@
+package loggerutils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotestyourself/gotestyourself/fs"
+	"github.com/stretchr/testify/require"
+)
+
+var testStart = time.Unix(1500000000, 0).UTC()
+
+// testLine returns the i-th line written by writeTestLines, 100 bytes long
+// without the newline.
+func testLine(i int) []byte {
+	return []byte(fmt.Sprintf("line %06d %s", i, strings.Repeat("x", 88)))
+}
+
+// testLines returns the lines from first to last, excluded.
+func testLines(first, last int) [][]byte {
+	var lines [][]byte
+	for i := first; i < last; i++ {
+		lines = append(lines, testLine(i))
+	}
+	return lines
+}
+
+// writeTestLines writes count lines to w, the i-th line being written at
+// testStart plus i seconds.
+func writeTestLines(t *testing.T, w *RotateFileWriter, count int) {
+	for i := 0; i < count; i++ {
+		_, err := w.WriteLog(append(testLine(i), '\n'), testStart.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+	}
+}
+
+func readLines(t *testing.T, lf *LogFile, since time.Time) [][]byte {
+	r, err := lf.ReadFrom(since)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	if len(b) == 0 {
+		return nil
+	}
+	return bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+}
+
+func TestCompressFile(t *testing.T) {
+	cases := []struct {
+		name  string
+		index []indexEntry
+	}{
+		{name: "no index"},
+		{
+			name:  "index from the start",
+			index: []indexEntry{{timeNano: 1, offset: 0}, {timeNano: 2, offset: 1000}, {timeNano: 3, offset: 2500}},
+		},
+		{
+			name:  "index after the start",
+			index: []indexEntry{{timeNano: 2, offset: 1000}, {timeNano: 3, offset: 2500}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tmp := fs.NewDir(t, "loggerutils-compress")
+			defer tmp.Remove()
+
+			path := tmp.Join("container.log.1")
+			var content []byte
+			for _, l := range testLines(0, 30) {
+				content = append(content, l...)
+				content = append(content, '\n')
+			}
+			require.NoError(t, ioutil.WriteFile(path, content, 0640))
+			if c.index != nil {
+				require.NoError(t, writeIndex(indexPath(path), c.index))
+			}
+
+			require.NoError(t, compressFile(path, &sync.RWMutex{}))
+
+			_, err := os.Stat(path)
+			require.True(t, os.IsNotExist(err), "the uncompressed file must be removed")
+			compressed, err := ioutil.ReadFile(path + compressedExt)
+			require.NoError(t, err)
+			index, err := readIndex(indexPath(path))
+			require.NoError(t, err)
+
+			// every section between two entries is a separate gzip member
+			sections := c.index
+			if len(sections) == 0 || sections[0].offset != 0 {
+				sections = append([]indexEntry{{offset: 0}}, sections...)
+			}
+			require.Len(t, index, len(sections))
+			for i, e := range index {
+				require.Equal(t, sections[i].timeNano, e.timeNano)
+				end, zend := int64(len(content)), int64(len(compressed))
+				if i+1 < len(index) {
+					end, zend = sections[i+1].offset, index[i+1].offset
+				}
+				zr, err := gzip.NewReader(bytes.NewReader(compressed[e.offset:zend]))
+				require.NoError(t, err)
+				b, err := ioutil.ReadAll(zr)
+				require.NoError(t, err)
+				require.Equal(t, content[sections[i].offset:end], b)
+			}
+		})
+	}
+}
+
+func TestRotateFileWriterCompress(t *testing.T) {
+	// 1500 lines fill a file
+	const capacity = 1500 * 101
+
+	cases := []struct {
+		name     string
+		maxFiles int
+		compress bool
+		count    int
+		files    []string
+		missing  []string
+		first    int
+		// rotated is the last line of container.log.1
+		rotated int
+	}{
+		{
+			name:     "compressed",
+			maxFiles: 3,
+			compress: true,
+			count:    4000,
+			files:    []string{"container.log.2.gz", "container.log.1.gz", "container.log"},
+			missing:  []string{"container.log.2", "container.log.1"},
+			rotated:  2999,
+		},
+		{
+			name:     "uncompressed",
+			maxFiles: 3,
+			count:    4000,
+			files:    []string{"container.log.2", "container.log.1", "container.log"},
+			missing:  []string{"container.log.2.gz", "container.log.1.gz"},
+			rotated:  2999,
+		},
+		{
+			name:     "compressed files dropped",
+			maxFiles: 2,
+			compress: true,
+			count:    4000,
+			files:    []string{"container.log.1.gz", "container.log"},
+			missing:  []string{"container.log.2.gz", "container.log.2", "container.log.1"},
+			first:    1500,
+			rotated:  2999,
+		},
+		{
+			name:     "compressed files shifted",
+			maxFiles: 4,
+			compress: true,
+			count:    5000,
+			files:    []string{"container.log.3.gz", "container.log.2.gz", "container.log.1.gz", "container.log"},
+			missing:  []string{"container.log.3", "container.log.2", "container.log.1"},
+			rotated:  4499,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tmp := fs.NewDir(t, "loggerutils-rotate")
+			defer tmp.Remove()
+
+			w, err := NewRotateFileWriter(tmp.Join("container.log"), capacity, c.maxFiles, c.compress, nil)
+			require.NoError(t, err)
+			writeTestLines(t, w, c.count)
+			// wait for the compression of the last rotated file
+			require.NoError(t, w.Close())
+
+			for _, name := range c.files {
+				_, err := os.Stat(tmp.Join(name))
+				require.NoError(t, err)
+			}
+			for _, name := range c.missing {
+				_, err := os.Stat(tmp.Join(name))
+				require.True(t, os.IsNotExist(err), "%s must not exist", name)
+			}
+
+			files, err := w.OpenFiles()
+			require.NoError(t, err)
+			defer func() {
+				for _, f := range files {
+					f.Close()
+				}
+			}()
+			require.Len(t, files, len(c.files))
+
+			var lines [][]byte
+			for _, f := range files {
+				lines = append(lines, readLines(t, f, time.Time{})...)
+			}
+			require.Equal(t, testLines(c.first, c.count), lines)
+
+			// reading since the last line of a rotated file skips the
+			// sections written before it
+			since := testStart.Add(time.Duration(c.rotated) * time.Second)
+			lines = readLines(t, files[len(files)-2], since)
+			require.True(t, len(lines) < 1500, "expected the start of the file to be skipped")
+			require.Equal(t, testLine(c.rotated), lines[len(lines)-1])
+		})
+	}
+}
+
+func TestLogFileTailCompressed(t *testing.T) {
+	tmp := fs.NewDir(t, "loggerutils-tail")
+	defer tmp.Remove()
+
+	// the latest file holds lines 3000 to 3999, and the compressed ones
+	// 1500 lines each
+	const count = 4000
+	w, err := NewRotateFileWriter(tmp.Join("container.log"), 1500*101, 3, true, nil)
+	require.NoError(t, err)
+	writeTestLines(t, w, count)
+	require.NoError(t, w.Close())
+
+	files, err := w.OpenFiles()
+	require.NoError(t, err)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	require.Len(t, files, 3)
+
+	cases := []struct {
+		name string
+		n    int
+	}{
+		{name: "latest file", n: 10},
+		{name: "whole latest file", n: 1000},
+		{name: "last member of a compressed file", n: 1010},
+		{name: "several members of a compressed file", n: 2000},
+		{name: "across compressed files", n: 3000},
+		{name: "whole log", n: count},
+		{name: "more than the log", n: 2 * count},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var lines [][]byte
+			for i := len(files) - 1; i >= 0 && len(lines) < c.n; i-- {
+				ls, err := files[i].Tail(c.n - len(lines))
+				require.NoError(t, err)
+				lines = append(ls, lines...)
+			}
+
+			first := count - c.n
+			if first < 0 {
+				first = 0
+			}
+			require.Equal(t, testLines(first, count), lines)
+		})
+	}
+}
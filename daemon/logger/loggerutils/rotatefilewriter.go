@@ -1,12 +1,16 @@
 package loggerutils
 
 import (
+	"bufio"
 	"errors"
+	"io"
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/pkg/pubsub"
+	"github.com/sirupsen/logrus"
 )
 
 // RotateFileWriter is Logger implementation for default Docker logging.
@@ -17,11 +21,26 @@ type RotateFileWriter struct {
 	capacity     int64 //maximum size of each file
 	currentSize  int64 // current size of the latest file
 	maxFiles     int   //maximum number of files
+	compress     bool  // whether rotated files are compressed
 	notifyRotate *pubsub.Publisher
+
+	index        *os.File // time index of the latest file
+	lastIndexed  int64    // offset of the last index entry in the latest file
+	maxTimestamp int64    // latest timestamp written so far, in nanoseconds
+
+	// filesMu protects the names of the files on disk, which change on
+	// rotation and when a rotated file has been compressed.
+	filesMu sync.RWMutex
+	// compressed is closed when the compression of the last rotated file
+	// is done.
+	compressed chan struct{}
 }
 
-//NewRotateFileWriter creates new RotateFileWriter
-func NewRotateFileWriter(logPath string, capacity int64, maxFiles int) (*RotateFileWriter, error) {
+//NewRotateFileWriter creates new RotateFileWriter. If compress is set,
+//rotated files are compressed with gzip. getTimestamp returns the timestamp
+//of a message written to the file, and is used to find the latest timestamp
+//written by a previous writer.
+func NewRotateFileWriter(logPath string, capacity int64, maxFiles int, compress bool, getTimestamp func([]byte) (time.Time, error)) (*RotateFileWriter, error) {
 	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
 	if err != nil {
 		return nil, err
@@ -29,20 +48,101 @@ func NewRotateFileWriter(logPath string, capacity int64, maxFiles int) (*RotateF
 
 	size, err := log.Seek(0, os.SEEK_END)
 	if err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	entries, err := readIndex(indexPath(logPath))
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
+	// drop entries past the end of a truncated file
+	for len(entries) > 0 && entries[len(entries)-1].offset >= size {
+		entries = entries[:len(entries)-1]
+	}
+	if err := writeIndex(indexPath(logPath), entries); err != nil {
+		log.Close()
+		return nil, err
+	}
+	index, err := os.OpenFile(indexPath(logPath), os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		log.Close()
 		return nil, err
 	}
 
-	return &RotateFileWriter{
+	w := &RotateFileWriter{
 		f:            log,
+		index:        index,
 		capacity:     capacity,
 		currentSize:  size,
 		maxFiles:     maxFiles,
+		compress:     compress,
 		notifyRotate: pubsub.NewPublisher(0, 1),
-	}, nil
+	}
+	// messages written after the last index entry are not indexed, so
+	// force a new entry on the next write
+	w.lastIndexed = -indexInterval
+	var offset int64
+	if n := len(entries); n > 0 {
+		w.maxTimestamp = entries[n-1].timeNano
+		offset = entries[n-1].offset
+	}
+	// the next index entry must also cover the messages written after the
+	// last one
+	ts, err := latestTimestamp(logPath, offset, size, getTimestamp)
+	if err != nil {
+		log.Close()
+		index.Close()
+		return nil, err
+	}
+	if ts > w.maxTimestamp {
+		w.maxTimestamp = ts
+	}
+	return w, nil
+}
+
+// latestTimestamp returns the latest timestamp of the messages in the file
+// at path between offset and size, in nanoseconds. Lines which cannot be
+// decoded, such as a message cut short by a crash, are skipped.
+func latestTimestamp(path string, offset, size int64, getTimestamp func([]byte) (time.Time, error)) (int64, error) {
+	if getTimestamp == nil || offset >= size {
+		return 0, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var latest int64
+	rd := bufio.NewReader(io.NewSectionReader(f, offset, size-offset))
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(line) > 0 {
+			if ts, terr := getTimestamp(line); terr == nil && !ts.IsZero() && ts.UnixNano() > latest {
+				latest = ts.UnixNano()
+			}
+		}
+		if err == io.EOF {
+			return latest, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
 }
 
 //WriteLog write log message to File
 func (w *RotateFileWriter) Write(message []byte) (int, error) {
+	return w.WriteLog(message, time.Time{})
+}
+
+// WriteLog writes a log message emitted at timestamp to the file. The
+// timestamp is used to maintain a sparse time index of the file, so that
+// readers can skip older messages without decoding them. The message must
+// be a whole number of lines.
+func (w *RotateFileWriter) WriteLog(message []byte, timestamp time.Time) (int, error) {
 	w.mu.Lock()
 	if w.closed {
 		w.mu.Unlock()
@@ -53,9 +153,23 @@ func (w *RotateFileWriter) Write(message []byte) (int, error) {
 		return -1, err
 	}
 
+	if w.currentSize-w.lastIndexed >= indexInterval {
+		// the entry records the latest timestamp of the messages before
+		// it, so that no message after since can be skipped even if
+		// messages are written slightly out of order.
+		e := indexEntry{timeNano: w.maxTimestamp, offset: w.currentSize}
+		if err := appendIndexEntry(w.index, e); err != nil {
+			logrus.WithError(err).Warn("error writing log file index")
+		}
+		w.lastIndexed = w.currentSize
+	}
+
 	n, err := w.f.Write(message)
 	if err == nil {
 		w.currentSize += int64(n)
+		if ts := timestamp.UnixNano(); !timestamp.IsZero() && ts > w.maxTimestamp {
+			w.maxTimestamp = ts
+		}
 	}
 	w.mu.Unlock()
 	return n, err
@@ -67,19 +181,50 @@ func (w *RotateFileWriter) checkCapacityAndRotate() error {
 	}
 
 	if w.currentSize >= w.capacity {
+		// rotated files cannot be shifted while the previous one is
+		// being compressed
+		if w.compressed != nil {
+			<-w.compressed
+			w.compressed = nil
+		}
+
 		name := w.f.Name()
 		if err := w.f.Close(); err != nil {
 			return err
 		}
-		if err := rotate(name, w.maxFiles); err != nil {
+		if err := w.index.Close(); err != nil {
+			return err
+		}
+		w.filesMu.Lock()
+		err := rotate(name, w.maxFiles)
+		w.filesMu.Unlock()
+		if err != nil {
 			return err
 		}
 		file, err := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0640)
 		if err != nil {
 			return err
 		}
+		index, err := os.OpenFile(indexPath(name), os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0640)
+		if err != nil {
+			file.Close()
+			return err
+		}
 		w.f = file
+		w.index = index
 		w.currentSize = 0
+		w.lastIndexed = -indexInterval
+
+		if w.compress && w.maxFiles > 1 {
+			done := make(chan struct{})
+			w.compressed = done
+			go func() {
+				defer close(done)
+				if err := compressFile(name+".1", &w.filesMu); err != nil {
+					logrus.WithError(err).WithField("file", name+".1").Error("error compressing rotated log file")
+				}
+			}()
+		}
 		w.notifyRotate.Publish(struct{}{})
 	}
 
@@ -93,13 +238,35 @@ func rotate(name string, maxFiles int) error {
 	for i := maxFiles - 1; i > 1; i-- {
 		toPath := name + "." + strconv.Itoa(i)
 		fromPath := name + "." + strconv.Itoa(i-1)
-		if err := os.Rename(fromPath, toPath); err != nil && !os.IsNotExist(err) {
+		if err := rotatePath(fromPath, toPath); err != nil {
 			return err
 		}
 	}
 
-	if err := os.Rename(name, name+".1"); err != nil && !os.IsNotExist(err) {
-		return err
+	return rotatePath(name, name+".1")
+}
+
+// rotatePath renames a log file, in its compressed or uncompressed form,
+// along with its index.
+func rotatePath(fromPath, toPath string) error {
+	for _, p := range []string{toPath, toPath + compressedExt} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for _, ext := range []string{"", compressedExt} {
+		if err := os.Rename(fromPath+ext, toPath+ext); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(indexPath(fromPath), indexPath(toPath)); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		// don't keep a stale index around for the new file
+		if err := os.Remove(indexPath(toPath)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 	return nil
 }
@@ -133,9 +300,16 @@ func (w *RotateFileWriter) Close() error {
 	if w.closed {
 		return nil
 	}
+	if w.compressed != nil {
+		<-w.compressed
+		w.compressed = nil
+	}
 	if err := w.f.Close(); err != nil {
 		return err
 	}
+	if err := w.index.Close(); err != nil {
+		return err
+	}
 	w.closed = true
 	return nil
 }
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	multilineStartKey        = "multiline-start-regex"
+	multilinePatternKey      = "multiline-pattern"
+	multilineFlushTimeoutKey = "multiline-flush-timeout"
+
+	defaultMultilineFlushTimeout = time.Second
+	// maxMultilineSize is the size above which a multi-line message is
+	// logged, even if the next lines belong to it.
+	maxMultilineSize = 1024 * 1024
+)
+
+// MultilineConfig configures the aggregation of consecutive log lines into a
+// single message by a Copier.
+type MultilineConfig struct {
+	// StartPattern matches the first line of a message. The lines which
+	// don't match it are appended to the current message, as with the
+	// awslogs-multiline-pattern option of the awslogs driver.
+	StartPattern *regexp.Regexp
+	// FlushTimeout is the time after which a message is logged if no
+	// line was appended to it.
+	FlushTimeout time.Duration
+}
+
+// ParseMultilineConfig returns the multi-line configuration set in the log
+// options, or nil if multi-line aggregation is not enabled.
+func ParseMultilineConfig(cfg map[string]string) (*MultilineConfig, error) {
+	start, hasStart := cfg[multilineStartKey]
+	pattern, hasPattern := cfg[multilinePatternKey]
+	timeout, hasTimeout := cfg[multilineFlushTimeoutKey]
+	if !hasStart && !hasPattern {
+		if hasTimeout {
+			return nil, fmt.Errorf("logger: %s requires %s or %s", multilineFlushTimeoutKey, multilineStartKey, multilinePatternKey)
+		}
+		return nil, nil
+	}
+	if hasStart && hasPattern {
+		return nil, fmt.Errorf("logger: %s and %s cannot be used together", multilineStartKey, multilinePatternKey)
+	}
+
+	// both options mark the start of a message
+	key := multilineStartKey
+	if hasPattern {
+		key, start = multilinePatternKey, pattern
+	}
+
+	config := &MultilineConfig{FlushTimeout: defaultMultilineFlushTimeout}
+	var err error
+	if config.StartPattern, err = regexp.Compile(start); err != nil {
+		return nil, errors.Wrapf(err, "logger: error parsing %s", key)
+	}
+	if hasTimeout {
+		if config.FlushTimeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, errors.Wrapf(err, "logger: error parsing %s", multilineFlushTimeoutKey)
+		}
+		if config.FlushTimeout <= 0 {
+			return nil, fmt.Errorf("logger: %s must be positive", multilineFlushTimeoutKey)
+		}
+	}
+	return config, nil
+}
+
+// multilineBuffer aggregates the lines of a log stream into messages, and
+// logs each message when its last line is known, or when no line was
+// appended to it for the flush timeout.
+type multilineBuffer struct {
+	mu     sync.Mutex
+	config *MultilineConfig
+	dst    Logger
+	source string
+	msg    *Message
+	timer  *time.Timer
+	// closed is set once the stream is done. The flush timer may still
+	// fire afterwards, and must not log to a logger which may be closed.
+	closed bool
+}
+
+func newMultilineBuffer(source string, dst Logger, config *MultilineConfig) *multilineBuffer {
+	b := &multilineBuffer{
+		config: config,
+		dst:    dst,
+		source: source,
+	}
+	b.timer = time.AfterFunc(config.FlushTimeout, b.flush)
+	b.timer.Stop()
+	return b
+}
+
+// add appends the line to the current message if it belongs to it, or logs
+// the current message and starts a new one with the line.
+func (b *multilineBuffer) add(line []byte, timestamp time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.msg != nil && !b.config.StartPattern.Match(line) && len(b.msg.Line)+1+len(line) <= maxMultilineSize {
+		b.msg.Line = append(b.msg.Line, '\n')
+		b.msg.Line = append(b.msg.Line, line...)
+		b.timer.Reset(b.config.FlushTimeout)
+		return
+	}
+
+	b.flushLocked()
+	b.msg = NewMessage()
+	b.msg.Source = b.source
+	b.msg.Timestamp = timestamp
+	b.msg.Line = append(b.msg.Line, line...)
+	b.timer.Reset(b.config.FlushTimeout)
+}
+
+// flush logs the current message, if any, unless the buffer is closed.
+func (b *multilineBuffer) flush() {
+	b.mu.Lock()
+	if !b.closed {
+		b.flushLocked()
+	}
+	b.mu.Unlock()
+}
+
+func (b *multilineBuffer) flushLocked() {
+	b.timer.Stop()
+	if b.msg == nil {
+		return
+	}
+	if err := b.dst.Log(b.msg); err != nil {
+		logrus.Errorf("Failed to log msg %q for logger %s: %s", b.msg.Line, b.dst.Name(), err)
+	}
+	b.msg = nil
+}
+
+// close logs the current message if flush is set, or else drops it. Nothing
+// is logged once the buffer is closed.
+func (b *multilineBuffer) close(flush bool) {
+	b.mu.Lock()
+	if flush {
+		b.flushLocked()
+	}
+	b.timer.Stop()
+	if b.msg != nil {
+		PutMessage(b.msg)
+		b.msg = nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
@@ -77,10 +78,16 @@ func (daemon *Daemon) ContainerLogs(ctx context.Context, containerName string, c
 		since = time.Unix(s, n)
 	}
 
+	filter, err := newLogReadFilter(config)
+	if err != nil {
+		return nil, false, err
+	}
+
 	readConfig := logger.ReadConfig{
 		Since:  since,
 		Tail:   tailLines,
 		Follow: follow,
+		Filter: filter,
 	}
 
 	logs := logReader.ReadLogs(readConfig)
@@ -140,6 +147,68 @@ func (daemon *Daemon) ContainerLogs(ctx context.Context, containerName string, c
 	return messageChan, container.Config.Tty, nil
 }
 
+var acceptedLogFilters = map[string]bool{
+	"grep":   true,
+	"stream": true,
+	"attr":   true,
+}
+
+// newLogReadFilter returns the filter selecting the messages requested by
+// the filters and streams of the logs options, or nil if all the messages
+// are requested.
+func newLogReadFilter(config *types.ContainerLogsOptions) (*logger.ReadFilter, error) {
+	if err := config.Filters.Validate(acceptedLogFilters); err != nil {
+		return nil, err
+	}
+
+	var streams []string
+	for _, stream := range []struct {
+		name string
+		show bool
+	}{{"stdout", config.ShowStdout}, {"stderr", config.ShowStderr}} {
+		if !stream.show {
+			continue
+		}
+		if config.Filters.Include("stream") && !config.Filters.ExactMatch("stream", stream.name) {
+			continue
+		}
+		streams = append(streams, stream.name)
+	}
+	for _, stream := range config.Filters.Get("stream") {
+		if stream != "stdout" && stream != "stderr" {
+			return nil, validationError{errors.New("invalid stream filter " + stream + ", must be stdout or stderr")}
+		}
+	}
+	if len(streams) == 0 {
+		return nil, validationError{errors.New("You must choose at least one stream")}
+	}
+	if len(streams) == 2 {
+		streams = nil
+	}
+
+	var attrs map[string][]string
+	for _, attr := range config.Filters.Get("attr") {
+		kv := strings.SplitN(attr, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, validationError{errors.New("invalid attr filter " + attr + ", must be key=value")}
+		}
+		if attrs == nil {
+			attrs = make(map[string][]string)
+		}
+		attrs[kv[0]] = append(attrs[kv[0]], kv[1])
+	}
+
+	patterns := config.Filters.Get("grep")
+	if len(patterns) == 0 && streams == nil && attrs == nil {
+		return nil, nil
+	}
+	filter, err := logger.NewReadFilter(patterns, streams, attrs)
+	if err != nil {
+		return nil, validationError{err}
+	}
+	return filter, nil
+}
+
 func (daemon *Daemon) getLogger(container *container.Container) (l logger.Logger, created bool, err error) {
 	container.Lock()
 	if container.State.Running {
@@ -23,6 +23,14 @@ func (daemon *Daemon) setStateCounter(c *container.Container) {
 	default:
 		stateCtr.set(c.ID, "stopped")
 	}
+
+	if daemon.containerMetrics != nil {
+		if c.IsRunning() {
+			daemon.containerMetrics.Add(c)
+		} else {
+			daemon.containerMetrics.Remove(c)
+		}
+	}
 }
 
 // StateChanged updates daemon state changes from containerd
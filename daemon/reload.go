@@ -43,6 +43,9 @@ func (daemon *Daemon) Reload(conf *config.Config) (err error) {
 	daemon.reloadDebug(conf, attributes)
 	daemon.reloadMaxConcurrentDownloadsAndUploads(conf, attributes)
 	daemon.reloadShutdownTimeout(conf, attributes)
+	if err := daemon.reloadEventsRetention(conf, attributes); err != nil {
+		return err
+	}
 
 	if err := daemon.reloadClusterDiscovery(conf, attributes); err != nil {
 		return err
@@ -62,6 +65,9 @@ func (daemon *Daemon) Reload(conf *config.Config) (err error) {
 	if err := daemon.reloadLiveRestore(conf, attributes); err != nil {
 		return err
 	}
+	if err := daemon.reloadAPIRateLimits(conf, attributes); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -125,6 +131,30 @@ func (daemon *Daemon) reloadShutdownTimeout(conf *config.Config, attributes map[
 	attributes["shutdown-timeout"] = fmt.Sprintf("%d", daemon.configStore.ShutdownTimeout)
 }
 
+// reloadEventsRetention updates the retention of the events journal
+// and updates the passed attributes
+func (daemon *Daemon) reloadEventsRetention(conf *config.Config, attributes map[string]string) error {
+	// update corresponding configuration
+	if conf.IsValueSet("events-max-size") {
+		daemon.configStore.EventsMaxSize = conf.EventsMaxSize
+	}
+	if conf.IsValueSet("events-max-age") {
+		daemon.configStore.EventsMaxAge = conf.EventsMaxAge
+	}
+	if daemon.EventsService != nil {
+		if j := daemon.EventsService.Journal(); j != nil {
+			if err := j.SetConfig(eventsJournalConfig(daemon.configStore)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// prepare reload event attributes with updatable configurations
+	attributes["events-max-size"] = fmt.Sprintf("%d", daemon.configStore.EventsMaxSize.Value())
+	attributes["events-max-age"] = daemon.configStore.EventsMaxAge
+	return nil
+}
+
 // reloadClusterDiscovery updates configuration with cluster discovery options
 // and updates the passed attributes
 func (daemon *Daemon) reloadClusterDiscovery(conf *config.Config, attributes map[string]string) (err error) {
@@ -312,3 +342,20 @@ func (daemon *Daemon) reloadLiveRestore(conf *config.Config, attributes map[stri
 	attributes["live-restore"] = fmt.Sprintf("%t", daemon.configStore.LiveRestoreEnabled)
 	return nil
 }
+
+// reloadAPIRateLimits updates configuration with the API rate limits
+// and updates the passed attributes
+func (daemon *Daemon) reloadAPIRateLimits(conf *config.Config, attributes map[string]string) error {
+	// update corresponding configuration
+	if conf.IsValueSet("api-rate-limits") {
+		daemon.configStore.APIRateLimits = conf.APIRateLimits
+	}
+
+	// prepare reload event attributes with updatable configurations
+	limits, err := json.Marshal(daemon.configStore.APIRateLimits)
+	if err != nil {
+		return err
+	}
+	attributes["api-rate-limits"] = string(limits)
+	return nil
+}
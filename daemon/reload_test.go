@@ -481,3 +481,26 @@ func TestDaemonDiscoveryReloadOnlyClusterAdvertise(t *testing.T) {
 		t.Fatal(e)
 	}
 }
+
+func TestDaemonReloadAPIRateLimits(t *testing.T) {
+	daemon := &Daemon{
+		configStore: &config.Config{},
+	}
+
+	valuesSets := make(map[string]interface{})
+	valuesSets["api-rate-limits"] = map[string]interface{}{"POST /build": "1/1m"}
+	newConfig := &config.Config{
+		CommonConfig: config.CommonConfig{
+			APIRateLimits: map[string]string{"POST /build": "1/1m"},
+			ValuesSet:     valuesSets,
+		},
+	}
+
+	if err := daemon.Reload(newConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if limit := daemon.configStore.APIRateLimits["POST /build"]; limit != "1/1m" {
+		t.Fatalf("Expected API rate limit `1/1m` for `POST /build`, got %q", limit)
+	}
+}
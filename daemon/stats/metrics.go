@@ -0,0 +1,190 @@
+package stats
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/container"
+	metrics "github.com/docker/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// ContainerMetrics exports the resource usage of containers, as gathered by
+// a Collector, as Prometheus metrics. Every metric is labelled with the name
+// and image of the container, and with the value of a configurable set of
+// container labels.
+type ContainerMetrics struct {
+	mu         sync.Mutex
+	collector  *Collector
+	labelKeys  []string
+	containers map[string]*containerMetrics
+
+	cpuUsage    *prometheus.Desc
+	memoryUsage *prometheus.Desc
+	memoryLimit *prometheus.Desc
+	blkioRead   *prometheus.Desc
+	blkioWrite  *prometheus.Desc
+	networkRx   *prometheus.Desc
+	networkTx   *prometheus.Desc
+	pidsCurrent *prometheus.Desc
+	pidsLimit   *prometheus.Desc
+}
+
+type containerMetrics struct {
+	container *container.Container
+	ch        chan interface{}
+	stats     types.StatsJSON
+}
+
+// NewContainerMetrics creates the metrics for the containers tracked with
+// Add, and adds them to the namespace ns. labelKeys are the container
+// labels exported as metric labels; their names are prefixed with
+// "container_label_" and characters not allowed in Prometheus label names
+// are replaced with underscores. Keys mapping to the same label name are
+// only exported once.
+func NewContainerMetrics(s *Collector, ns *metrics.Namespace, labelKeys []string) *ContainerMetrics {
+	labels := []string{"name", "image"}
+	seen := make(map[string]bool)
+	var keys []string
+	for _, k := range labelKeys {
+		name := "container_label_" + invalidLabelChars.ReplaceAllString(k, "_")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		keys = append(keys, k)
+		labels = append(labels, name)
+	}
+	netLabels := append(append([]string{}, labels...), "interface")
+
+	m := &ContainerMetrics{
+		collector:  s,
+		labelKeys:  keys,
+		containers: make(map[string]*containerMetrics),
+
+		cpuUsage:    ns.NewDesc("cpu_usage_seconds", "The total CPU time consumed by the container", metrics.Total, labels...),
+		memoryUsage: ns.NewDesc("memory_usage", "The memory used by the container", metrics.Bytes, labels...),
+		memoryLimit: ns.NewDesc("memory_limit", "The memory limit of the container", metrics.Bytes, labels...),
+		blkioRead:   ns.NewDesc("blkio_read_bytes", "The number of bytes read from block devices by the container", metrics.Total, labels...),
+		blkioWrite:  ns.NewDesc("blkio_write_bytes", "The number of bytes written to block devices by the container", metrics.Total, labels...),
+		networkRx:   ns.NewDesc("network_receive_bytes", "The number of bytes received by the container per network interface", metrics.Total, netLabels...),
+		networkTx:   ns.NewDesc("network_transmit_bytes", "The number of bytes sent by the container per network interface", metrics.Total, netLabels...),
+		pidsCurrent: ns.NewDesc("pids", "The number of processes running in the container", metrics.Unit(""), labels...),
+		pidsLimit:   ns.NewDesc("pids_limit", "The maximum number of processes of the container, 0 if unlimited", metrics.Unit(""), labels...),
+	}
+	ns.Add(m)
+	return m
+}
+
+// Add starts exporting the resource usage of the container. It is a no-op
+// if the container is already tracked.
+func (m *ContainerMetrics) Add(c *container.Container) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.containers[c.ID]; exists {
+		return
+	}
+	ch := m.collector.Collect(c)
+	if ch == nil {
+		return
+	}
+	cm := &containerMetrics{container: c, ch: ch}
+	m.containers[c.ID] = cm
+	go m.update(cm)
+}
+
+// Remove stops exporting the resource usage of the container.
+func (m *ContainerMetrics) Remove(c *container.Container) {
+	m.mu.Lock()
+	cm, exists := m.containers[c.ID]
+	delete(m.containers, c.ID)
+	m.mu.Unlock()
+	if exists {
+		m.collector.Unsubscribe(c, cm.ch)
+	}
+}
+
+// update records the stats published for the container until the
+// subscription is closed.
+func (m *ContainerMetrics) update(cm *containerMetrics) {
+	for v := range cm.ch {
+		stats, ok := v.(types.StatsJSON)
+		if !ok {
+			continue
+		}
+		m.mu.Lock()
+		cm.stats = stats
+		m.mu.Unlock()
+	}
+	// the collector closes the subscription when the container is removed
+	m.mu.Lock()
+	if m.containers[cm.container.ID] == cm {
+		delete(m.containers, cm.container.ID)
+	}
+	m.mu.Unlock()
+}
+
+// Describe implements prometheus.Collector.
+func (m *ContainerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.cpuUsage
+	ch <- m.memoryUsage
+	ch <- m.memoryLimit
+	ch <- m.blkioRead
+	ch <- m.blkioWrite
+	ch <- m.networkRx
+	ch <- m.networkTx
+	ch <- m.pidsCurrent
+	ch <- m.pidsLimit
+}
+
+// Collect implements prometheus.Collector.
+func (m *ContainerMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, cm := range m.containers {
+		s := cm.stats
+		if s.Read.IsZero() {
+			// no stats collected yet, or the container is not running
+			continue
+		}
+		labels := m.labelValues(cm.container)
+
+		ch <- prometheus.MustNewConstMetric(m.cpuUsage, prometheus.CounterValue, float64(s.CPUStats.CPUUsage.TotalUsage)/1e9, labels...)
+		ch <- prometheus.MustNewConstMetric(m.memoryUsage, prometheus.GaugeValue, float64(s.MemoryStats.Usage), labels...)
+		ch <- prometheus.MustNewConstMetric(m.memoryLimit, prometheus.GaugeValue, float64(s.MemoryStats.Limit), labels...)
+
+		var read, write uint64
+		for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+			switch strings.ToLower(e.Op) {
+			case "read":
+				read += e.Value
+			case "write":
+				write += e.Value
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(m.blkioRead, prometheus.CounterValue, float64(read), labels...)
+		ch <- prometheus.MustNewConstMetric(m.blkioWrite, prometheus.CounterValue, float64(write), labels...)
+
+		for iface, n := range s.Networks {
+			netLabels := append(append([]string{}, labels...), iface)
+			ch <- prometheus.MustNewConstMetric(m.networkRx, prometheus.CounterValue, float64(n.RxBytes), netLabels...)
+			ch <- prometheus.MustNewConstMetric(m.networkTx, prometheus.CounterValue, float64(n.TxBytes), netLabels...)
+		}
+
+		ch <- prometheus.MustNewConstMetric(m.pidsCurrent, prometheus.GaugeValue, float64(s.PidsStats.Current), labels...)
+		ch <- prometheus.MustNewConstMetric(m.pidsLimit, prometheus.GaugeValue, float64(s.PidsStats.Limit), labels...)
+	}
+}
+
+func (m *ContainerMetrics) labelValues(c *container.Container) []string {
+	values := []string{strings.TrimPrefix(c.Name, "/"), c.Config.Image}
+	for _, k := range m.labelKeys {
+		values = append(values, c.Config.Labels[k])
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/container"
+	metrics "github.com/docker/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestContainerMetricsCollect(t *testing.T) {
+	ns := metrics.NewNamespace("engine", "container", nil)
+	m := NewContainerMetrics(NewCollector(nil, time.Second), ns, []string{"com.example.team", "com.example-team"})
+
+	c := &container.Container{
+		ID:     "abc",
+		Name:   "/web",
+		Config: &containertypes.Config{Image: "nginx", Labels: map[string]string{"com.example.team": "infra"}},
+	}
+	stats := types.StatsJSON{Networks: map[string]types.NetworkStats{"eth0": {RxBytes: 10, TxBytes: 20}}}
+	stats.Read = time.Now()
+	stats.CPUStats.CPUUsage.TotalUsage = 2e9
+	stats.MemoryStats.Usage = 1024
+	stats.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{{Op: "Read", Value: 5}, {Op: "Write", Value: 7}, {Op: "Read", Value: 1}}
+	stats.PidsStats.Current = 3
+	m.containers[c.ID] = &containerMetrics{container: c, stats: stats}
+	// containers without stats are not exported
+	m.containers["def"] = &containerMetrics{container: c}
+
+	ch := make(chan prometheus.Metric, 100)
+	m.Collect(ch)
+	close(ch)
+
+	values := map[string]float64{}
+	for metric := range ch {
+		var pb dto.Metric
+		if err := metric.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		labels := map[string]string{}
+		for _, l := range pb.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["name"] != "web" || labels["image"] != "nginx" || labels["container_label_com_example_team"] != "infra" {
+			t.Fatalf("unexpected labels: %v", labels)
+		}
+
+		name := metric.Desc().String()
+		switch {
+		case pb.Counter != nil:
+			values[name] = pb.Counter.GetValue()
+		case pb.Gauge != nil:
+			values[name] = pb.Gauge.GetValue()
+		}
+	}
+
+	for desc, expected := range map[*prometheus.Desc]float64{
+		m.cpuUsage:    2,
+		m.memoryUsage: 1024,
+		m.blkioRead:   6,
+		m.blkioWrite:  7,
+		m.networkRx:   10,
+		m.networkTx:   20,
+		m.pidsCurrent: 3,
+	} {
+		if v, ok := values[desc.String()]; !ok || v != expected {
+			t.Fatalf("expected %v for %s, got %v", expected, desc, v)
+		}
+	}
+}
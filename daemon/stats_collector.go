@@ -6,6 +6,7 @@ import (
 
 	"github.com/docker/docker/daemon/stats"
 	"github.com/docker/docker/pkg/system"
+	metrics "github.com/docker/go-metrics"
 )
 
 // newStatsCollector returns a new statsCollector that collections
@@ -24,3 +25,12 @@ func (daemon *Daemon) newStatsCollector(interval time.Duration) *stats.Collector
 	go s.Run()
 	return s
 }
+
+// newContainerMetrics exports the stats of the running containers as
+// Prometheus metrics, labelled with the given container labels.
+func (daemon *Daemon) newContainerMetrics(labelKeys []string) *stats.ContainerMetrics {
+	ns := metrics.NewNamespace("engine", "container", nil)
+	m := stats.NewContainerMetrics(daemon.statsCollector, ns, labelKeys)
+	metrics.Register(ns)
+	return m
+}
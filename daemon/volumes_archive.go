@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/volume"
+	volumestore "github.com/docker/docker/volume/store"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// VolumeClone creates a volume holding a copy of the data of the source
+// volume. The copy is made natively by the volume driver when the new volume
+// uses the same driver as the source and the driver supports it, and by
+// copying the files of the source volume otherwise.
+// This is called directly from the Engine API
+func (daemon *Daemon) VolumeClone(source, name, driverName string, opts, labels map[string]string) (*types.Volume, error) {
+	src, err := daemon.volumes.Get(source)
+	if err != nil {
+		if volumestore.IsNotExist(err) {
+			return nil, volumeNotFound(source)
+		}
+		return nil, err
+	}
+	if name == "" {
+		name = stringid.GenerateNonCryptoID()
+	}
+	if driverName == "" {
+		driverName = src.DriverName()
+	}
+
+	if driverName == src.DriverName() {
+		v, err := daemon.volumes.Snapshot(src, name, opts, labels)
+		if err == nil {
+			daemon.LogVolumeEvent(v.Name(), "create", map[string]string{"driver": v.DriverName()})
+			apiV := volumeToAPIType(v)
+			apiV.Mountpoint = v.Path()
+			return apiV, nil
+		}
+		if err != volume.ErrSnapshotNotSupported {
+			return nil, err
+		}
+	}
+
+	// the volume is created only if it doesn't exist yet, so that it can be
+	// removed if the copy fails
+	v, err := daemon.volumes.CreateNew(name, driverName, opts, labels)
+	if err != nil {
+		return nil, err
+	}
+	if err := daemon.copyVolume(source, v.Name()); err != nil {
+		if rmErr := daemon.volumes.Remove(v); rmErr != nil {
+			logrus.WithError(rmErr).WithField("volume", v.Name()).Warn("error removing volume after failed clone")
+		}
+		return nil, errors.Wrapf(err, "error copying volume %s to %s", source, v.Name())
+	}
+	daemon.LogVolumeEvent(v.Name(), "create", map[string]string{"driver": v.DriverName()})
+	apiV := volumeToAPIType(v)
+	apiV.Mountpoint = v.Path()
+	return apiV, nil
+}
+
+// VolumeExport writes a tar archive of the content of the volume with the
+// given name to out.
+// This is called directly from the Engine API
+func (daemon *Daemon) VolumeExport(name string, out io.Writer) error {
+	path, release, err := daemon.mountVolume(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	data, err := archive.TarWithOptions(path, &archive.TarOptions{
+		Compression: archive.Uncompressed,
+		UIDMaps:     daemon.idMappings.UIDs(),
+		GIDMaps:     daemon.idMappings.GIDs(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error exporting volume %s", name)
+	}
+	defer data.Close()
+
+	if _, err := io.Copy(out, data); err != nil {
+		return errors.Wrapf(err, "error exporting volume %s", name)
+	}
+	return nil
+}
+
+// VolumeImport extracts the tar archive read from in to the volume with the
+// given name, creating the volume if it doesn't exist. A volume created by
+// the import is removed if the archive cannot be extracted.
+// This is called directly from the Engine API
+func (daemon *Daemon) VolumeImport(name, driverName string, opts, labels map[string]string, in io.Reader) (*types.Volume, error) {
+	var created bool
+	if name == "" {
+		created = true
+	} else if _, err := daemon.volumes.Get(name); err != nil {
+		if !volumestore.IsNotExist(err) {
+			return nil, err
+		}
+		created = true
+	}
+
+	apiV, err := daemon.VolumeCreate(name, driverName, opts, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	err = daemon.extractToVolume(apiV.Name, in)
+	if err != nil {
+		if created {
+			if rmErr := daemon.volumeRm(apiV.Name); rmErr != nil {
+				logrus.WithError(rmErr).WithField("volume", apiV.Name).Warn("error removing volume after failed import")
+			}
+		}
+		return nil, errors.Wrapf(err, "error importing volume %s", apiV.Name)
+	}
+	return apiV, nil
+}
+
+func (daemon *Daemon) extractToVolume(name string, in io.Reader) error {
+	path, release, err := daemon.mountVolume(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return chrootarchive.Untar(in, path, &archive.TarOptions{
+		UIDMaps: daemon.idMappings.UIDs(),
+		GIDMaps: daemon.idMappings.GIDs(),
+	})
+}
+
+// copyVolume copies the files of the source volume to the target volume.
+func (daemon *Daemon) copyVolume(source, target string) error {
+	srcPath, releaseSrc, err := daemon.mountVolume(source)
+	if err != nil {
+		return err
+	}
+	defer releaseSrc()
+
+	data, err := archive.TarWithOptions(srcPath, &archive.TarOptions{Compression: archive.Uncompressed})
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	dstPath, releaseDst, err := daemon.mountVolume(target)
+	if err != nil {
+		return err
+	}
+	defer releaseDst()
+
+	return chrootarchive.UntarUncompressed(data, dstPath, &archive.TarOptions{})
+}
+
+// mountVolume mounts the volume with the given name, and returns its path
+// along with a function to unmount it. The volume holds a reference, so that
+// it cannot be removed while it is mounted.
+func (daemon *Daemon) mountVolume(name string) (string, func(), error) {
+	v, err := daemon.volumes.Get(name)
+	if err != nil {
+		if volumestore.IsNotExist(err) {
+			return "", nil, volumeNotFound(name)
+		}
+		return "", nil, err
+	}
+
+	ref := stringid.GenerateNonCryptoID()
+	v, err = daemon.volumes.GetWithRef(v.Name(), v.DriverName(), ref)
+	if err != nil {
+		return "", nil, err
+	}
+	path, err := v.Mount(ref)
+	if err != nil {
+		daemon.volumes.Dereference(v, ref)
+		return "", nil, errors.Wrapf(err, "error mounting volume %s", name)
+	}
+
+	release := func() {
+		if err := v.Unmount(ref); err != nil {
+			logrus.WithError(err).WithField("volume", name).Warn("error unmounting volume")
+		}
+		daemon.volumes.Dereference(v, ref)
+	}
+	return path, release, nil
+}
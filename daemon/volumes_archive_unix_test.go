@@ -0,0 +1,169 @@
+// +build !windows,!solaris
+
+package daemon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/errdefs"
+	"github.com/docker/docker/daemon/events"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/docker/volume"
+	"github.com/docker/docker/volume/drivers"
+	"github.com/docker/docker/volume/local"
+	"github.com/docker/docker/volume/store"
+	volumetestutils "github.com/docker/docker/volume/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	// the files of volumes are copied with chrootarchive
+	reexec.Init()
+}
+
+// snapshotDriver is a fake driver which snapshots volumes natively.
+type snapshotDriver struct {
+	volume.Driver
+	snapshots []string
+}
+
+func (d *snapshotDriver) Snapshot(source volume.Volume, name string, opts map[string]string) (volume.Volume, error) {
+	d.snapshots = append(d.snapshots, source.Name()+":"+name)
+	return d.Create(name, opts)
+}
+
+// unmountableDriver is a fake driver whose volumes cannot be mounted.
+type unmountableDriver struct {
+	volume.Driver
+}
+
+func (d unmountableDriver) Create(name string, opts map[string]string) (volume.Volume, error) {
+	v, err := d.Driver.Create(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return unmountableVolume{v}, nil
+}
+
+func (d unmountableDriver) Get(name string) (volume.Volume, error) {
+	v, err := d.Driver.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return unmountableVolume{v}, nil
+}
+
+type unmountableVolume struct {
+	volume.Volume
+}
+
+func (unmountableVolume) Mount(_ string) (string, error) {
+	return "", errors.New("mount failed")
+}
+
+func newVolumeTestDaemon(t *testing.T) (*Daemon, func()) {
+	if os.Getuid() != 0 {
+		t.Skip("copying volumes requires root")
+	}
+	tmp, err := ioutil.TempDir("", "docker-volume-clone-")
+	require.NoError(t, err)
+
+	volStore, err := store.New(tmp)
+	require.NoError(t, err)
+	drv, err := local.New(tmp, idtools.IDPair{UID: 0, GID: 0})
+	require.NoError(t, err)
+
+	volumedrivers.Unregister(volume.DefaultDriverName)
+	volumedrivers.Register(drv, volume.DefaultDriverName)
+
+	daemon := &Daemon{
+		volumes:       volStore,
+		EventsService: events.New(),
+		idMappings:    &idtools.IDMappings{},
+	}
+	return daemon, func() {
+		volumedrivers.Unregister(volume.DefaultDriverName)
+		volStore.Shutdown()
+		os.RemoveAll(tmp)
+	}
+}
+
+func TestVolumeCloneCopiesFiles(t *testing.T) {
+	daemon, cleanup := newVolumeTestDaemon(t)
+	defer cleanup()
+
+	src, err := daemon.VolumeCreate("src", "", nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src.Mountpoint, "foo"), []byte("bar"), 0644))
+
+	dst, err := daemon.VolumeClone("src", "dst", "", nil, map[string]string{"a": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "dst", dst.Name)
+	assert.Equal(t, volume.DefaultDriverName, dst.Driver)
+	assert.Equal(t, map[string]string{"a": "b"}, dst.Labels)
+
+	data, err := ioutil.ReadFile(filepath.Join(dst.Mountpoint, "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(data))
+}
+
+func TestVolumeCloneSnapshot(t *testing.T) {
+	daemon, cleanup := newVolumeTestDaemon(t)
+	defer cleanup()
+
+	drv := &snapshotDriver{Driver: volumetestutils.NewFakeDriver("snapshot")}
+	volumedrivers.Register(drv, "snapshot")
+	defer volumedrivers.Unregister("snapshot")
+
+	_, err := daemon.VolumeCreate("src", "snapshot", nil, nil)
+	require.NoError(t, err)
+
+	dst, err := daemon.VolumeClone("src", "dst", "", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot", dst.Driver)
+	assert.Equal(t, []string{"src:dst"}, drv.snapshots)
+}
+
+func TestVolumeCloneNameConflict(t *testing.T) {
+	daemon, cleanup := newVolumeTestDaemon(t)
+	defer cleanup()
+
+	_, err := daemon.VolumeCreate("src", "", nil, nil)
+	require.NoError(t, err)
+	dst, err := daemon.VolumeCreate("dst", "", nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dst.Mountpoint, "foo"), []byte("bar"), 0644))
+
+	_, err = daemon.VolumeClone("src", "dst", "", nil, nil)
+	assert.True(t, errdefs.IsConflict(err), "expected a conflict error, got %v", err)
+
+	data, err := ioutil.ReadFile(filepath.Join(dst.Mountpoint, "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(data))
+}
+
+func TestVolumeCloneRemovesVolumeOnFailure(t *testing.T) {
+	daemon, cleanup := newVolumeTestDaemon(t)
+	defer cleanup()
+
+	volumedrivers.Register(unmountableDriver{volumetestutils.NewFakeDriver("unmountable")}, "unmountable")
+	defer volumedrivers.Unregister("unmountable")
+
+	_, err := daemon.VolumeCreate("src", "", nil, nil)
+	require.NoError(t, err)
+
+	_, err = daemon.VolumeClone("src", "dst", "unmountable", nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mount failed")
+
+	_, err = daemon.volumes.Get("dst")
+	assert.True(t, store.IsNotExist(err), "expected the volume to be removed, got %v", err)
+	_, err = daemon.volumes.Get("src")
+	assert.NoError(t, err)
+}
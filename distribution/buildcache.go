@@ -0,0 +1,171 @@
+package distribution
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/registry"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// BuildCacheBlob is a layer of a build cache pushed to a registry.
+type BuildCacheBlob struct {
+	distribution.Descriptor
+	// Open returns the content of the blob.
+	Open func() (io.ReadCloser, error)
+}
+
+// PushBuildCache pushes a build cache to a registry, as an image manifest
+// whose config is the build cache manifest, of type configMediaType, and
+// whose layers are the layers of the build cache.
+func PushBuildCache(ctx context.Context, ref reference.NamedTagged, configMediaType string, config []byte, blobs []BuildCacheBlob, c *Config) error {
+	return withV2Repository(ctx, ref, c, true, func(repo distribution.Repository) error {
+		bs := repo.Blobs(ctx)
+		builder := schema2.NewManifestBuilder(bs, configMediaType, config)
+		for _, blob := range blobs {
+			if err := pushBuildCacheBlob(ctx, bs, blob, c.ProgressOutput); err != nil {
+				return err
+			}
+			if err := builder.AppendReference(blob.Descriptor); err != nil {
+				return err
+			}
+		}
+
+		manifest, err := builder.Build(ctx)
+		if err != nil {
+			return err
+		}
+		manSvc, err := repo.Manifests(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = manSvc.Put(ctx, manifest, distribution.WithTag(ref.Tag()))
+		return err
+	})
+}
+
+func pushBuildCacheBlob(ctx context.Context, bs distribution.BlobStore, blob BuildCacheBlob, progressOutput progress.Output) error {
+	id := stringid.TruncateID(blob.Digest.String())
+	if _, err := bs.Stat(ctx, blob.Digest); err == nil {
+		progress.Update(progressOutput, id, "Layer already exists")
+		return nil
+	} else if err != distribution.ErrBlobUnknown {
+		return err
+	}
+
+	progress.Update(progressOutput, id, "Pushing")
+	rc, err := blob.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	bw, err := bs.Create(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(bw, rc); err != nil {
+		bw.Cancel(ctx)
+		return err
+	}
+	if _, err := bw.Commit(ctx, blob.Descriptor); err != nil {
+		return err
+	}
+	progress.Update(progressOutput, id, "Pushed")
+	return nil
+}
+
+// PullBuildCache returns the build cache manifest pushed to a registry with
+// PushBuildCache, and the blob store its layers can be read from.
+func PullBuildCache(ctx context.Context, ref reference.NamedTagged, configMediaType string, c *Config) ([]byte, distribution.BlobProvider, error) {
+	var (
+		config []byte
+		bs     distribution.BlobStore
+	)
+	err := withV2Repository(ctx, ref, c, false, func(repo distribution.Repository) error {
+		manSvc, err := repo.Manifests(ctx)
+		if err != nil {
+			return err
+		}
+		manifest, err := manSvc.Get(ctx, "", distribution.WithTag(ref.Tag()))
+		if err != nil {
+			return err
+		}
+		m, ok := manifest.(*schema2.DeserializedManifest)
+		if !ok || m.Config.MediaType != configMediaType {
+			return errors.Errorf("%s is not a build cache", reference.FamiliarString(ref))
+		}
+
+		bs = repo.Blobs(ctx)
+		config, err = bs.Get(ctx, m.Config.Digest)
+		if err != nil {
+			return err
+		}
+		if m.Config.Digest != digest.FromBytes(config) {
+			return errors.Errorf("build cache manifest %s failed digest verification", m.Config.Digest)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, bs, nil
+}
+
+// withV2Repository calls fn with the repository of ref, trying the v2
+// endpoints of its registry in turn until fn succeeds on one of them, or
+// fails with an error the next endpoints would fail with too.
+func withV2Repository(ctx context.Context, ref reference.Named, c *Config, push bool, fn func(distribution.Repository) error) error {
+	repoInfo, err := c.RegistryService.ResolveRepository(ref)
+	if err != nil {
+		return err
+	}
+	var (
+		endpoints []registry.APIEndpoint
+		actions   = []string{"pull"}
+	)
+	if push {
+		endpoints, err = c.RegistryService.LookupPushEndpoints(reference.Domain(repoInfo.Name))
+		actions = append(actions, "push")
+	} else {
+		endpoints, err = c.RegistryService.LookupPullEndpoints(repoInfo.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		if endpoint.Version == registry.APIVersion1 {
+			continue
+		}
+		repo, _, err := NewV2Repository(ctx, repoInfo, endpoint, c.MetaHeaders, c.AuthConfig, actions...)
+		if err == nil {
+			err = fn(repo)
+		}
+		if err == nil {
+			return nil
+		}
+		if fallbackErr, ok := err.(fallbackError); ok {
+			err = fallbackErr.err
+		} else if !continueOnError(err) {
+			return err
+		}
+		// a mirror may not have the build cache, or not be reachable, so
+		// the next endpoints are tried as for the pulls of images
+		lastErr = err
+		logrus.Infof("Attempting next endpoint for build cache after error: %v", lastErr)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no v2 endpoint found for %s", repoInfo.Name.Name())
+	}
+	return lastErr
+}
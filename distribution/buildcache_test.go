@@ -0,0 +1,83 @@
+package distribution
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// endpointsService is a registry service with fixed pull endpoints
+type endpointsService struct {
+	registry.Service
+	endpoints []registry.APIEndpoint
+}
+
+func (s *endpointsService) ResolveRepository(name reference.Named) (*registry.RepositoryInfo, error) {
+	return &registry.RepositoryInfo{Name: name, Index: &registrytypes.IndexInfo{Name: reference.Domain(name)}}, nil
+}
+
+func (s *endpointsService) LookupPullEndpoints(name reference.Named) ([]registry.APIEndpoint, error) {
+	return s.endpoints, nil
+}
+
+// manifestRequests serves a registry without any manifest, recording the
+// manifests requested from it.
+type manifestRequests struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (m *manifestRequests) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.URL.Path, "/manifests/") {
+		m.mu.Lock()
+		m.requests = append(m.requests, r.URL.Path)
+		m.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+		return
+	}
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+}
+
+func TestPullBuildCacheTriesNextEndpoint(t *testing.T) {
+	mirror := &manifestRequests{}
+	mirrorServer := httptest.NewServer(mirror)
+	defer mirrorServer.Close()
+	upstream := &manifestRequests{}
+	upstreamServer := httptest.NewServer(upstream)
+	defer upstreamServer.Close()
+
+	var endpoints []registry.APIEndpoint
+	for _, server := range []*httptest.Server{mirrorServer, upstreamServer} {
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		endpoints = append(endpoints, registry.APIEndpoint{URL: u, Version: registry.APIVersion2, TrimHostname: true, Mirror: server == mirrorServer})
+	}
+
+	named, err := reference.ParseNormalizedNamed("example.com/app")
+	require.NoError(t, err)
+	ref, err := reference.WithTag(named, "buildcache")
+	require.NoError(t, err)
+
+	_, _, err = PullBuildCache(context.Background(), ref, "application/vnd.docker.buildcache.v1+json", &Config{
+		AuthConfig:      &types.AuthConfig{},
+		ProgressOutput:  progress.DiscardOutput(),
+		RegistryService: &endpointsService{endpoints: endpoints},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"/v2/app/manifests/buildcache"}, mirror.requests)
+	assert.Equal(t, []string{"/v2/app/manifests/buildcache"}, upstream.requests)
+}
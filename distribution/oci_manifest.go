@@ -0,0 +1,37 @@
+package distribution
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// OCI image manifests have the same structure as schema2 manifests, with
+// other media types, so they are handled as schema2 manifests. They are only
+// pushed for the layers compressed with zstd, which have no schema2 media
+// type.
+func init() {
+	ociFunc := func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+		m := new(schema2.DeserializedManifest)
+		if err := m.UnmarshalJSON(b); err != nil {
+			return nil, distribution.Descriptor{}, err
+		}
+		// the media type is optional in OCI manifests
+		m.MediaType = ocispec.MediaTypeImageManifest
+		return m, distribution.Descriptor{Digest: digest.FromBytes(b), Size: int64(len(b)), MediaType: ocispec.MediaTypeImageManifest}, nil
+	}
+	if err := distribution.RegisterManifestSchema(ocispec.MediaTypeImageManifest, ociFunc); err != nil {
+		panic(fmt.Sprintf("Unable to register manifest: %s", err))
+	}
+}
+
+// ociManifestFromSchema2 returns the OCI image manifest of the same image as
+// the schema2 manifest m, whose config has the OCI media type.
+func ociManifestFromSchema2(m *schema2.DeserializedManifest) (*schema2.DeserializedManifest, error) {
+	oci := m.Manifest
+	oci.MediaType = ocispec.MediaTypeImageManifest
+	return schema2.FromStruct(oci)
+}
@@ -62,7 +62,7 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 		return err
 	}
 
-	endpoints, err := imagePullConfig.RegistryService.LookupPullEndpoints(reference.Domain(repoInfo.Name))
+	endpoints, err := imagePullConfig.RegistryService.LookupPullEndpoints(repoInfo.Name)
 	if err != nil {
 		return err
 	}
@@ -126,6 +126,12 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 					if fallbackErr.transportOK && endpoint.URL.Scheme == "https" {
 						confirmedTLSRegistries[endpoint.URL.Host] = struct{}{}
 					}
+					// a mirror which can't be reached is skipped by the next pulls
+					if fallbackErr.transportOK {
+						imagePullConfig.RegistryService.ReportMirrorStatus(endpoint, nil)
+					} else {
+						imagePullConfig.RegistryService.ReportMirrorStatus(endpoint, fallbackErr.err)
+					}
 					err = fallbackErr.err
 				}
 			}
@@ -148,6 +154,7 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 			return TranslatePullError(err, ref)
 		}
 
+		imagePullConfig.RegistryService.ReportMirrorStatus(endpoint, nil)
 		imagePullConfig.ImageEventLogger(reference.FamiliarString(ref), reference.FamiliarName(repoInfo.Name), "pull")
 		return nil
 	}
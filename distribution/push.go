@@ -2,12 +2,12 @@ package distribution
 
 import (
 	"bufio"
-	"compress/gzip"
 	"fmt"
 	"io"
 
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/registry"
 	"github.com/sirupsen/logrus"
@@ -158,18 +158,20 @@ func Push(ctx context.Context, ref reference.Named, imagePushConfig *ImagePushCo
 // is finished. This allows the caller to make sure the goroutine finishes
 // before it releases any resources connected with the reader that was
 // passed in.
-func compress(in io.Reader) (io.ReadCloser, chan struct{}) {
+func compress(in io.Reader, options archive.CompressionOptions) (io.ReadCloser, chan struct{}) {
 	compressionDone := make(chan struct{})
 
 	pipeReader, pipeWriter := io.Pipe()
 	// Use a bufio.Writer to avoid excessive chunking in HTTP request.
 	bufWriter := bufio.NewWriterSize(pipeWriter, compressionBufSize)
-	compressor := gzip.NewWriter(bufWriter)
 
 	go func() {
-		_, err := io.Copy(compressor, in)
+		compressor, err := archive.CompressStreamWithOptions(bufWriter, options)
 		if err == nil {
-			err = compressor.Close()
+			_, err = io.Copy(compressor, in)
+			if cerr := compressor.Close(); err == nil {
+				err = cerr
+			}
 		}
 		if err == nil {
 			err = bufWriter.Flush()
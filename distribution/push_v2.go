@@ -20,17 +20,23 @@ import (
 	"github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/registry"
 	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	smallLayerMaximumSize  = 100 * (1 << 10) // 100KB
 	middleLayerMaximumSize = 10 * (1 << 20)  // 10MB
+
+	// mediaTypeLayerZstd is the media type of the layers compressed with
+	// zstd, as defined by the OCI image specification.
+	mediaTypeLayerZstd = "application/vnd.oci.image.layer.v1.tar+zstd"
 )
 
 type v2Pusher struct {
@@ -136,6 +142,11 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 
 	var descriptors []xfer.UploadDescriptor
 
+	compression := archive.CompressionOptions{Compression: archive.Gzip}
+	if p.config.LayerCompression != nil {
+		compression = *p.config.LayerCompression
+	}
+
 	descriptorTemplate := v2PushDescriptor{
 		v2MetadataService: p.v2MetadataService,
 		hmacKey:           hmacKey,
@@ -144,6 +155,7 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		endpoint:          p.endpoint,
 		repo:              p.repo,
 		pushState:         &p.pushState,
+		compression:       compression,
 	}
 
 	// Loop bounds condition is to avoid pushing the base layer on Windows.
@@ -160,9 +172,19 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		return err
 	}
 
-	// Try schema2 first
-	builder := schema2.NewManifestBuilder(p.repo.Blobs(ctx), p.config.ConfigMediaType, imgConfig)
-	manifest, err := manifestFromBuilder(ctx, builder, descriptors)
+	// Try schema2 first, or OCI for the zstd compressed layers which schema2
+	// manifests can't describe
+	var manifest distribution.Manifest
+	if compression.Compression == archive.Zstd {
+		builder := schema2.NewManifestBuilder(p.repo.Blobs(ctx), ocispec.MediaTypeImageConfig, imgConfig)
+		manifest, err = manifestFromBuilder(ctx, builder, descriptors)
+		if err == nil {
+			manifest, err = ociManifestFromSchema2(manifest.(*schema2.DeserializedManifest))
+		}
+	} else {
+		builder := schema2.NewManifestBuilder(p.repo.Blobs(ctx), p.config.ConfigMediaType, imgConfig)
+		manifest, err = manifestFromBuilder(ctx, builder, descriptors)
+	}
 	if err != nil {
 		return err
 	}
@@ -174,7 +196,8 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 
 	putOptions := []distribution.ManifestServiceOption{distribution.WithTag(ref.Tag())}
 	if _, err = manSvc.Put(ctx, manifest, putOptions...); err != nil {
-		if runtime.GOOS == "windows" || p.config.TrustKey == nil || p.config.RequireSchema2 {
+		// schema1 manifests can only describe gzip compressed layers
+		if runtime.GOOS == "windows" || p.config.TrustKey == nil || p.config.RequireSchema2 || compression.Compression != archive.Gzip {
 			logrus.Warnf("failed to upload schema2 manifest: %v", err)
 			return err
 		}
@@ -185,7 +208,7 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		if err != nil {
 			return err
 		}
-		builder = schema1.NewConfigManifestBuilder(p.repo.Blobs(ctx), p.config.TrustKey, manifestRef, imgConfig)
+		builder := schema1.NewConfigManifestBuilder(p.repo.Blobs(ctx), p.config.TrustKey, manifestRef, imgConfig)
 		manifest, err = manifestFromBuilder(ctx, builder, descriptors)
 		if err != nil {
 			return err
@@ -244,12 +267,25 @@ type v2PushDescriptor struct {
 	repo              distribution.Repository
 	pushState         *pushState
 	remoteDescriptor  distribution.Descriptor
+	// compression is used for the layers stored uncompressed
+	compression archive.CompressionOptions
 	// a set of digests whose presence has been checked in a target repository
 	checkedDigests map[digest.Digest]struct{}
 }
 
 func (pd *v2PushDescriptor) Key() string {
-	return "v2push:" + pd.ref.Name() + " " + pd.layer.DiffID().String()
+	return "v2push:" + pd.ref.Name() + " " + pd.layer.DiffID().String() + " " + pd.mediaType()
+}
+
+// mediaType returns the media type of the layer in the registry.
+func (pd *v2PushDescriptor) mediaType() string {
+	if m := pd.layer.MediaType(); m != schema2.MediaTypeUncompressedLayer {
+		return m
+	}
+	if pd.compression.Compression == archive.Zstd {
+		return mediaTypeLayerZstd
+	}
+	return schema2.MediaTypeLayer
 }
 
 func (pd *v2PushDescriptor) ID() string {
@@ -288,6 +324,9 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 	// Do we have any metadata associated with this layer's DiffID?
 	v2Metadata, err := pd.v2MetadataService.GetMetadata(diffID)
 	if err == nil {
+		// only the blobs compressed with the same algorithm can be reused
+		v2Metadata = filterV2MetadataByMediaType(v2Metadata, pd.mediaType())
+
 		// check for blob existence in the target repository
 		descriptor, exists, err := pd.layerAlreadyExists(ctx, progressOutput, diffID, true, 1, v2Metadata)
 		if exists || err != nil {
@@ -340,7 +379,7 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 		case distribution.ErrBlobMounted:
 			progress.Updatef(progressOutput, pd.ID(), "Mounted from %s", err.From.Name())
 
-			err.Descriptor.MediaType = schema2.MediaTypeLayer
+			err.Descriptor.MediaType = pd.mediaType()
 
 			pd.pushState.Lock()
 			pd.pushState.confirmedV2 = true
@@ -348,10 +387,7 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 			pd.pushState.Unlock()
 
 			// Cache mapping from this layer's DiffID to the blobsum
-			if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
-				Digest:           err.Descriptor.Digest,
-				SourceRepository: pd.repoInfo.Name(),
-			}); err != nil {
+			if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, newV2Metadata(err.Descriptor.Digest, pd.repoInfo.Name(), pd.mediaType())); err != nil {
 				return distribution.Descriptor{}, xfer.DoNotRetry{Err: err}
 			}
 			return err.Descriptor, nil
@@ -425,7 +461,7 @@ func (pd *v2PushDescriptor) uploadUsingSession(
 
 	switch m := pd.layer.MediaType(); m {
 	case schema2.MediaTypeUncompressedLayer:
-		compressedReader, compressionDone := compress(reader)
+		compressedReader, compressionDone := compress(reader, pd.compression)
 		defer func(closer io.Closer) {
 			closer.Close()
 			<-compressionDone
@@ -455,16 +491,13 @@ func (pd *v2PushDescriptor) uploadUsingSession(
 	progress.Update(progressOutput, pd.ID(), "Pushed")
 
 	// Cache mapping from this layer's DiffID to the blobsum
-	if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
-		Digest:           pushDigest,
-		SourceRepository: pd.repoInfo.Name(),
-	}); err != nil {
+	if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, newV2Metadata(pushDigest, pd.repoInfo.Name(), pd.mediaType())); err != nil {
 		return distribution.Descriptor{}, xfer.DoNotRetry{Err: err}
 	}
 
 	desc := distribution.Descriptor{
 		Digest:    pushDigest,
-		MediaType: schema2.MediaTypeLayer,
+		MediaType: pd.mediaType(),
 		Size:      nn,
 	}
 
@@ -530,14 +563,11 @@ attempts:
 		case nil:
 			if m, ok := digestToMetadata[desc.Digest]; !ok || m.SourceRepository != pd.repoInfo.Name() || !metadata.CheckV2MetadataHMAC(m, pd.hmacKey) {
 				// cache mapping from this layer's DiffID to the blobsum
-				if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
-					Digest:           desc.Digest,
-					SourceRepository: pd.repoInfo.Name(),
-				}); err != nil {
+				if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, newV2Metadata(desc.Digest, pd.repoInfo.Name(), pd.mediaType())); err != nil {
 					return distribution.Descriptor{}, false, xfer.DoNotRetry{Err: err}
 				}
 			}
-			desc.MediaType = schema2.MediaTypeLayer
+			desc.MediaType = pd.mediaType()
 			exists = true
 			break attempts
 		case distribution.ErrBlobUnknown:
@@ -560,6 +590,41 @@ attempts:
 	return desc, exists, nil
 }
 
+// newV2Metadata returns the v2 metadata of a blob of the given media type.
+// Gzip compressed layers are recorded without a media type, like the entries
+// added before the media type was recorded.
+func newV2Metadata(dgst digest.Digest, sourceRepository, mediaType string) metadata.V2Metadata {
+	meta := metadata.V2Metadata{
+		Digest:           dgst,
+		SourceRepository: sourceRepository,
+	}
+	switch mediaType {
+	case schema2.MediaTypeLayer, schema2.MediaTypeForeignLayer:
+	default:
+		meta.MediaType = mediaType
+	}
+	return meta
+}
+
+// filterV2MetadataByMediaType returns the v2 metadata entries of the blobs
+// of the given media type.
+func filterV2MetadataByMediaType(v2Metadata []metadata.V2Metadata, mediaType string) []metadata.V2Metadata {
+	if mediaType == schema2.MediaTypeForeignLayer {
+		mediaType = schema2.MediaTypeLayer
+	}
+	filtered := []metadata.V2Metadata{}
+	for _, meta := range v2Metadata {
+		m := meta.MediaType
+		if m == "" {
+			m = schema2.MediaTypeLayer
+		}
+		if m == mediaType {
+			filtered = append(filtered, meta)
+		}
+	}
+	return filtered
+}
+
 // getMaxMountAndExistenceCheckAttempts returns a maximum number of cross repository mount attempts from
 // source repositories of target registry, maximum number of layer existence checks performed on the target
 // repository and whether the check shall be done also with digests mapped to different repositories. The
@@ -11,8 +11,10 @@ import (
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 func TestGetRepositoryMountCandidates(t *testing.T) {
@@ -144,6 +146,69 @@ func TestGetRepositoryMountCandidates(t *testing.T) {
 	}
 }
 
+func TestPushDescriptorMediaType(t *testing.T) {
+	pd := &v2PushDescriptor{layer: &storeLayer{Layer: layer.EmptyLayer}}
+	if m := pd.mediaType(); m != schema2.MediaTypeLayer {
+		t.Errorf("unexpected media type for gzip: %s", m)
+	}
+	pd.compression = archive.CompressionOptions{Compression: archive.Zstd}
+	if m := pd.mediaType(); m != mediaTypeLayerZstd {
+		t.Errorf("unexpected media type for zstd: %s", m)
+	}
+}
+
+func TestOCIManifestFromSchema2(t *testing.T) {
+	m, err := schema2.FromStruct(schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: ocispec.MediaTypeImageConfig, Digest: digest.Digest("sha256:config"), Size: 2},
+		Layers:    []distribution.Descriptor{{MediaType: mediaTypeLayerZstd, Digest: digest.Digest("sha256:layer"), Size: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oci, err := ociManifestFromSchema2(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, payload, err := oci.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != ocispec.MediaTypeImageManifest {
+		t.Errorf("unexpected media type: %s", mediaType)
+	}
+
+	// the OCI manifests are read back as schema2 manifests when pulled
+	pulled, desc, err := distribution.UnmarshalManifest(mediaType, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.MediaType != ocispec.MediaTypeImageManifest || desc.Digest != digest.FromBytes(payload) {
+		t.Errorf("unexpected descriptor: %#+v", desc)
+	}
+	if !reflect.DeepEqual(pulled.References(), oci.References()) {
+		t.Errorf("unexpected references: %#+v", pulled.References())
+	}
+}
+
+func TestFilterV2MetadataByMediaType(t *testing.T) {
+	legacy := metadata.V2Metadata{Digest: digest.Digest("legacy")}
+	gzipped := newV2Metadata(digest.Digest("gzip"), "docker.io/library/busybox", schema2.MediaTypeLayer)
+	foreign := newV2Metadata(digest.Digest("foreign"), "docker.io/library/busybox", schema2.MediaTypeForeignLayer)
+	zstd := newV2Metadata(digest.Digest("zstd"), "docker.io/library/busybox", mediaTypeLayerZstd)
+	all := []metadata.V2Metadata{legacy, gzipped, foreign, zstd}
+
+	if gzipped.MediaType != "" || foreign.MediaType != "" {
+		t.Errorf("gzip compressed layers must be recorded without media type")
+	}
+	if filtered := filterV2MetadataByMediaType(all, schema2.MediaTypeLayer); !reflect.DeepEqual(filtered, []metadata.V2Metadata{legacy, gzipped, foreign}) {
+		t.Errorf("unexpected metadata for gzip: %#+v", filtered)
+	}
+	if filtered := filterV2MetadataByMediaType(all, mediaTypeLayerZstd); !reflect.DeepEqual(filtered, []metadata.V2Metadata{zstd}) {
+		t.Errorf("unexpected metadata for zstd: %#+v", filtered)
+	}
+}
+
 func TestLayerAlreadyExists(t *testing.T) {
 	for _, tc := range []struct {
 		name                   string
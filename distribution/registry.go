@@ -60,6 +60,9 @@ func NewV2Repository(ctx context.Context, repoInfo *registry.RepositoryInfo, end
 	if endpoint.TrimHostname {
 		repoName = reference.Path(repoInfo.Name)
 	}
+	if endpoint.RemoteName != "" {
+		repoName = endpoint.RemoteName
+	}
 
 	direct := &net.Dialer{
 		Timeout:   30 * time.Second,
@@ -0,0 +1,243 @@
+package cache
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ManifestSchemaVersion is the version of the build cache manifest format.
+	ManifestSchemaVersion = 1
+
+	// MediaTypeManifest is the media type of a build cache manifest, when
+	// it is pushed to a registry as the config of an image manifest.
+	MediaTypeManifest = "application/vnd.docker.buildcache.v1+json"
+)
+
+// Manifest describes a build cache that can be exported from a daemon and
+// imported into another one. It records the images created by the build
+// instructions, keyed by the image the instructions ran on and their
+// container config, with the layers they added.
+type Manifest struct {
+	SchemaVersion int      `json:"schemaVersion"`
+	Records       []Record `json:"records"`
+}
+
+// Record is an entry of a build cache manifest.
+type Record struct {
+	// Parent is the ID of the image the instruction ran on. It is empty if
+	// the instruction followed `FROM scratch`.
+	Parent image.ID `json:"parent,omitempty"`
+	// Config is the config of the image created by the instruction. Its
+	// container config is the one matched against the instructions of
+	// later builds.
+	Config json.RawMessage `json:"config"`
+	// Layer is the layer added by the instruction, if any.
+	Layer *Layer `json:"layer,omitempty"`
+}
+
+// Layer is a layer of a build cache. Its content is stored as a gzipped
+// tarball.
+type Layer struct {
+	DiffID layer.DiffID  `json:"diffID"`
+	Digest digest.Digest `json:"digest"`
+	Size   int64         `json:"size"`
+}
+
+// Records returns the records of the images in the parent chain of top, up
+// to but excluding base. If base is empty, or is not in the parent chain of
+// top, the records of the whole chain are returned.
+func Records(store image.Store, top, base image.ID) ([]Record, error) {
+	var records []Record
+	for id := top; id != "" && id != base; {
+		img, err := store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		parentID, err := store.GetParent(id)
+		if err != nil {
+			// the first image of the chain, pulled or loaded
+			break
+		}
+		var parentLayers []layer.DiffID
+		if parentID != "" {
+			parent, err := store.Get(parentID)
+			if err != nil {
+				return nil, err
+			}
+			parentLayers = parent.RootFS.DiffIDs
+		}
+
+		record := Record{Parent: parentID, Config: img.RawJSON()}
+		switch len(img.RootFS.DiffIDs) - len(parentLayers) {
+		case 0:
+		case 1:
+			record.Layer = &Layer{DiffID: img.RootFS.DiffIDs[len(parentLayers)]}
+		default:
+			// not created by a build instruction
+			return records, nil
+		}
+		records = append(records, record)
+		id = parentID
+	}
+	return records, nil
+}
+
+// BlobOpener returns the content of the blob with the given digest.
+type BlobOpener func(dgst digest.Digest) (io.ReadCloser, error)
+
+// ImportedCache is an image cache matching the records of an imported build
+// cache manifest. The images and layers of the records are only restored
+// when they are matched.
+type ImportedCache struct {
+	store    image.Store
+	ls       layer.Store
+	platform layer.Platform
+	openBlob BlobOpener
+
+	mu       sync.Mutex
+	byParent map[image.ID][]importedRecord
+}
+
+type importedRecord struct {
+	Record
+	img *image.Image
+}
+
+// NewImported returns an image cache for the records of a build cache
+// manifest, reading their layers with openBlob.
+func NewImported(store image.Store, ls layer.Store, platform string, m *Manifest, openBlob BlobOpener) (*ImportedCache, error) {
+	if m.SchemaVersion != ManifestSchemaVersion {
+		return nil, errors.Errorf("unsupported build cache manifest version %d", m.SchemaVersion)
+	}
+	ic := &ImportedCache{
+		store:    store,
+		ls:       ls,
+		platform: layer.Platform(platform),
+		openBlob: openBlob,
+		byParent: make(map[image.ID][]importedRecord),
+	}
+	for _, r := range m.Records {
+		img, err := image.NewFromJSON(r.Config)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid build cache record")
+		}
+		ic.byParent[r.Parent] = append(ic.byParent[r.Parent], importedRecord{Record: r, img: img})
+	}
+	return ic, nil
+}
+
+// GetCache returns the ID of the image created by the most recent record
+// matching the instruction, restoring it if needed. Records that cannot be
+// restored are treated as cache misses.
+func (ic *ImportedCache) GetCache(parentID string, config *containertypes.Config) (string, error) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	var match *importedRecord
+	for i, r := range ic.byParent[image.ID(parentID)] {
+		if compare(&r.img.ContainerConfig, config) {
+			if match == nil || match.img.Created.Before(r.img.Created) {
+				match = &ic.byParent[image.ID(parentID)][i]
+			}
+		}
+	}
+	if match == nil {
+		return "", nil
+	}
+
+	id, err := ic.restore(image.ID(parentID), match)
+	if err != nil {
+		logrus.Warnf("failed to restore image from build cache: %v", err)
+		return "", nil
+	}
+	return id.String(), nil
+}
+
+func (ic *ImportedCache) restore(parentID image.ID, r *importedRecord) (image.ID, error) {
+	id := image.IDFromDigest(digest.FromBytes(r.Config))
+	if _, err := ic.store.Get(id); err != nil {
+		parentRootFS := image.NewRootFS()
+		if parentID != "" {
+			parent, err := ic.store.Get(parentID)
+			if err != nil {
+				return "", err
+			}
+			parentRootFS = parent.RootFS
+		}
+
+		// the record must only add its layer to the layers of its parent
+		expected := append([]layer.DiffID{}, parentRootFS.DiffIDs...)
+		if r.Layer != nil {
+			expected = append(expected, r.Layer.DiffID)
+		}
+		if layer.CreateChainID(expected) != r.img.RootFS.ChainID() {
+			return "", errors.Errorf("layers of cached image %s do not match its parent %s", id, parentID)
+		}
+
+		if r.Layer != nil {
+			l, err := ic.ls.Get(r.img.RootFS.ChainID())
+			if err != nil {
+				l, err = ic.registerLayer(r.Layer, parentRootFS.ChainID())
+				if err != nil {
+					return "", err
+				}
+			}
+			defer layer.ReleaseAndLog(ic.ls, l)
+		}
+
+		if _, err := ic.store.Create(r.Config); err != nil {
+			return "", errors.Wrap(err, "failed to create cached image")
+		}
+	}
+
+	if parentID != "" {
+		if err := ic.store.SetParent(id, parentID); err != nil {
+			return "", errors.Wrapf(err, "failed to set parent for %v to %v", id, parentID)
+		}
+	}
+	return id, nil
+}
+
+func (ic *ImportedCache) registerLayer(l *Layer, parent layer.ChainID) (layer.Layer, error) {
+	blob, err := ic.openBlob(l.Digest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open cached layer %s", l.Digest)
+	}
+	defer blob.Close()
+
+	verifier := l.Digest.Verifier()
+	verified := io.TeeReader(blob, verifier)
+	rc, err := archive.DecompressStream(verified)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	newLayer, err := ic.ls.Register(rc, parent, ic.platform)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to register cached layer %s", l.Digest)
+	}
+	// read the end of the blob, so that all of it is verified
+	io.Copy(ioutil.Discard, rc)
+	io.Copy(ioutil.Discard, verified)
+	if !verifier.Verified() {
+		layer.ReleaseAndLog(ic.ls, newLayer)
+		return nil, errors.Errorf("cached layer %s failed digest verification", l.Digest)
+	}
+	if newLayer.DiffID() != l.DiffID {
+		layer.ReleaseAndLog(ic.ls, newLayer)
+		return nil, errors.Errorf("cached layer %s has diff ID %s, expected %s", l.Digest, newLayer.DiffID(), l.DiffID)
+	}
+	return newLayer, nil
+}
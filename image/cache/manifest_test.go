@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLayerGetReleaser struct{}
+
+func (ls *mockLayerGetReleaser) Get(layer.ChainID) (layer.Layer, error) {
+	return nil, nil
+}
+
+func (ls *mockLayerGetReleaser) Release(layer.Layer) ([]layer.Metadata, error) {
+	return nil, nil
+}
+
+func newTestImageStore(t *testing.T) (image.Store, func()) {
+	root, err := ioutil.TempDir("", "buildcache-")
+	require.NoError(t, err)
+	fs, err := image.NewFSStoreBackend(root)
+	require.NoError(t, err)
+	store, err := image.NewImageStore(fs, runtime.GOOS, &mockLayerGetReleaser{})
+	require.NoError(t, err)
+	return store, func() { os.RemoveAll(root) }
+}
+
+const (
+	baseConfig  = `{"architecture":"amd64","os":"linux","rootfs":{"type":"layers"}}`
+	childConfig = `{"architecture":"amd64","os":"linux","created":"2017-08-01T00:00:00Z","container_config":{"Cmd":["/bin/sh","-c","#(nop) ","ENV A=1"]},"rootfs":{"type":"layers"}}`
+)
+
+func TestManifestRecordsAndImport(t *testing.T) {
+	src, cleanup := newTestImageStore(t)
+	defer cleanup()
+	baseID, err := src.Create([]byte(baseConfig))
+	require.NoError(t, err)
+	childID, err := src.Create([]byte(childConfig))
+	require.NoError(t, err)
+	require.NoError(t, src.SetParent(childID, baseID))
+
+	records, err := Records(src, childID, baseID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, baseID, records[0].Parent)
+	assert.Nil(t, records[0].Layer)
+
+	dest, cleanup := newTestImageStore(t)
+	defer cleanup()
+	_, err = dest.Create([]byte(baseConfig))
+	require.NoError(t, err)
+
+	ic, err := NewImported(dest, nil, runtime.GOOS, &Manifest{SchemaVersion: ManifestSchemaVersion, Records: records}, nil)
+	require.NoError(t, err)
+
+	id, err := ic.GetCache(baseID.String(), &container.Config{Cmd: strslice.StrSlice{"/bin/sh", "-c", "#(nop) ", "ENV A=2"}})
+	require.NoError(t, err)
+	assert.Equal(t, "", id)
+
+	id, err = ic.GetCache(baseID.String(), &container.Config{Cmd: strslice.StrSlice{"/bin/sh", "-c", "#(nop) ", "ENV A=1"}})
+	require.NoError(t, err)
+	assert.Equal(t, childID.String(), id)
+	parentID, err := dest.GetParent(childID)
+	require.NoError(t, err)
+	assert.Equal(t, baseID, parentID)
+}
+
+func TestNewImportedUnsupportedVersion(t *testing.T) {
+	_, err := NewImported(nil, nil, runtime.GOOS, &Manifest{SchemaVersion: 2}, nil)
+	assert.EqualError(t, err, "unsupported build cache manifest version 2")
+}
@@ -132,6 +132,9 @@ type ChildConfig struct {
 	DiffID          layer.DiffID
 	ContainerConfig *container.Config
 	Config          *container.Config
+	// Created is the creation time of the image, for reproducible images. It
+	// defaults to the current time.
+	Created time.Time
 }
 
 // NewChildImage creates a new Image as a child of this image.
@@ -152,6 +155,9 @@ func NewChildImage(img *Image, child ChildConfig, platform string) *Image {
 		child.Comment,
 		strings.Join(child.ContainerConfig.Cmd, " "),
 		isEmptyLayer)
+	if !child.Created.IsZero() {
+		imgHistory.Created = child.Created.UTC()
+	}
 
 	return &Image{
 		V1Image: V1Image{
@@ -203,7 +209,9 @@ func NewHistory(author, comment, createdBy string, isEmptyLayer bool) History {
 type Exporter interface {
 	Load(io.ReadCloser, io.Writer, bool) error
 	// TODO: Load(net.Context, io.ReadCloser, <- chan StatusMessage) error id:170 gh:171
-	Save([]string, io.Writer) error
+	// Save writes the images to the output stream in the format given, one
+	// of the ImageSaveFormat constants of api/types.
+	Save(names []string, format string, outStream io.Writer) error
 }
 
 // NewFromJSON creates an Image configuration from json.
@@ -3,6 +3,7 @@ package image
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +26,8 @@ type Store interface {
 	GetParent(id ID) (ID, error)
 	SetLastUpdated(id ID) error
 	GetLastUpdated(id ID) (time.Time, error)
+	SetAnnotations(id ID, annotations map[string]string) error
+	GetAnnotations(id ID) (map[string]string, error)
 	Children(id ID) []ID
 	Map() map[ID]*Image
 	Heads() map[ID]*Image
@@ -286,6 +289,43 @@ func (is *store) GetLastUpdated(id ID) (time.Time, error) {
 	return time.Parse(time.RFC3339Nano, string(bytes))
 }
 
+// SetAnnotations sets the OCI annotations of the manifest of the image ID,
+// such as the ones of an image loaded from an OCI image layout
+func (is *store) SetAnnotations(id ID, annotations map[string]string) error {
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return err
+	}
+	return is.fs.SetMetadata(id.Digest(), "annotations", data)
+}
+
+// GetAnnotations returns the OCI annotations of the manifest of the image ID
+func (is *store) GetAnnotations(id ID) (map[string]string, error) {
+	is.RLock()
+	known := is.images[id] != nil
+	is.RUnlock()
+	if !known {
+		return nil, fmt.Errorf("unrecognized image ID %s", id.String())
+	}
+
+	data, err := is.fs.GetMetadata(id.Digest(), "annotations")
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			// No annotations
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error reading annotations of image %s", id)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var annotations map[string]string
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, errors.Wrapf(err, "error decoding annotations of image %s", id)
+	}
+	return annotations, nil
+}
+
 func (is *store) Children(id ID) []ID {
 	is.RLock()
 	defer is.RUnlock()
@@ -2,6 +2,7 @@ package image
 
 import (
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/docker/docker/internal/testutil"
@@ -167,6 +168,42 @@ func TestGetAndSetLastUpdated(t *testing.T) {
 	assert.Equal(t, updated.IsZero(), false)
 }
 
+func TestGetAndSetAnnotations(t *testing.T) {
+	store, cleanup := defaultImageStore(t)
+	defer cleanup()
+
+	id, err := store.Create([]byte(`{"comment": "abc1", "rootfs": {"type": "layers"}}`))
+	assert.NoError(t, err)
+
+	annotations, err := store.GetAnnotations(id)
+	assert.NoError(t, err)
+	assert.Nil(t, annotations)
+
+	expected := map[string]string{"org.opencontainers.image.vendor": "vendor"}
+	assert.NoError(t, store.SetAnnotations(id, expected))
+
+	annotations, err = store.GetAnnotations(id)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, annotations)
+
+	_, err = store.GetAnnotations(ID("sha256:" + strings.Repeat("f", 64)))
+	testutil.ErrorContains(t, err, "unrecognized image ID")
+}
+
+func TestGetCorruptedAnnotations(t *testing.T) {
+	fsBackend, cleanup := defaultFSStoreBackend(t)
+	defer cleanup()
+	store, err := NewImageStore(fsBackend, runtime.GOOS, &mockLayerGetReleaser{})
+	assert.NoError(t, err)
+
+	id, err := store.Create([]byte(`{"comment": "abc1", "rootfs": {"type": "layers"}}`))
+	assert.NoError(t, err)
+	assert.NoError(t, fsBackend.SetMetadata(id.Digest(), "annotations", []byte("invalid")))
+
+	_, err = store.GetAnnotations(id)
+	testutil.ErrorContains(t, err, "error decoding annotations of image")
+}
+
 type mockLayerGetReleaser struct{}
 
 func (ls *mockLayerGetReleaser) Get(layer.ChainID) (layer.Layer, error) {
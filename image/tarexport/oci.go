@@ -0,0 +1,442 @@
+package tarexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/system"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// saveOCI writes the images as an OCI image layout. The configuration of an
+// image is saved as is, so that its ID is preserved, and its layers are saved
+// uncompressed, with their DiffID as digest.
+func (s *saveSession) saveOCI(outStream io.Writer) error {
+	tempDir, err := ioutil.TempDir("", "docker-export-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	s.outDir = tempDir
+	if err := os.MkdirAll(filepath.Join(tempDir, ociBlobsDir, string(digest.Canonical)), 0755); err != nil {
+		return err
+	}
+	layerDescs := make(map[layer.DiffID]ocispec.Descriptor)
+
+	ids := make([]image.ID, 0, len(s.images))
+	for id := range s.images {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	index := ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: []ocispec.Descriptor{},
+	}
+	for _, id := range ids {
+		desc, err := s.saveOCIImage(id, layerDescs)
+		if err != nil {
+			return err
+		}
+
+		refs := s.images[id].refs
+		if len(refs) == 0 {
+			index.Manifests = append(index.Manifests, desc)
+		}
+		for _, ref := range refs {
+			tagged := desc
+			tagged.Annotations = map[string]string{
+				annotationImageName:       ref.String(),
+				ocispec.AnnotationRefName: ref.Tag(),
+			}
+			index.Manifests = append(index.Manifests, tagged)
+		}
+		s.tarexporter.loggerImgEvent.LogImageEvent(id.String(), id.String(), "save")
+	}
+
+	layout, err := json.Marshal(ocispec.ImageLayout{Version: ocispec.ImageLayoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := writeOCIFile(filepath.Join(tempDir, ocispec.ImageLayoutFile), layout); err != nil {
+		return err
+	}
+	indexJSON, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := writeOCIFile(filepath.Join(tempDir, ociIndexFileName), indexJSON); err != nil {
+		return err
+	}
+
+	fs, err := archive.Tar(tempDir, archive.Uncompressed)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	_, err = io.Copy(outStream, fs)
+	return err
+}
+
+// saveOCIImage writes the blobs of an image, and returns the descriptor of
+// its manifest
+func (s *saveSession) saveOCIImage(id image.ID, layerDescs map[layer.DiffID]ocispec.Descriptor) (ocispec.Descriptor, error) {
+	img := s.images[id].image
+	if len(img.RootFS.DiffIDs) == 0 {
+		return ocispec.Descriptor{}, fmt.Errorf("empty export - not implemented")
+	}
+
+	config, err := s.writeOCIBlob(ocispec.MediaTypeImageConfig, img.RawJSON())
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    config,
+	}
+
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for _, diffID := range img.RootFS.DiffIDs {
+		rootFS.Append(diffID)
+		desc, ok := layerDescs[diffID]
+		if !ok {
+			desc, err = s.saveOCILayer(rootFS.ChainID())
+			if err != nil {
+				return ocispec.Descriptor{}, err
+			}
+			layerDescs[diffID] = desc
+		}
+		manifest.Layers = append(manifest.Layers, desc)
+	}
+
+	annotations, err := s.is.GetAnnotations(id)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	manifest.Annotations = make(map[string]string)
+	for k, v := range annotations {
+		manifest.Annotations[k] = v
+	}
+	if _, ok := manifest.Annotations[ocispec.AnnotationCreated]; !ok && !img.Created.IsZero() {
+		manifest.Annotations[ocispec.AnnotationCreated] = img.Created.UTC().Format(time.RFC3339)
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc, err := s.writeOCIBlob(ocispec.MediaTypeImageManifest, manifestJSON)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc.Platform = &ocispec.Platform{
+		Architecture: img.Architecture,
+		OS:           img.OS,
+		OSVersion:    img.OSVersion,
+		OSFeatures:   img.OSFeatures,
+	}
+	return desc, nil
+}
+
+func (s *saveSession) saveOCILayer(id layer.ChainID) (ocispec.Descriptor, error) {
+	l, err := s.ls.Get(id)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer layer.ReleaseAndLog(s.ls, l)
+
+	arch, err := l.TarStream()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer arch.Close()
+
+	// Use system.CreateSequential rather than os.Create. This ensures sequential
+	// file access on Windows to avoid eating into MM standby list.
+	// On Linux, this equates to a regular os.Create.
+	tmpPath := filepath.Join(s.outDir, ociBlobsDir, "layer.tmp")
+	tarFile, err := system.CreateSequential(tmpPath)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	digester := digest.Canonical.Digester()
+	size, err := io.Copy(io.MultiWriter(tarFile, digester.Hash()), arch)
+	tarFile.Close()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digester.Digest(),
+		Size:      size,
+	}
+	blobPath := s.ociBlobPath(desc.Digest)
+	if err := os.Rename(tmpPath, blobPath); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := system.Chtimes(blobPath, time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+func (s *saveSession) writeOCIBlob(mediaType string, data []byte) (ocispec.Descriptor, error) {
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+	return desc, writeOCIFile(s.ociBlobPath(desc.Digest), data)
+}
+
+func (s *saveSession) ociBlobPath(dgst digest.Digest) string {
+	return filepath.Join(s.outDir, ociBlobsDir, string(dgst.Algorithm()), dgst.Hex())
+}
+
+// writeOCIFile writes a file of the layout, with a fixed modification time so
+// that saving the same images twice produces the same archive
+func writeOCIFile(path string, data []byte) error {
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	return system.Chtimes(path, time.Unix(0, 0), time.Unix(0, 0))
+}
+
+// loadOCI loads the images of the OCI image layout extracted in tmpDir. The
+// images are tagged with the reference in the annotations of their manifest
+// in the index, and the annotations of the manifest are kept in the image
+// store, to be saved again.
+func (l *tarexporter) loadOCI(tmpDir string, outStream io.Writer, progressOutput progress.Output) error {
+	var layout ocispec.ImageLayout
+	if err := readOCIJSON(tmpDir, ocispec.ImageLayoutFile, &layout); err != nil {
+		return err
+	}
+	if layout.Version != ocispec.ImageLayoutVersion {
+		return errors.Errorf("unsupported OCI image layout version: %s", layout.Version)
+	}
+	var index ocispec.Index
+	if err := readOCIJSON(tmpDir, ociIndexFileName, &index); err != nil {
+		return err
+	}
+
+	var imageIDsStr string
+	var imageRefCount int
+	loaded := make(map[digest.Digest]image.ID)
+
+	for _, desc := range index.Manifests {
+		manifestDesc, err := ociManifestDescriptor(tmpDir, desc)
+		if err != nil {
+			return err
+		}
+		imgID, ok := loaded[manifestDesc.Digest]
+		if !ok {
+			imgID, err = l.loadOCIImage(tmpDir, manifestDesc, progressOutput)
+			if err != nil {
+				return err
+			}
+			loaded[manifestDesc.Digest] = imgID
+			imageIDsStr += fmt.Sprintf("Loaded image ID: %s\n", imgID)
+			l.loggerImgEvent.LogImageEvent(imgID.String(), imgID.String(), "load")
+		}
+
+		ref := ociReference(desc.Annotations)
+		if ref == nil {
+			continue
+		}
+		l.setLoadedTag(ref, imgID.Digest(), outStream)
+		outStream.Write([]byte(fmt.Sprintf("Loaded image: %s\n", reference.FamiliarString(ref))))
+		imageRefCount++
+	}
+
+	if imageRefCount == 0 {
+		outStream.Write([]byte(imageIDsStr))
+	}
+	return nil
+}
+
+// ociManifestDescriptor returns the descriptor of the image manifest to load
+// for an entry of the index of an OCI image layout. An entry pointing to an
+// image index, such as the one of a multi-platform image, is resolved to the
+// manifest for the platform of the daemon. Only one level of nested indexes
+// is supported.
+func ociManifestDescriptor(tmpDir string, desc ocispec.Descriptor) (ocispec.Descriptor, error) {
+	if !isOCIIndex(desc.MediaType) {
+		return desc, nil
+	}
+	indexJSON, err := readOCIBlob(tmpDir, desc.Digest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var index ocispec.Index
+	if err := json.Unmarshal(indexJSON, &index); err != nil {
+		return ocispec.Descriptor{}, errors.Wrapf(err, "error decoding image index %s", desc.Digest)
+	}
+
+	var nested bool
+	for _, m := range index.Manifests {
+		if isOCIIndex(m.MediaType) {
+			nested = true
+			continue
+		}
+		if m.Platform != nil && (checkCompatibleOS(m.Platform.OS) != nil || m.Platform.Architecture != runtime.GOARCH) {
+			continue
+		}
+		return m, nil
+	}
+	if nested {
+		return ocispec.Descriptor{}, errors.Errorf("image index %s holds nested image indexes, which are not supported", desc.Digest)
+	}
+	return ocispec.Descriptor{}, errors.Errorf("no manifest for %s/%s in image index %s", runtime.GOOS, runtime.GOARCH, desc.Digest)
+}
+
+func isOCIIndex(mediaType string) bool {
+	return mediaType == ocispec.MediaTypeImageIndex || mediaType == manifestlist.MediaTypeManifestList
+}
+
+func (l *tarexporter) loadOCIImage(tmpDir string, desc ocispec.Descriptor, progressOutput progress.Output) (image.ID, error) {
+	if desc.MediaType != ocispec.MediaTypeImageManifest && desc.MediaType != schema2.MediaTypeManifest {
+		return "", errors.Errorf("unsupported manifest media type in OCI image layout: %s", desc.MediaType)
+	}
+	manifestJSON, err := readOCIBlob(tmpDir, desc.Digest)
+	if err != nil {
+		return "", err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return "", err
+	}
+
+	config, err := readOCIBlob(tmpDir, manifest.Config.Digest)
+	if err != nil {
+		return "", err
+	}
+	img, err := image.NewFromJSON(config)
+	if err != nil {
+		return "", err
+	}
+	if err := checkCompatibleOS(img.OS); err != nil {
+		return "", err
+	}
+	if expected, actual := len(manifest.Layers), len(img.RootFS.DiffIDs); expected != actual {
+		return "", fmt.Errorf("invalid manifest, layers length mismatch: expected %d, got %d", expected, actual)
+	}
+	platform, err := layerPlatform(img)
+	if err != nil {
+		return "", err
+	}
+
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for i, diffID := range img.RootFS.DiffIDs {
+		r := rootFS
+		r.Append(diffID)
+		newLayer, err := l.ls.Get(r.ChainID())
+		if err != nil {
+			layerPath, err := ociBlobPath(tmpDir, manifest.Layers[i].Digest)
+			if err != nil {
+				return "", err
+			}
+			newLayer, err = l.loadLayer(layerPath, rootFS, diffID.String(), platform, distribution.Descriptor{}, progressOutput)
+			if err != nil {
+				return "", err
+			}
+		}
+		defer layer.ReleaseAndLog(l.ls, newLayer)
+		if expected, actual := diffID, newLayer.DiffID(); expected != actual {
+			return "", fmt.Errorf("invalid diffID for layer %d: expected %q, got %q", i, expected, actual)
+		}
+		rootFS.Append(diffID)
+	}
+
+	imgID, err := l.is.Create(config)
+	if err != nil {
+		return "", err
+	}
+	if len(manifest.Annotations) > 0 {
+		if err := l.is.SetAnnotations(imgID, manifest.Annotations); err != nil {
+			return "", err
+		}
+	}
+	return imgID, nil
+}
+
+// ociReference returns the reference of an image from the annotations of its
+// manifest in the index of an OCI image layout, or nil if the ref.name
+// annotation only holds a tag without the name of the image.
+func ociReference(annotations map[string]string) reference.NamedTagged {
+	name := annotations[annotationImageName]
+	if name == "" {
+		name = annotations[ocispec.AnnotationRefName]
+	}
+	if name == "" {
+		return nil
+	}
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		logrus.Debugf("Ignoring invalid reference %q in OCI image layout: %v", name, err)
+		return nil
+	}
+	tagged, ok := named.(reference.NamedTagged)
+	if !ok {
+		return nil
+	}
+	return tagged
+}
+
+func readOCIJSON(tmpDir, name string, v interface{}) error {
+	path, err := safePath(tmpDir, name)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func ociBlobPath(tmpDir string, dgst digest.Digest) (string, error) {
+	if err := dgst.Validate(); err != nil {
+		return "", err
+	}
+	return safePath(tmpDir, filepath.Join(ociBlobsDir, string(dgst.Algorithm()), dgst.Hex()))
+}
+
+// readOCIBlob reads a blob of an OCI image layout, and verifies its digest
+func readOCIBlob(tmpDir string, dgst digest.Digest) ([]byte, error) {
+	path, err := ociBlobPath(tmpDir, dgst)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if actual := dgst.Algorithm().FromBytes(data); actual != dgst {
+		return nil, errors.Errorf("invalid digest for blob %s: got %s", dgst, actual)
+	}
+	return data, nil
+}
@@ -0,0 +1,124 @@
+package tarexport
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/internal/testutil"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOCIReference(t *testing.T) {
+	testcases := []struct {
+		annotations map[string]string
+		expected    string
+	}{
+		{
+			annotations: map[string]string{
+				annotationImageName:       "docker.io/library/busybox:1.27",
+				ocispec.AnnotationRefName: "1.27",
+			},
+			expected: "docker.io/library/busybox:1.27",
+		},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "example.com/app:v1"},
+			expected:    "example.com/app:v1",
+		},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "v1"},
+		},
+		{
+			annotations: map[string]string{ocispec.AnnotationRefName: "Invalid:Reference"},
+		},
+		{},
+	}
+	for _, tc := range testcases {
+		ref := ociReference(tc.annotations)
+		if tc.expected == "" {
+			assert.Nil(t, ref, "%v", tc.annotations)
+			continue
+		}
+		require.NotNil(t, ref, "%v", tc.annotations)
+		assert.Equal(t, tc.expected, ref.String())
+	}
+}
+
+func TestReadOCIBlob(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "oci-layout-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	blobsDir := filepath.Join(tmpDir, ociBlobsDir, string(digest.Canonical))
+	require.NoError(t, os.MkdirAll(blobsDir, 0755))
+
+	content := []byte(`{"schemaVersion": 2}`)
+	dgst := digest.FromBytes(content)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(blobsDir, dgst.Hex()), content, 0644))
+
+	data, err := readOCIBlob(tmpDir, dgst)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	other := digest.FromString("other")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(blobsDir, other.Hex()), content, 0644))
+	_, err = readOCIBlob(tmpDir, other)
+	testutil.ErrorContains(t, err, "invalid digest for blob")
+
+	_, err = readOCIBlob(tmpDir, digest.Digest("sha256:../../oci-layout"))
+	assert.Error(t, err)
+}
+
+func TestOCIManifestDescriptor(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "oci-layout-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	blobsDir := filepath.Join(tmpDir, ociBlobsDir, string(digest.Canonical))
+	require.NoError(t, os.MkdirAll(blobsDir, 0755))
+	writeIndex := func(manifests ...ocispec.Descriptor) ocispec.Descriptor {
+		data, err := json.Marshal(ocispec.Index{Manifests: manifests})
+		require.NoError(t, err)
+		dgst := digest.FromBytes(data)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(blobsDir, dgst.Hex()), data, 0644))
+		return ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex, Digest: dgst, Size: int64(len(data))}
+	}
+
+	manifest := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.FromString("manifest")}
+	native := manifest
+	native.Platform = &ocispec.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}
+	other := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("other"),
+		Platform:  &ocispec.Platform{OS: runtime.GOOS, Architecture: "unknown"},
+	}
+	nested := writeIndex(native)
+
+	testcases := []struct {
+		desc     ocispec.Descriptor
+		expected digest.Digest
+		err      string
+	}{
+		{desc: manifest, expected: manifest.Digest},
+		{desc: writeIndex(other, native), expected: manifest.Digest},
+		{desc: writeIndex(manifest), expected: manifest.Digest},
+		{desc: writeIndex(other), err: "no manifest for"},
+		{desc: writeIndex(nested), err: "nested image indexes, which are not supported"},
+		{desc: writeIndex(other, nested), err: "nested image indexes, which are not supported"},
+	}
+	for _, tc := range testcases {
+		desc, err := ociManifestDescriptor(tmpDir, tc.desc)
+		if tc.err != "" {
+			testutil.ErrorContains(t, err, tc.err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, desc.Digest)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/image/v1"
 	"github.com/docker/docker/layer"
@@ -35,7 +36,10 @@ type saveSession struct {
 	diffIDPaths map[layer.DiffID]string // cache every diffID blob to avoid duplicates
 }
 
-func (l *tarexporter) Save(names []string, outStream io.Writer) error {
+func (l *tarexporter) Save(names []string, format string, outStream io.Writer) error {
+	if format != "" && format != types.ImageSaveFormatDocker && format != types.ImageSaveFormatOCI {
+		return errors.Errorf("unsupported image archive format: %s", format)
+	}
 	images, err := l.parseNames(names)
 	if err != nil {
 		return err
@@ -43,7 +47,11 @@ func (l *tarexporter) Save(names []string, outStream io.Writer) error {
 
 	// Release all the image top layer references
 	defer l.releaseLayerReferences(images)
-	return (&saveSession{tarexporter: l, images: images}).save(outStream)
+	s := &saveSession{tarexporter: l, images: images}
+	if format == types.ImageSaveFormatOCI {
+		return s.saveOCI(outStream)
+	}
+	return s.save(outStream)
 }
 
 // parseNames will parse the image names to a map which contains image.ID to *imageDescriptor.
@@ -13,6 +13,13 @@ const (
 	legacyConfigFileName       = "json"
 	legacyVersionFileName      = "VERSION"
 	legacyRepositoriesFileName = "repositories"
+	ociIndexFileName           = "index.json"
+	ociBlobsDir                = "blobs"
+
+	// annotationImageName is the annotation holding the full reference of an
+	// image in the index of an OCI image layout, as the ref.name annotation
+	// may only hold its tag.
+	annotationImageName = "io.containerd.image.name"
 )
 
 type manifestItem struct {
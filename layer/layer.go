@@ -212,6 +212,12 @@ type DescribableStore interface {
 	RegisterWithDescriptor(io.Reader, ChainID, Platform, distribution.Descriptor) (Layer, error)
 }
 
+// SharedSizer represents a layer store capable of reporting the size of
+// the content shared between its layers.
+type SharedSizer interface {
+	SharedSize() (int64, error)
+}
+
 // MetadataTransaction represents functions for setting layer metadata
 // with a single transaction.
 type MetadataTransaction interface {
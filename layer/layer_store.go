@@ -722,6 +722,15 @@ func (ls *layerStore) Cleanup() error {
 	return ls.driver.Cleanup()
 }
 
+// SharedSize returns the number of bytes of file content shared between
+// the layers by the graph driver.
+func (ls *layerStore) SharedSize() (int64, error) {
+	if s, ok := ls.driver.(graphdriver.SharedSizer); ok {
+		return s.SharedSize()
+	}
+	return 0, nil
+}
+
 func (ls *layerStore) DriverStatus() [][2]string {
 	return ls.driver.Status()
 }
@@ -15,6 +15,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/idtools"
@@ -81,6 +82,8 @@ const (
 	Gzip
 	// Xz is xz compression algorithm.
 	Xz
+	// Zstd is zstd compression algorithm.
+	Zstd
 )
 
 const (
@@ -124,6 +127,7 @@ func DetectCompression(source []byte) Compression {
 		Bzip2: {0x42, 0x5A, 0x68},
 		Gzip:  {0x1F, 0x8B, 0x08},
 		Xz:    {0xFD, 0x37, 0x7A, 0x58, 0x5A, 0x00},
+		Zstd:  {0x28, 0xB5, 0x2F, 0xFD},
 	} {
 		if len(source) < len(m) {
 			logrus.Debug("Len too short")
@@ -142,6 +146,12 @@ func xzDecompress(archive io.Reader) (io.ReadCloser, <-chan struct{}, error) {
 	return cmdStream(exec.Command(args[0], args[1:]...), archive)
 }
 
+func zstdDecompress(archive io.Reader) (io.ReadCloser, <-chan struct{}, error) {
+	args := []string{"zstd", "-d", "-c", "-q"}
+
+	return cmdStream(exec.Command(args[0], args[1:]...), archive)
+}
+
 // DecompressStream decompresses the archive and returns a ReaderCloser with the decompressed archive.
 func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 	p := pools.BufioReader32KPool
@@ -183,29 +193,100 @@ func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 			<-chdone
 			return readBufWrapper.Close()
 		}), nil
+	case Zstd:
+		zstdReader, chdone, err := zstdDecompress(buf)
+		if err != nil {
+			return nil, err
+		}
+		readBufWrapper := p.NewReadCloserWrapper(buf, zstdReader)
+		return ioutils.NewReadCloserWrapper(readBufWrapper, func() error {
+			<-chdone
+			return readBufWrapper.Close()
+		}), nil
 	default:
 		return nil, fmt.Errorf("Unsupported compression format %s", (&compression).Extension())
 	}
 }
 
+// CompressionOptions holds the options of CompressStreamWithOptions.
+type CompressionOptions struct {
+	Compression Compression
+	// Level is the compression level, from 1 to 9 for gzip and from 1 to 19
+	// for zstd. The default level of the algorithm is used if 0.
+	Level int
+	// Parallel compresses gzip streams with pigz, on all the CPUs, when it
+	// is installed.
+	Parallel bool
+}
+
+// Validate checks that the compression algorithm supports the level.
+func (options CompressionOptions) Validate() error {
+	var maxLevel int
+	switch options.Compression {
+	case Uncompressed:
+	case Gzip:
+		maxLevel = gzip.BestCompression
+	case Zstd:
+		maxLevel = 19
+	default:
+		// archive/bzip2 does not support writing, and there is no xz support at all
+		// However, this is not a problem as docker only currently generates gzipped tars
+		return fmt.Errorf("Unsupported compression format %s", (&options.Compression).Extension())
+	}
+	if options.Level < 0 || options.Level > maxLevel {
+		return fmt.Errorf("invalid compression level %d for %s", options.Level, (&options.Compression).Extension())
+	}
+	return nil
+}
+
 // CompressStream compresses the dest with specified compression algorithm.
 func CompressStream(dest io.Writer, compression Compression) (io.WriteCloser, error) {
+	return CompressStreamWithOptions(dest, CompressionOptions{Compression: compression})
+}
+
+// CompressStreamWithOptions compresses the dest with the specified
+// compression algorithm and level.
+func CompressStreamWithOptions(dest io.Writer, options CompressionOptions) (io.WriteCloser, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
 	p := pools.BufioWriter32KPool
-	buf := p.Get(dest)
-	switch compression {
+	switch options.Compression {
 	case Uncompressed:
+		buf := p.Get(dest)
 		writeBufWrapper := p.NewWriteCloserWrapper(buf, buf)
 		return writeBufWrapper, nil
 	case Gzip:
-		gzWriter := gzip.NewWriter(dest)
+		if options.Parallel {
+			if pigz, err := exec.LookPath("pigz"); err == nil {
+				args := []string{"-c", "-q"}
+				if options.Level > 0 {
+					args = append(args, fmt.Sprintf("-%d", options.Level))
+				}
+				return cmdWriteStream(exec.Command(pigz, args...), dest)
+			}
+			logrus.Debug("pigz not found, compressing with a single CPU")
+		}
+		level := options.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		gzWriter, err := gzip.NewWriterLevel(dest, level)
+		if err != nil {
+			return nil, err
+		}
+		buf := p.Get(dest)
 		writeBufWrapper := p.NewWriteCloserWrapper(buf, gzWriter)
 		return writeBufWrapper, nil
-	case Bzip2, Xz:
-		// archive/bzip2 does not support writing, and there is no xz support at all
-		// However, this is not a problem as docker only currently generates gzipped tars
-		return nil, fmt.Errorf("Unsupported compression format %s", (&compression).Extension())
+	case Zstd:
+		args := []string{"zstd", "-c", "-q"}
+		if options.Level > 0 {
+			args = append(args, fmt.Sprintf("-%d", options.Level))
+		}
+		return cmdWriteStream(exec.Command(args[0], args[1:]...), dest)
 	default:
-		return nil, fmt.Errorf("Unsupported compression format %s", (&compression).Extension())
+		return nil, fmt.Errorf("Unsupported compression format %s", (&options.Compression).Extension())
 	}
 }
 
@@ -296,6 +377,53 @@ func ReplaceFileTarWrapper(inputTarStream io.ReadCloser, mods map[string]TarModi
 	return pipeReader
 }
 
+// ClampTimesTarWrapper converts inputTarStream to a new tar stream that does
+// not depend on when its files were written, for reproducible layers. The
+// modification times of the entries are truncated to the second and clamped
+// to epoch, and their access and change times are removed, as are the user and
+// group names, which depend on the host.
+func ClampTimesTarWrapper(inputTarStream io.ReadCloser, epoch time.Time) io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		tarReader := tar.NewReader(inputTarStream)
+		tarWriter := tar.NewWriter(pipeWriter)
+		defer inputTarStream.Close()
+		defer tarWriter.Close()
+
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+
+			header.ModTime = header.ModTime.Truncate(time.Second)
+			if header.ModTime.After(epoch) {
+				header.ModTime = epoch
+			}
+			header.AccessTime = time.Time{}
+			header.ChangeTime = time.Time{}
+			header.Uname = ""
+			header.Gname = ""
+			if err := tarWriter.WriteHeader(header); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			if _, err := pools.Copy(tarWriter, tarReader); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+		}
+
+		pipeWriter.Close()
+	}()
+	return pipeReader
+}
+
 // Extension returns the extension of a file that uses the specified compression algorithm.
 func (compression *Compression) Extension() string {
 	switch *compression {
@@ -307,6 +435,8 @@ func (compression *Compression) Extension() string {
 		return "tar.gz"
 	case Xz:
 		return "tar.xz"
+	case Zstd:
+		return "tar.zst"
 	}
 	return ""
 }
@@ -1183,6 +1313,31 @@ func cmdStream(cmd *exec.Cmd, input io.Reader) (io.ReadCloser, <-chan struct{},
 	return pipeR, chdone, nil
 }
 
+// cmdWriteStream executes a command, and writes its output to dest. The
+// returned WriteCloser is the input of the command, and closing it waits for
+// the command to exit.
+func cmdWriteStream(cmd *exec.Cmd, dest io.Writer) (io.WriteCloser, error) {
+	cmd.Stdout = dest
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return ioutils.NewWriteCloserWrapper(stdin, func() error {
+		stdin.Close()
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("%s: %s", err, errBuf.String())
+		}
+		return nil
+	}), nil
+}
+
 // NewTempArchive reads the content of src into a temporary file, and returns the contents
 // of that file as an archive. The archive can only be read once - as soon as reading completes,
 // the file will be deleted.
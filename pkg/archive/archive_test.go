@@ -133,6 +133,66 @@ func TestDecompressStreamXz(t *testing.T) {
 	testDecompressStream(t, "xz", "xz -f")
 }
 
+func TestDecompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	testDecompressStream(t, "zst", "zstd -f -q")
+}
+
+func testCompressStreamRoundTrip(t *testing.T, options CompressionOptions) {
+	var compressed bytes.Buffer
+	w, err := CompressStreamWithOptions(&compressed, options)
+	require.NoError(t, err)
+	content := bytes.Repeat([]byte("compressed content\n"), 1000)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, options.Compression, DetectCompression(compressed.Bytes()))
+	r, err := DecompressStream(&compressed)
+	require.NoError(t, err)
+	defer r.Close()
+	decompressed, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, content, decompressed)
+}
+
+func TestCompressStreamGzipLevel(t *testing.T) {
+	testCompressStreamRoundTrip(t, CompressionOptions{Compression: Gzip, Level: 1})
+	testCompressStreamRoundTrip(t, CompressionOptions{Compression: Gzip, Level: 9, Parallel: true})
+}
+
+func TestCompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	testCompressStreamRoundTrip(t, CompressionOptions{Compression: Zstd})
+	testCompressStreamRoundTrip(t, CompressionOptions{Compression: Zstd, Level: 19})
+}
+
+func TestCompressionOptionsValidate(t *testing.T) {
+	valid := []CompressionOptions{
+		{Compression: Uncompressed},
+		{Compression: Gzip},
+		{Compression: Gzip, Level: 9},
+		{Compression: Zstd, Level: 19},
+	}
+	for _, options := range valid {
+		assert.NoError(t, options.Validate(), "%+v", options)
+	}
+	invalid := []CompressionOptions{
+		{Compression: Uncompressed, Level: 1},
+		{Compression: Gzip, Level: 10},
+		{Compression: Gzip, Level: -1},
+		{Compression: Zstd, Level: 20},
+		{Compression: Xz},
+	}
+	for _, options := range invalid {
+		assert.Error(t, options.Validate(), "%+v", options)
+	}
+}
+
 func TestCompressStreamXzUnsupported(t *testing.T) {
 	dest, err := os.Create(tmp + "dest")
 	if err != nil {
@@ -209,6 +269,14 @@ func TestExtensionXz(t *testing.T) {
 	}
 }
 
+func TestExtensionZstd(t *testing.T) {
+	compression := Zstd
+	output := compression.Extension()
+	if output != "tar.zst" {
+		t.Fatalf("The extension of a zstd archive should be 'tar.zst'")
+	}
+}
+
 func TestCmdStreamLargeStderr(t *testing.T) {
 	cmd := exec.Command("sh", "-c", "dd if=/dev/zero bs=1k count=1000 of=/dev/stderr; echo hello")
 	out, _, err := cmdStream(cmd, nil)
@@ -1249,6 +1317,54 @@ func TestReplaceFileTarWrapper(t *testing.T) {
 	}
 }
 
+func TestClampTimesTarWrapper(t *testing.T) {
+	epoch := time.Unix(1500000000, 0)
+	times := map[string]time.Time{
+		"old": epoch.Add(-time.Hour).Add(500 * time.Millisecond),
+		"new": epoch.Add(time.Hour),
+	}
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, name := range []string{"old", "new"} {
+		hdr := &tar.Header{
+			Name:       name,
+			Mode:       0600,
+			Size:       int64(len(name)),
+			ModTime:    times[name],
+			AccessTime: times[name],
+			ChangeTime: times[name],
+			Uname:      "user",
+			Gname:      "group",
+			Typeflag:   tar.TypeReg,
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	tr := tar.NewReader(ClampTimesTarWrapper(ioutil.NopCloser(buf), epoch))
+	expected := map[string]time.Time{
+		"old": epoch.Add(-time.Hour),
+		"new": epoch,
+	}
+	for range expected {
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		assert.True(t, expected[hdr.Name].Equal(hdr.ModTime), hdr.Name)
+		assert.True(t, hdr.AccessTime.IsZero(), hdr.Name)
+		assert.True(t, hdr.ChangeTime.IsZero(), hdr.Name)
+		assert.Equal(t, "", hdr.Uname)
+		assert.Equal(t, "", hdr.Gname)
+		content, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		assert.Equal(t, hdr.Name, string(content))
+	}
+	_, err := tr.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
 // TestPrefixHeaderReadable tests that files that could be created with the
 // version of this package that was built with <=go17 are still readable.
 func TestPrefixHeaderReadable(t *testing.T) {
@@ -31,8 +31,12 @@ type Archiver struct {
 // TarUntar is a convenience function which calls Tar and Untar, with the output of one piped into the other.
 // If either Tar or Untar fails, TarUntar aborts and returns the error.
 func (archiver *Archiver) TarUntar(src, dst string) error {
+	return archiver.tarUntar(src, dst, nil)
+}
+
+func (archiver *Archiver) tarUntar(src, dst string, excludes []string) error {
 	logrus.Debugf("TarUntar(%s %s)", src, dst)
-	tarArchive, err := archiver.Tar(src, &archive.TarOptions{Compression: archive.Uncompressed})
+	tarArchive, err := archiver.Tar(src, &archive.TarOptions{Compression: archive.Uncompressed, ExcludePatterns: excludes})
 	if err != nil {
 		return err
 	}
@@ -58,11 +62,73 @@ func (archiver *Archiver) UntarPath(src, dst string) error {
 	return archiver.Untar(tarArchive, dst, options)
 }
 
+// UntarPathWithFilter works like UntarPath, but passes the header of each
+// entry of the archive to filter, which may change it, and leaves out the
+// entries for which filter returns false.
+func (archiver *Archiver) UntarPathWithFilter(src, dst string, filter func(*tar.Header) (bool, error)) error {
+	tarArchive, err := archiver.SrcDriver.Open(src)
+	if err != nil {
+		return err
+	}
+	defer tarArchive.Close()
+	decompressed, err := archive.DecompressStream(tarArchive)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(filterTar(decompressed, w, filter))
+	}()
+	defer r.Close()
+
+	options := &archive.TarOptions{
+		UIDMaps: archiver.IDMappingsVar.UIDs(),
+		GIDMaps: archiver.IDMappingsVar.GIDs(),
+	}
+	return archiver.Untar(r, dst, options)
+}
+
+func filterTar(src io.Reader, dst io.Writer, filter func(*tar.Header) (bool, error)) error {
+	tr := tar.NewReader(src)
+	tw := tar.NewWriter(dst)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		keep, err := filter(hdr)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
 // CopyWithTar creates a tar archive of filesystem path `src`, and
 // unpacks it at filesystem path `dst`.
 // The archive is streamed directly with fixed buffering and no
 // intermediary disk IO.
 func (archiver *Archiver) CopyWithTar(src, dst string) error {
+	return archiver.CopyWithTarExcluding(src, dst, nil)
+}
+
+// CopyWithTarExcluding works like CopyWithTar, but leaves out the files of
+// the directory src matching the patterns of excludes, in the syntax of the
+// .dockerignore file.
+func (archiver *Archiver) CopyWithTarExcluding(src, dst string, excludes []string) error {
 	srcSt, err := archiver.SrcDriver.Stat(src)
 	if err != nil {
 		return err
@@ -80,7 +146,7 @@ func (archiver *Archiver) CopyWithTar(src, dst string) error {
 		return err
 	}
 	logrus.Debugf("Calling TarUntar(%s, %s)", src, dst)
-	return archiver.TarUntar(src, dst)
+	return archiver.tarUntar(src, dst, excludes)
 }
 
 // CopyFileWithTar emulates the behavior of the 'cp' command-line
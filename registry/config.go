@@ -19,6 +19,12 @@ type ServiceOptions struct {
 	AllowNondistributableArtifacts []string `json:"allow-nondistributable-artifacts,omitempty"`
 	Mirrors                        []string `json:"registry-mirrors,omitempty"`
 	InsecureRegistries             []string `json:"insecure-registries,omitempty"`
+	// RegistryMirrors are the mirrors of registries other than the Docker
+	// Hub, as host=mirror pairs. The mirrors of a registry are tried in order.
+	RegistryMirrors []string `json:"registry-host-mirrors,omitempty"`
+	// RegistryRewrites are rewrite rules of repository names to pull from,
+	// as prefix=target pairs, such as gcr.io/foo=mirror.local/gcr/foo.
+	RegistryRewrites []string `json:"registry-rewrites,omitempty"`
 
 	// V2Only controls access to legacy registries.  If it is set to true via the
 	// command line flag the daemon will not attempt to contact v1 legacy registries
@@ -29,6 +35,18 @@ type ServiceOptions struct {
 type serviceConfig struct {
 	registrytypes.ServiceConfig
 	V2Only bool
+
+	// hostMirrors are the mirrors of the registries, by hostname
+	hostMirrors map[string][]string
+	rewrites    []registryRewrite
+}
+
+// registryRewrite is a rule to pull the repositories starting with prefix
+// from target instead. Both are repository names including the registry
+// hostname, or a registry hostname alone.
+type registryRewrite struct {
+	prefix string
+	target string
 }
 
 var (
@@ -64,7 +82,8 @@ var (
 )
 
 var (
-	validHostPortRegex = regexp.MustCompile(`^` + reference.DomainRegexp.String() + `$`)
+	validHostPortRegex       = regexp.MustCompile(`^` + reference.DomainRegexp.String() + `$`)
+	validRepositoryPathRegex = regexp.MustCompile(`^` + reference.NameRegexp.String() + `$`)
 )
 
 // for mocking in unit tests
@@ -87,6 +106,12 @@ func newServiceConfig(options ServiceOptions) (*serviceConfig, error) {
 	if err := config.LoadMirrors(options.Mirrors); err != nil {
 		return nil, err
 	}
+	if err := config.LoadRegistryMirrors(options.RegistryMirrors); err != nil {
+		return nil, err
+	}
+	if err := config.LoadRegistryRewrites(options.RegistryRewrites); err != nil {
+		return nil, err
+	}
 	if err := config.LoadInsecureRegistries(options.InsecureRegistries); err != nil {
 		return nil, err
 	}
@@ -161,6 +186,66 @@ func (config *serviceConfig) LoadMirrors(mirrors []string) error {
 	return nil
 }
 
+// LoadRegistryMirrors loads the mirrors of registries other than the Docker
+// Hub to config, after removing duplicates. Returns an error if one of them
+// is invalid.
+func (config *serviceConfig) LoadRegistryMirrors(mirrors []string) error {
+	hostMirrors := make(map[string][]string)
+	seen := make(map[string]struct{})
+
+	for _, m := range mirrors {
+		v, err := ValidateRegistryMirror(m)
+		if err != nil {
+			return err
+		}
+		if _, exist := seen[v]; exist {
+			continue
+		}
+		seen[v] = struct{}{}
+		parts := strings.SplitN(v, "=", 2)
+		hostMirrors[parts[0]] = append(hostMirrors[parts[0]], parts[1])
+	}
+
+	config.hostMirrors = hostMirrors
+	return nil
+}
+
+// LoadRegistryRewrites loads the rewrite rules of repository names to config.
+// Returns an error if one of them is invalid.
+func (config *serviceConfig) LoadRegistryRewrites(rewrites []string) error {
+	rules := make([]registryRewrite, 0, len(rewrites))
+
+	for _, r := range rewrites {
+		v, err := ValidateRegistryRewrite(r)
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(v, "=", 2)
+		rules = append(rules, registryRewrite{prefix: parts[0], target: parts[1]})
+	}
+
+	config.rewrites = rules
+	return nil
+}
+
+// rewrite returns the names of the repositories to pull name from instead,
+// in order, according to the rewrite rules matching it.
+func (config *serviceConfig) rewrite(name reference.Named) []reference.Named {
+	var names []reference.Named
+	for _, r := range config.rewrites {
+		if name.Name() != r.prefix && !strings.HasPrefix(name.Name(), r.prefix+"/") {
+			continue
+		}
+		rewritten, err := reference.ParseNormalizedNamed(r.target + strings.TrimPrefix(name.Name(), r.prefix))
+		if err != nil {
+			logrus.Warnf("ignoring invalid rewrite of %s to %s: %v", name.Name(), r.target, err)
+			continue
+		}
+		names = append(names, rewritten)
+	}
+	return names
+}
+
 // LoadInsecureRegistries loads insecure registries to config
 func (config *serviceConfig) LoadInsecureRegistries(registries []string) error {
 	// Localhost is by default considered as an insecure registry
@@ -336,6 +421,60 @@ func ValidateMirror(val string) (string, error) {
 	return strings.TrimSuffix(val, "/") + "/", nil
 }
 
+// ValidateRegistryMirror validates a mirror of a registry, of the form
+// host=mirror where mirror is an HTTP(S) URI
+func ValidateRegistryMirror(val string) (string, error) {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid registry mirror %q: must be of the form host=mirror", val)
+	}
+	host, err := ValidateIndexName(parts[0])
+	if err != nil {
+		return "", err
+	}
+	if err := validateHostPort(host); err != nil {
+		return "", fmt.Errorf("invalid registry mirror %q: %v", val, err)
+	}
+	mirror, err := ValidateMirror(parts[1])
+	if err != nil {
+		return "", err
+	}
+	return host + "=" + mirror, nil
+}
+
+// ValidateRegistryRewrite validates a rewrite rule of repository names, of
+// the form prefix=target where prefix and target are registry hostnames, or
+// repository names including the registry hostname, such as
+// gcr.io/foo=mirror.local/gcr/foo
+func ValidateRegistryRewrite(val string) (string, error) {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid registry rewrite %q: must be of the form prefix=target", val)
+	}
+	for i, name := range parts {
+		name = strings.TrimSuffix(name, "/")
+		if err := validateRewriteName(name); err != nil {
+			return "", fmt.Errorf("invalid registry rewrite %q: %v", val, err)
+		}
+		parts[i] = name
+	}
+	return parts[0] + "=" + parts[1], nil
+}
+
+func validateRewriteName(name string) error {
+	parts := strings.SplitN(name, "/", 2)
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return fmt.Errorf("%s does not start with a registry hostname", name)
+	}
+	if err := validateHostPort(parts[0]); err != nil {
+		return err
+	}
+	if len(parts) == 2 && !validRepositoryPathRegex.MatchString(parts[1]) {
+		return fmt.Errorf("%s is not a valid repository name", name)
+	}
+	return nil
+}
+
 // ValidateIndexName validates an index name.
 func ValidateIndexName(val string) (string, error) {
 	// TODO: upstream this to check to reference package id:237 gh:238
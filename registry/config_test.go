@@ -171,6 +171,59 @@ func TestValidateMirror(t *testing.T) {
 	}
 }
 
+func TestValidateRegistryMirror(t *testing.T) {
+	valid := map[string]string{
+		"quay.io=https://quay-cache.local":      "quay.io=https://quay-cache.local/",
+		"localhost:5000=http://mirror:5000/":    "localhost:5000=http://mirror:5000/",
+		"index.docker.io=https://mirror-1.com/": "docker.io=https://mirror-1.com/",
+	}
+	invalid := []string{
+		"quay.io",
+		"quay.io=",
+		"=https://quay-cache.local",
+		"-quay.io=https://quay-cache.local",
+		"quay.io=ftp://quay-cache.local",
+		"quay.io=https://quay-cache.local/v2/",
+	}
+
+	for val, expected := range valid {
+		ret, err := ValidateRegistryMirror(val)
+		assert.NoError(t, err, val)
+		assert.Equal(t, expected, ret)
+	}
+	for _, val := range invalid {
+		_, err := ValidateRegistryMirror(val)
+		assert.Error(t, err, val)
+	}
+}
+
+func TestValidateRegistryRewrite(t *testing.T) {
+	valid := map[string]string{
+		"gcr.io/foo=mirror.local/gcr/foo":    "gcr.io/foo=mirror.local/gcr/foo",
+		"gcr.io/=mirror.local:5000/gcr/":     "gcr.io=mirror.local:5000/gcr",
+		"quay.io=mirror.local":               "quay.io=mirror.local",
+		"docker.io/library=mirror.local/hub": "docker.io/library=mirror.local/hub",
+	}
+	invalid := []string{
+		"gcr.io/foo",
+		"gcr.io/foo=",
+		"gcr.io/foo=mirror.local/gcr/foo:latest",
+		"gcr.io/Foo=mirror.local/gcr/foo",
+		"foo/bar=mirror.local/foo/bar",
+		"gcr.io/foo=https://mirror.local/gcr/foo",
+	}
+
+	for val, expected := range valid {
+		ret, err := ValidateRegistryRewrite(val)
+		assert.NoError(t, err, val)
+		assert.Equal(t, expected, ret)
+	}
+	for _, val := range invalid {
+		_, err := ValidateRegistryRewrite(val)
+		assert.Error(t, err, val)
+	}
+}
+
 func TestLoadInsecureRegistries(t *testing.T) {
 	testCases := []struct {
 		registries []string
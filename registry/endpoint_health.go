@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// mirrorBackoffBase is how long a mirror is skipped after it failed once.
+	// The delay doubles on every further failure, up to mirrorBackoffMax.
+	mirrorBackoffBase = 5 * time.Second
+	mirrorBackoffMax  = 5 * time.Minute
+)
+
+// endpointHealth tracks the failures of the mirrors, so that a mirror which
+// can't be reached is skipped for a while, instead of being tried again and
+// timing out on every pull.
+type endpointHealth struct {
+	mu       sync.Mutex
+	failures map[string]*endpointFailures
+	now      func() time.Time
+}
+
+type endpointFailures struct {
+	count int
+	until time.Time
+}
+
+func newEndpointHealth() *endpointHealth {
+	return &endpointHealth{
+		failures: make(map[string]*endpointFailures),
+		now:      time.Now,
+	}
+}
+
+// healthy returns whether the endpoint at url can be tried
+func (h *endpointHealth) healthy(url string) bool {
+	if h == nil {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, ok := h.failures[url]
+	return !ok || !h.now().Before(f.until)
+}
+
+// report records the result of a request to the endpoint at url. A failure
+// backs the endpoint off exponentially, and a success resets it.
+func (h *endpointHealth) report(url string, err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err == nil {
+		delete(h.failures, url)
+		return
+	}
+	f, ok := h.failures[url]
+	if !ok {
+		f = &endpointFailures{}
+		h.failures[url] = f
+	}
+	backoff := mirrorBackoffMax
+	if f.count < 10 {
+		backoff = mirrorBackoffBase << uint(f.count)
+		if backoff > mirrorBackoffMax {
+			backoff = mirrorBackoffMax
+		}
+	}
+	f.count++
+	f.until = h.now().Add(backoff)
+}
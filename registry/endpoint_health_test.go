@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointHealth(t *testing.T) {
+	now := time.Now()
+	h := newEndpointHealth()
+	h.now = func() time.Time { return now }
+
+	const url = "https://mirror.local"
+	assert.True(t, h.healthy(url))
+
+	h.report(url, errors.New("connection refused"))
+	assert.False(t, h.healthy(url))
+	now = now.Add(mirrorBackoffBase)
+	assert.True(t, h.healthy(url))
+
+	// the backoff doubles on every failure
+	h.report(url, errors.New("connection refused"))
+	now = now.Add(mirrorBackoffBase)
+	assert.False(t, h.healthy(url))
+	now = now.Add(mirrorBackoffBase)
+	assert.True(t, h.healthy(url))
+
+	for i := 0; i < 20; i++ {
+		h.report(url, errors.New("connection refused"))
+	}
+	now = now.Add(mirrorBackoffMax)
+	assert.True(t, h.healthy(url))
+
+	h.report(url, errors.New("connection refused"))
+	h.report(url, nil)
+	assert.True(t, h.healthy(url))
+}
+
+func TestEndpointHealthNil(t *testing.T) {
+	var h *endpointHealth
+	h.report("https://mirror.local", errors.New("connection refused"))
+	assert.True(t, h.healthy("https://mirror.local"))
+}
@@ -3,6 +3,7 @@
 package registry
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
@@ -689,7 +690,7 @@ func TestMirrorEndpointLookup(t *testing.T) {
 		t.Fatal("Push endpoint should not contain mirror")
 	}
 
-	pullAPIEndpoints, err := s.LookupPullEndpoints(reference.Domain(imageName))
+	pullAPIEndpoints, err := s.LookupPullEndpoints(imageName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -698,6 +699,62 @@ func TestMirrorEndpointLookup(t *testing.T) {
 	}
 }
 
+func TestRewriteEndpointLookup(t *testing.T) {
+	cfg, err := newServiceConfig(ServiceOptions{
+		RegistryMirrors: []string{
+			"gcr.io=https://gcr-cache-1.local",
+			"gcr.io=https://gcr-cache-2.local",
+		},
+		RegistryRewrites: []string{
+			"gcr.io/foo=mirror.local/gcr/foo",
+			"gcr.io/bar=mirror.local/gcr/bar",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := DefaultService{config: cfg, health: newEndpointHealth()}
+
+	imageName, err := reference.ParseNormalizedNamed("gcr.io/foo/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints, err := s.LookupPullEndpoints(imageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls, remoteNames []string
+	for _, endpoint := range endpoints {
+		urls = append(urls, endpoint.URL.String())
+		remoteNames = append(remoteNames, endpoint.RemoteName)
+	}
+	assert.Equal(t, []string{"https://mirror.local", "https://gcr-cache-1.local/", "https://gcr-cache-2.local/", "https://gcr.io"}, urls[:4])
+	assert.Equal(t, []string{"gcr/foo/app", "", "", ""}, remoteNames[:4])
+
+	// a mirror which failed is skipped
+	s.ReportMirrorStatus(endpoints[1], errors.New("connection refused"))
+	endpoints, err = s.LookupPullEndpoints(imageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.URL.Host == "gcr-cache-1.local" {
+			t.Fatal("Pull endpoints should not contain the failed mirror")
+		}
+	}
+
+	// mirrors are not used to push
+	pushEndpoints, err := s.LookupPushEndpoints(reference.Domain(imageName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range pushEndpoints {
+		if endpoint.Mirror {
+			t.Fatal("Push endpoints should not contain mirrors")
+		}
+	}
+}
+
 func TestPushRegistryTag(t *testing.T) {
 	r := spawnTestRegistrySession(t)
 	repoRef, err := reference.ParseNormalizedNamed(REPO)
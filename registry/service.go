@@ -25,7 +25,7 @@ const (
 // Service is the interface defining what a registry service should implement.
 type Service interface {
 	Auth(ctx context.Context, authConfig *types.AuthConfig, userAgent string) (status, token string, err error)
-	LookupPullEndpoints(hostname string) (endpoints []APIEndpoint, err error)
+	LookupPullEndpoints(name reference.Named) (endpoints []APIEndpoint, err error)
 	LookupPushEndpoints(hostname string) (endpoints []APIEndpoint, err error)
 	ResolveRepository(name reference.Named) (*RepositoryInfo, error)
 	Search(ctx context.Context, term string, limit int, authConfig *types.AuthConfig, userAgent string, headers map[string][]string) (*registrytypes.SearchResults, error)
@@ -34,6 +34,7 @@ type Service interface {
 	LoadAllowNondistributableArtifacts([]string) error
 	LoadMirrors([]string) error
 	LoadInsecureRegistries([]string) error
+	ReportMirrorStatus(endpoint APIEndpoint, err error)
 }
 
 // DefaultService is a registry service. It tracks configuration data such as a list
@@ -41,6 +42,7 @@ type Service interface {
 type DefaultService struct {
 	config *serviceConfig
 	mu     sync.Mutex
+	health *endpointHealth
 }
 
 // NewService returns a new instance of DefaultService ready to be
@@ -48,7 +50,7 @@ type DefaultService struct {
 func NewService(options ServiceOptions) (*DefaultService, error) {
 	config, err := newServiceConfig(options)
 
-	return &DefaultService{config: config}, err
+	return &DefaultService{config: config, health: newEndpointHealth()}, err
 }
 
 // ServiceConfig returns the public registry service configuration.
@@ -256,6 +258,9 @@ type APIEndpoint struct {
 	Official                       bool
 	TrimHostname                   bool
 	TLSConfig                      *tls.Config
+	// RemoteName is the name of the repository on the endpoint, if it was
+	// rewritten by a registry rewrite rule.
+	RemoteName string
 }
 
 // ToV1Endpoint returns a V1 API endpoint based on the APIEndpoint
@@ -280,14 +285,39 @@ func (s *DefaultService) tlsConfigForMirror(mirrorURL *url.URL) (*tls.Config, er
 	return s.tlsConfig(mirrorURL.Host)
 }
 
-// LookupPullEndpoints creates a list of endpoints to try to pull from, in order of preference.
-// It gives preference to v2 endpoints over v1, mirrors over the actual
-// registry, and HTTPS over plain HTTP.
-func (s *DefaultService) LookupPullEndpoints(hostname string) (endpoints []APIEndpoint, err error) {
+// LookupPullEndpoints creates a list of endpoints to try to pull the repository
+// name from, in order of preference. It gives preference to the rewrites of
+// name over mirrors, mirrors over the actual registry, v2 endpoints over v1,
+// and HTTPS over plain HTTP. The mirrors which recently failed are skipped.
+func (s *DefaultService) LookupPullEndpoints(name reference.Named) (endpoints []APIEndpoint, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.lookupEndpoints(hostname)
+	mirrors, err := s.lookupMirrorEndpoints(name)
+	if err != nil {
+		return nil, err
+	}
+	allEndpoints, err := s.lookupEndpoints(reference.Domain(name))
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range append(mirrors, allEndpoints...) {
+		if endpoint.Mirror && !s.health.healthy(endpoint.URL.String()) {
+			logrus.Debugf("Skipping mirror %s which recently failed", endpoint.URL)
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// ReportMirrorStatus records whether a mirror could be reached, so that the
+// mirrors which can't be are skipped by LookupPullEndpoints for a while.
+func (s *DefaultService) ReportMirrorStatus(endpoint APIEndpoint, err error) {
+	if !endpoint.Mirror {
+		return
+	}
+	s.health.report(endpoint.URL.String(), err)
 }
 
 // LookupPushEndpoints creates a list of endpoints to try to push to, in order of preference.
@@ -4,9 +4,60 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/docker/distribution/reference"
 	"github.com/docker/go-connections/tlsconfig"
 )
 
+// lookupMirrorEndpoints returns the endpoints of the rewrites of the
+// repository name, followed by the ones of the mirrors of its registry.
+func (s *DefaultService) lookupMirrorEndpoints(name reference.Named) (endpoints []APIEndpoint, err error) {
+	for _, rewritten := range s.config.rewrite(name) {
+		hostname := reference.Domain(rewritten)
+		tlsConfig, err := s.tlsConfig(hostname)
+		if err != nil {
+			return nil, err
+		}
+		endpoint := APIEndpoint{
+			URL: &url.URL{
+				Scheme: "https",
+				Host:   hostname,
+			},
+			Version:      APIVersion2,
+			Mirror:       true,
+			TrimHostname: true,
+			TLSConfig:    tlsConfig,
+			RemoteName:   reference.Path(rewritten),
+		}
+		endpoints = append(endpoints, endpoint)
+		if tlsConfig.InsecureSkipVerify {
+			endpoint.URL = &url.URL{
+				Scheme: "http",
+				Host:   hostname,
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	for _, mirror := range s.config.hostMirrors[reference.Domain(name)] {
+		mirrorURL, err := url.Parse(mirror)
+		if err != nil {
+			return nil, err
+		}
+		mirrorTLSConfig, err := s.tlsConfigForMirror(mirrorURL)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, APIEndpoint{
+			URL:          mirrorURL,
+			Version:      APIVersion2,
+			Mirror:       true,
+			TrimHostname: true,
+			TLSConfig:    mirrorTLSConfig,
+		})
+	}
+	return endpoints, nil
+}
+
 func (s *DefaultService) lookupV2Endpoints(hostname string) (endpoints []APIEndpoint, err error) {
 	tlsConfig := tlsconfig.ServerDefault()
 	if hostname == DefaultNamespace || hostname == IndexHostname {
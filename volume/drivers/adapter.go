@@ -37,6 +37,23 @@ func (a *volumeDriverAdapter) Create(name string, opts map[string]string) (volum
 	}, nil
 }
 
+// Snapshot asks the plugin to create the volume with a copy of the data of
+// source, if the plugin advertises the snapshot capability.
+func (a *volumeDriverAdapter) Snapshot(source volume.Volume, name string, opts map[string]string) (volume.Volume, error) {
+	if !a.getCapabilities().Snapshot {
+		return nil, volume.ErrSnapshotNotSupported
+	}
+	if err := a.proxy.Snapshot(source.Name(), name, opts); err != nil {
+		return nil, err
+	}
+	return &volumeAdapter{
+		proxy:        a.proxy,
+		name:         name,
+		driverName:   a.name,
+		baseHostPath: a.baseHostPath,
+	}, nil
+}
+
 func (a *volumeDriverAdapter) Remove(v volume.Volume) error {
 	return a.proxy.Remove(v.Name())
 }
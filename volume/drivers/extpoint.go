@@ -50,6 +50,9 @@ type volumeDriver interface {
 	Get(name string) (volume *proxyVolume, err error)
 	// Capabilities gets the list of capabilities of the driver
 	Capabilities() (capabilities volume.Capability, err error)
+	// Snapshot creates the target volume with a copy of the data of the
+	// volume with the given name
+	Snapshot(name, target string, opts map[string]string) (err error)
 }
 
 type driverExtpoint struct {
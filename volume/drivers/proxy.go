@@ -240,3 +240,33 @@ func (pp *volumeDriverProxy) Capabilities() (capabilities volume.Capability, err
 
 	return
 }
+
+type volumeDriverProxySnapshotRequest struct {
+	Name   string
+	Target string
+	Opts   map[string]string
+}
+
+type volumeDriverProxySnapshotResponse struct {
+	Err string
+}
+
+func (pp *volumeDriverProxy) Snapshot(name string, target string, opts map[string]string) (err error) {
+	var (
+		req volumeDriverProxySnapshotRequest
+		ret volumeDriverProxySnapshotResponse
+	)
+
+	req.Name = name
+	req.Target = target
+	req.Opts = opts
+	if err = pp.Call("VolumeDriver.Snapshot", req, &ret); err != nil {
+		return
+	}
+
+	if ret.Err != "" {
+		err = errors.New(ret.Err)
+	}
+
+	return
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/daemon/names"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
@@ -53,6 +54,7 @@ func New(scope string, rootIDs idtools.IDPair) (*Root, error) {
 		return nil, err
 	}
 
+	var err error
 	r := &Root{
 		scope:   scope,
 		path:    rootDirectory,
@@ -60,6 +62,12 @@ func New(scope string, rootIDs idtools.IDPair) (*Root, error) {
 		rootIDs: rootIDs,
 	}
 
+	// Volumes created with a size option are limited with project quotas
+	// when the backing filesystem supports them.
+	if r.quotaCtl, err = quota.NewControl(rootDirectory); err != nil {
+		logrus.Debugf("project quotas are not available for local volumes: %v", err)
+	}
+
 	dirs, err := ioutil.ReadDir(rootDirectory)
 	if err != nil {
 		return nil, err
@@ -80,6 +88,7 @@ func New(scope string, rootIDs idtools.IDPair) (*Root, error) {
 			driverName: r.Name(),
 			name:       name,
 			path:       r.DataPath(name),
+			quotaCtl:   r.quotaCtl,
 		}
 		r.volumes[name] = v
 		optsFilePath := filepath.Join(rootDirectory, name, "opts.json")
@@ -116,6 +125,9 @@ type Root struct {
 	path    string
 	volumes map[string]*localVolume
 	rootIDs idtools.IDPair
+	// quotaCtl is nil if the backing filesystem doesn't support project
+	// quotas.
+	quotaCtl *quota.Control
 }
 
 // List lists all the volumes
@@ -180,11 +192,11 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 	}
 
 	path := r.DataPath(name)
-	if err := idtools.MkdirAllAndChown(path, 0755, r.rootIDs); err != nil {
+	if err := idtools.MkdirAllAndChown(filepath.Dir(path), 0755, r.rootIDs); err != nil {
 		if os.IsExist(err) {
 			return nil, alreadyExistsError{filepath.Dir(path)}
 		}
-		return nil, errors.Wrapf(systemError{err}, "error while creating volume path '%s'", path)
+		return nil, errors.Wrapf(systemError{err}, "error while creating volume path '%s'", filepath.Dir(path))
 	}
 
 	var err error
@@ -198,10 +210,11 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 		driverName: r.Name(),
 		name:       name,
 		path:       path,
+		quotaCtl:   r.quotaCtl,
 	}
 
 	if len(opts) != 0 {
-		if err = setOpts(v, opts); err != nil {
+		if err = r.setOpts(v, opts); err != nil {
 			return nil, err
 		}
 		var b []byte
@@ -214,6 +227,13 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 		}
 	}
 
+	// the data directory is created after the options are set, so that it
+	// inherits the project quota set on the volume directory by the size
+	// option
+	if err = idtools.MkdirAllAndChown(path, 0755, r.rootIDs); err != nil {
+		return nil, errors.Wrapf(systemError{err}, "error while creating volume path '%s'", path)
+	}
+
 	r.volumes[name] = v
 	return v, nil
 }
@@ -256,7 +276,13 @@ func (r *Root) Remove(v volume.Volume) error {
 	}
 
 	delete(r.volumes, lv.name)
-	return removePath(filepath.Dir(lv.path))
+	if err := removePath(filepath.Dir(lv.path)); err != nil {
+		return err
+	}
+	if r.quotaCtl != nil {
+		r.quotaCtl.ClearQuota(filepath.Dir(lv.path))
+	}
+	return nil
 }
 
 func removePath(path string) error {
@@ -317,6 +343,8 @@ type localVolume struct {
 	opts *optsConfig
 	// active refcounts the active mounts
 	active activeMount
+	// quotaCtl is the project quota control of the Root of the volume
+	quotaCtl *quota.Control
 }
 
 // Name returns the name of the given Volume.
@@ -398,10 +426,6 @@ func validateOpts(opts map[string]string) error {
 	return nil
 }
 
-func (v *localVolume) Status() map[string]interface{} {
-	return nil
-}
-
 // getAddress finds out address/hostname from options
 func getAddress(opts string) string {
 	optsList := strings.Split(opts, ",")
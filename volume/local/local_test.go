@@ -3,6 +3,7 @@ package local
 import (
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -286,6 +287,102 @@ func TestCreateWithOpts(t *testing.T) {
 	}
 }
 
+func TestCreateWithSizeOpt(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip()
+	}
+	rootDir, err := ioutil.TempDir("", "local-volume-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []map[string]string{
+		{"size": "notasize"},
+		{"size": "0"},
+		{"size": "10m", "type": "tmpfs", "device": "tmpfs"},
+	} {
+		if _, err := r.Create("test", opts); err == nil {
+			t.Fatalf("expected %v to cause error", opts)
+		}
+		if _, err := os.Stat(filepath.Join(rootDir, volumesPathName, "test")); !os.IsNotExist(err) {
+			t.Fatalf("expected volume directory to be removed after error with %v", opts)
+		}
+	}
+
+	if r.quotaCtl == nil {
+		if _, err := exec.LookPath("mkfs.ext4"); err != nil {
+			t.Skip("project quotas and mkfs.ext4 are not available")
+		}
+	}
+	vol, err := r.Create("test", map[string]string{"size": "32m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vol.(*localVolume)
+	if v.opts.Size != 32*1024*1024 {
+		t.Fatalf("expected size to be 32m, got %d", v.opts.Size)
+	}
+	if status := v.Status(); status["Size"] != v.opts.Size {
+		t.Fatalf("expected size in volume status, got %v", status)
+	}
+}
+
+func TestCreateWithSizeOptLimitsData(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip()
+	}
+	rootDir, err := ioutil.TempDir("", "local-volume-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.quotaCtl == nil {
+		if _, err := exec.LookPath("mkfs.ext4"); err != nil {
+			t.Skip("project quotas and mkfs.ext4 are not available")
+		}
+	}
+
+	vol, err := r.Create("test", map[string]string{"size": "32m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vol.(*localVolume)
+	if _, err := v.Mount("1234"); err != nil {
+		if v.opts.Loopback {
+			t.Skipf("cannot mount volume image: %v", err)
+		}
+		t.Fatal(err)
+	}
+	defer v.Unmount("1234")
+
+	// writing more than the size of the volume to its data directory fails
+	data := make([]byte, 1024*1024)
+	f, err := os.Create(filepath.Join(rootDir, volumesPathName, "test", VolumeDataPathName, "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for i := 0; i < 64; i++ {
+		if _, err = f.Write(data); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Fatal("expected writes beyond the volume size to fail")
+	}
+}
+
 func TestRealodNoOpts(t *testing.T) {
 	rootDir, err := ioutil.TempDir("", "volume-test-reload-no-opts")
 	if err != nil {
@@ -343,3 +440,37 @@ func TestRealodNoOpts(t *testing.T) {
 		}
 	}
 }
+
+func TestRemoveClearsQuota(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip()
+	}
+	rootDir, err := ioutil.TempDir("", "local-volume-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, idtools.IDPair{UID: 0, GID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.quotaCtl == nil {
+		t.Skip("project quotas are not available")
+	}
+
+	vol, err := r.Create("test", map[string]string{"size": "32m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(vol.Path())
+	if _, err := r.quotaCtl.GetUsage(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remove(vol); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.quotaCtl.GetUsage(dir); err == nil {
+		t.Fatal("expected the quota of the removed volume to be released")
+	}
+}
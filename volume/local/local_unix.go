@@ -15,8 +15,10 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/go-units"
 )
 
 var (
@@ -26,6 +28,7 @@ var (
 		"type":   true, // specify the filesystem type for mount, e.g. nfs
 		"o":      true, // generic mount options
 		"device": true, // device to mount from
+		"size":   true, // maximum size of the volume, e.g. 10G
 	}
 )
 
@@ -33,9 +36,18 @@ type optsConfig struct {
 	MountType   string
 	MountOpts   string
 	MountDevice string
+	// Size is the maximum size of the volume in bytes.
+	Size int64 `json:",omitempty"`
+	// Loopback is set when the size of the volume is limited by mounting
+	// a filesystem image of that size instead of a project quota. The
+	// image is then the MountDevice.
+	Loopback bool `json:",omitempty"`
 }
 
 func (o *optsConfig) String() string {
+	if o.Size > 0 {
+		return fmt.Sprintf("type='%s' device='%s' o='%s' size='%d'", o.MountType, o.MountDevice, o.MountOpts, o.Size)
+	}
 	return fmt.Sprintf("type='%s' device='%s' o='%s'", o.MountType, o.MountDevice, o.MountOpts)
 }
 
@@ -55,7 +67,7 @@ func (r *Root) scopedPath(realPath string) bool {
 	return false
 }
 
-func setOpts(v *localVolume, opts map[string]string) error {
+func (r *Root) setOpts(v *localVolume, opts map[string]string) error {
 	if len(opts) == 0 {
 		return nil
 	}
@@ -68,13 +80,35 @@ func setOpts(v *localVolume, opts map[string]string) error {
 		MountOpts:   opts["o"],
 		MountDevice: opts["device"],
 	}
+	if val, ok := opts["size"]; ok {
+		size, err := units.RAMInBytes(val)
+		if err != nil {
+			return validationError(fmt.Sprintf("invalid size option %q: %v", val, err))
+		}
+		if size <= 0 {
+			return validationError(fmt.Sprintf("invalid size option %q: size must be positive", val))
+		}
+		if v.opts.MountType != "" || v.opts.MountDevice != "" || v.opts.MountOpts != "" {
+			return validationError("the size option cannot be combined with the type, device or o options")
+		}
+		v.opts.Size = size
+		return r.setSize(v)
+	}
 	return nil
 }
 
 func (v *localVolume) mount() error {
 	if v.opts.MountDevice == "" {
+		if v.opts.Size > 0 {
+			// the size is limited by a project quota, nothing to mount
+			return nil
+		}
 		return fmt.Errorf("missing device in volume options")
 	}
+	if v.opts.Loopback {
+		err := mountLoopback(v.opts.MountDevice, v.path, v.opts.MountType)
+		return errors.Wrapf(err, "error while mounting volume with options: %s", v.opts)
+	}
 	mountOpts := v.opts.MountOpts
 	if v.opts.MountType == "nfs" {
 		if addrValue := getAddress(v.opts.MountOpts); addrValue != "" && net.ParseIP(addrValue).To4() == nil {
@@ -97,3 +131,21 @@ func (v *localVolume) CreatedAt() (time.Time, error) {
 	sec, nsec := fileInfo.Sys().(*syscall.Stat_t).Ctim.Unix()
 	return time.Unix(sec, nsec), nil
 }
+
+// Status returns the size limit and the current usage of volumes created
+// with a size option.
+func (v *localVolume) Status() map[string]interface{} {
+	if v.opts == nil || v.opts.Size <= 0 {
+		return nil
+	}
+	status := map[string]interface{}{
+		"Size": v.opts.Size,
+	}
+	usage, err := v.usage()
+	if err != nil {
+		logrus.WithError(err).WithField("volume", v.name).Debug("error getting local volume usage")
+		return status
+	}
+	status["Usage"] = usage
+	return status
+}
@@ -25,7 +25,7 @@ func (r *Root) scopedPath(realPath string) bool {
 	return false
 }
 
-func setOpts(v *localVolume, opts map[string]string) error {
+func (r *Root) setOpts(v *localVolume, opts map[string]string) error {
 	if len(opts) > 0 {
 		return fmt.Errorf("options are not supported on this platform")
 	}
@@ -36,6 +36,10 @@ func (v *localVolume) mount() error {
 	return nil
 }
 
+func (v *localVolume) Status() map[string]interface{} {
+	return nil
+}
+
 func (v *localVolume) CreatedAt() (time.Time, error) {
 	fileInfo, err := os.Stat(v.path)
 	if err != nil {
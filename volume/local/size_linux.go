@@ -0,0 +1,91 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/docker/docker/daemon/graphdriver/quota"
+	"github.com/docker/docker/pkg/loopback"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// loopbackImageName is the name of the filesystem image limiting the size of
+// a volume when project quotas are not available. It is stored next to the
+// data directory of the volume.
+const loopbackImageName = "disk.img"
+
+// setSize limits the size of the volume to v.opts.Size, with a project quota
+// on the volume directory if the backing filesystem supports it, or else with
+// an ext4 image of that size mounted on the data directory. The quota only
+// applies to the files created in the volume directory afterwards, so it must
+// be set before the data directory is created.
+func (r *Root) setSize(v *localVolume) error {
+	dir := filepath.Dir(v.path)
+	if r.quotaCtl != nil {
+		if err := r.quotaCtl.SetQuota(dir, quota.Quota{Size: uint64(v.opts.Size)}); err != nil {
+			return errors.Wrap(systemError{err}, "error setting volume quota")
+		}
+		return nil
+	}
+
+	if _, err := exec.LookPath("mkfs.ext4"); err != nil {
+		return errors.Wrap(systemError{err}, "project quotas are not supported by the volumes filesystem and mkfs.ext4 is not available")
+	}
+	image := filepath.Join(dir, loopbackImageName)
+	f, err := os.OpenFile(image, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.Wrap(systemError{err}, "error creating volume image")
+	}
+	err = f.Truncate(v.opts.Size)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrap(systemError{err}, "error creating volume image")
+	}
+
+	rootOwner := fmt.Sprintf("root_owner=%d:%d", r.rootIDs.UID, r.rootIDs.GID)
+	if out, err := exec.Command("mkfs.ext4", "-q", "-F", "-m", "0", "-E", rootOwner, image).CombinedOutput(); err != nil {
+		return errors.Wrapf(systemError{err}, "error formatting volume image: %s", out)
+	}
+
+	v.opts.Loopback = true
+	v.opts.MountType = "ext4"
+	v.opts.MountDevice = image
+	return nil
+}
+
+// mountLoopback mounts the filesystem image on target through a loop device.
+// The loop device is detached when the filesystem is unmounted.
+func mountLoopback(image, target, fsType string) error {
+	loop, err := loopback.AttachLoopDevice(image)
+	if err != nil {
+		return err
+	}
+	// the loop device has the autoclear flag set, so it is released on
+	// unmount, or right away if the mount fails
+	defer loop.Close()
+	return mount.Mount(loop.Name(), target, fsType, "")
+}
+
+// usage returns the number of bytes used by a volume created with a size
+// option. When the size is limited with a filesystem image, this is the
+// space allocated to the image.
+func (v *localVolume) usage() (int64, error) {
+	if !v.opts.Loopback {
+		if v.quotaCtl == nil {
+			return 0, errors.New("project quotas are not available")
+		}
+		usage, err := v.quotaCtl.GetUsage(filepath.Dir(v.path))
+		return int64(usage), err
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(v.opts.MountDevice, &st); err != nil {
+		return 0, err
+	}
+	return st.Blocks * 512, nil
+}
@@ -0,0 +1,19 @@
+// +build !linux
+
+package local
+
+import "errors"
+
+var errSizeNotSupported = errors.New("the size option is not supported on this platform")
+
+func (r *Root) setSize(v *localVolume) error {
+	return validationError(errSizeNotSupported.Error())
+}
+
+func mountLoopback(image, target, fsType string) error {
+	return errSizeNotSupported
+}
+
+func (v *localVolume) usage() (int64, error) {
+	return 0, errSizeNotSupported
+}
@@ -11,6 +11,8 @@ const (
 	errNoSuchVolume notFoundError = "no such volume"
 	// errNameConflict is a typed error returned on create when a volume exists with the given name, but for a different driver
 	errNameConflict conflictError = "volume name must be unique"
+	// errVolumeExists is a typed error returned when a volume to be populated from another volume already exists
+	errVolumeExists conflictError = "volume already exists"
 )
 
 type conflictError string
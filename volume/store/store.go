@@ -292,6 +292,50 @@ func (s *VolumeStore) Create(name, driverName string, opts, labels map[string]st
 	return s.CreateWithRef(name, driverName, "", opts, labels)
 }
 
+// CreateNew creates a volume with the given name and driver, to be populated
+// from another volume. Unlike Create, an existing volume with the same name is
+// not returned: the check and the creation are made under the same lock, so
+// that the caller knows the volume is its own.
+func (s *VolumeStore) CreateNew(name, driverName string, opts, labels map[string]string) (volume.Volume, error) {
+	name = normalizeVolumeName(name)
+	s.locks.Lock(name)
+	defer s.locks.Unlock(name)
+
+	v, err := s.checkConflict(name, driverName)
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "create"}
+	}
+	if v != nil {
+		return nil, &OpErr{Err: errVolumeExists, Name: name, Op: "create"}
+	}
+
+	parser := volume.NewParser(runtime.GOOS)
+	if err := parser.ValidateVolumeName(name); err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "create"}
+	}
+
+	vd, err := volumedrivers.CreateDriver(driverName)
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "create"}
+	}
+	// the driver may know the volume even though the store doesn't
+	if v, _ := vd.Get(name); v != nil {
+		return nil, &OpErr{Err: errVolumeExists, Name: name, Op: "create"}
+	}
+
+	v, err = vd.Create(name, opts)
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "create"}
+	}
+	v, err = s.register(name, vd, v, opts, labels)
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "create"}
+	}
+
+	s.setNamed(v, "")
+	return v, nil
+}
+
 // checkConflict checks the local cache for name collisions with the passed in name,
 // for existing volumes with the same name but in a different driver.
 // This is used by `Create` as a best effort to prevent name collisions for volumes.
@@ -411,6 +455,12 @@ func (s *VolumeStore) create(name, driverName string, opts, labels map[string]st
 	if err != nil {
 		return nil, err
 	}
+	return s.register(name, vd, v, opts, labels)
+}
+
+// register stores the labels and options of a volume newly created by the
+// driver, and persists them.
+func (s *VolumeStore) register(name string, vd volume.Driver, v volume.Volume, opts, labels map[string]string) (volume.Volume, error) {
 	s.globalLock.Lock()
 	s.labels[name] = labels
 	s.options[name] = opts
@@ -430,6 +480,51 @@ func (s *VolumeStore) create(name, driverName string, opts, labels map[string]st
 	return volumeWrapper{v, labels, vd.Scope(), opts}, nil
 }
 
+// Snapshot creates a volume with the given name and options, holding a copy
+// of the data of source, using the native snapshot support of the driver of
+// source. volume.ErrSnapshotNotSupported is returned if the driver cannot
+// snapshot the volume.
+func (s *VolumeStore) Snapshot(source volume.Volume, name string, opts, labels map[string]string) (volume.Volume, error) {
+	name = normalizeVolumeName(name)
+	s.locks.Lock(name)
+	defer s.locks.Unlock(name)
+
+	parser := volume.NewParser(runtime.GOOS)
+	if err := parser.ValidateVolumeName(name); err != nil {
+		return nil, err
+	}
+
+	v, err := s.checkConflict(name, source.DriverName())
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "snapshot"}
+	}
+	if v != nil {
+		return nil, &OpErr{Err: errVolumeExists, Name: name, Op: "snapshot"}
+	}
+
+	vd, err := volumedrivers.GetDriver(source.DriverName())
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: source.DriverName(), Op: "snapshot"}
+	}
+	sd, ok := vd.(volume.SnapshotDriver)
+	if !ok {
+		return nil, volume.ErrSnapshotNotSupported
+	}
+
+	v, err = sd.Snapshot(unwrapVolume(source), name, opts)
+	if err != nil {
+		if err == volume.ErrSnapshotNotSupported {
+			return nil, err
+		}
+		return nil, &OpErr{Err: err, Name: name, Op: "snapshot"}
+	}
+	v, err = s.register(name, vd, v, opts, labels)
+	if err != nil {
+		return nil, &OpErr{Err: err, Name: name, Op: "snapshot"}
+	}
+	return v, nil
+}
+
 // GetWithRef gets a volume with the given name from the passed in driver and stores the ref
 // This is just like Get(), but we store the reference while holding the lock.
 // This makes sure there are no races between checking for the existence of a volume and adding a reference for it
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/volume"
 	"github.com/docker/docker/volume/drivers"
 	volumetestutils "github.com/docker/docker/volume/testutils"
 )
@@ -232,3 +233,104 @@ func TestDerefMultipleOfSameRef(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type fakeSnapshotDriver struct {
+	volume.Driver
+}
+
+func (d fakeSnapshotDriver) Snapshot(source volume.Volume, name string, opts map[string]string) (volume.Volume, error) {
+	if opts["snapshot"] == "false" {
+		return nil, volume.ErrSnapshotNotSupported
+	}
+	return d.Create(name, opts)
+}
+
+func TestSnapshot(t *testing.T) {
+	volumedrivers.Register(fakeSnapshotDriver{volumetestutils.NewFakeDriver("snapshot")}, "snapshot")
+	volumedrivers.Register(volumetestutils.NewFakeDriver("noop"), "noop")
+	defer volumedrivers.Unregister("snapshot")
+	defer volumedrivers.Unregister("noop")
+	dir, err := ioutil.TempDir("", "test-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := s.Create("src", "snapshot", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := s.Snapshot(src, "dst", nil, map[string]string{"a": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name() != "dst" || v.DriverName() != "snapshot" {
+		t.Fatalf("expected snapshot volume dst, got %s %s", v.DriverName(), v.Name())
+	}
+	if _, err := s.Get("dst"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Snapshot(src, "dst", nil, nil); !isErr(err, errVolumeExists) {
+		t.Fatalf("expected volume exists error, got %v", err)
+	}
+	if _, err := s.Snapshot(src, "unsupported", map[string]string{"snapshot": "false"}, nil); err != volume.ErrSnapshotNotSupported {
+		t.Fatalf("expected snapshot not supported error, got %v", err)
+	}
+
+	noop, err := s.Create("noop1", "noop", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Snapshot(noop, "noop2", nil, nil); err != volume.ErrSnapshotNotSupported {
+		t.Fatalf("expected snapshot not supported error, got %v", err)
+	}
+}
+
+func TestCreateNew(t *testing.T) {
+	volumedrivers.Register(volumetestutils.NewFakeDriver("fake"), "fake")
+	volumedrivers.Register(volumetestutils.NewFakeDriver("noop"), "noop")
+	defer volumedrivers.Unregister("fake")
+	defer volumedrivers.Unregister("noop")
+	dir, err := ioutil.TempDir("", "test-create-new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.CreateNew("fake1", "fake", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name() != "fake1" {
+		t.Fatalf("expected fake1 volume, got %v", v)
+	}
+
+	if _, err := s.CreateNew("fake1", "fake", nil, nil); !isErr(err, errVolumeExists) {
+		t.Fatalf("expected volume exists error, got %v", err)
+	}
+	if _, err := s.CreateNew("fake1", "noop", nil, nil); !IsNameConflict(err) {
+		t.Fatalf("expected name conflict error, got %v", err)
+	}
+
+	// a volume the driver knows of but the store doesn't is not taken over
+	vd, err := volumedrivers.GetDriver("noop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vd.Create("noop1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateNew("noop1", "noop", nil, nil); !isErr(err, errVolumeExists) {
+		t.Fatalf("expected volume exists error, got %v", err)
+	}
+}
@@ -49,6 +49,22 @@ type Capability struct {
 	// A `local` scope indicates that the driver only manages volumes resources local to the host
 	// Scope is declared by the driver
 	Scope string
+	// Snapshot indicates that the driver is able to copy the data of a
+	// volume to a new volume natively
+	Snapshot bool
+}
+
+// ErrSnapshotNotSupported is returned by SnapshotDriver.Snapshot when the
+// driver cannot snapshot the volume.
+var ErrSnapshotNotSupported = errors.New("volume driver does not support snapshots")
+
+// SnapshotDriver is a Driver which may be able to copy the data of a volume
+// to a new volume, more efficiently than by copying its files.
+type SnapshotDriver interface {
+	Driver
+	// Snapshot creates a new volume with the given name and options,
+	// holding a copy of the data of the source volume.
+	Snapshot(source Volume, name string, opts map[string]string) (Volume, error)
 }
 
 // Volume is a place to store data. It is backed by a specific driver, and can be mounted.